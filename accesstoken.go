@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// accessTokenClaims are the claims that RFC9068 requires every access token
+// to have.
+var accessTokenClaims = []string{"iss", "exp", "aud", "sub", "client_id", "iat", "jti"}
+
+// WithAccessTokenProfile makes a Verify function enforce the rules that
+// RFC9068 sets for resource servers validating JWT access tokens. issuer is the
+// issuer identifier of your authorization server, and resource is the
+// identifier of your resource server, as it appears in the "aud" of access
+// tokens meant for it.
+//
+// With WithAccessTokenProfile, a Verify function rejects tokens:
+//
+//   - that are signed with HS256 or HS512, with ErrWrongAlgorithm,
+//   - whose "typ" is not "at+jwt" or "application/at+jwt", with
+//     ErrUnexpectedType, even if WithExpectedType allows other types,
+//   - that lack any of the "iss", "exp", "aud", "sub", "client_id", "iat", or
+//     "jti" claims, with an error wrapping ErrMissingClaim that names the first
+//     claim that is missing,
+//   - whose "iss" is not issuer, with an error wrapping ErrUnexpectedIssuer,
+//   - whose "aud" doesn't contain resource, with an error wrapping
+//     ErrUnexpectedAudience,
+//   - that are expired or not yet valid, with ErrExpiredToken or
+//     ErrNotYetValid. Use WithLeeway to allow for clock skew.
+//
+// RFC9068 requires access tokens to be signed, and says resource servers
+// should at least support RS256. Use WithAccessTokenProfile with VerifyRS256,
+// VerifyES256, or another Verify function for an asymmetric algorithm. Tokens
+// signed with a shared secret are rejected, because anyone who can verify them
+// can also mint them, and a resource server should never be able to mint
+// access tokens. Like WithExpectedIssuer, WithAccessTokenProfile checks the
+// claims in the JWT, not v, so v can be of any type.
+//
+// https://tools.ietf.org/html/rfc9068#section-4
+func WithAccessTokenProfile(issuer, resource string) VerifyOption {
+	iss := WithExpectedIssuer(issuer)
+	aud := WithExpectedAudience(resource)
+
+	return verifyOptionFunc(func(c *verifyConfig) {
+		// The "typ" is checked apart from c.expectedTypes, which
+		// WithExpectedType adds to, so that it can't be widened.
+		c.accessTokenProfile = true
+		iss.applyVerify(c)
+		aud.applyVerify(c)
+
+		// Unlike WithLeeway, leave c.leeway alone, so that it doesn't matter
+		// whether WithLeeway comes before or after WithAccessTokenProfile.
+		c.checkTimes = true
+		c.requiredClaims = append(c.requiredClaims, accessTokenClaims...)
+	})
+}
+
+// checkAccessTokenHeader returns ErrWrongAlgorithm if WithAccessTokenProfile is
+// in use and the "alg" in the JSON header h is an HMAC algorithm, and
+// ErrUnexpectedType if the "typ" in h isn't "at+jwt".
+func (c *verifyConfig) checkAccessTokenHeader(h []byte) error {
+	if !c.accessTokenProfile {
+		return nil
+	}
+
+	var header header
+	if err := json.Unmarshal(h, &header); err != nil {
+		return malformedToken("header", err)
+	}
+
+	if header.Algorithm == algHS256 || header.Algorithm == algHS512 {
+		return ErrWrongAlgorithm
+	}
+
+	if !strings.EqualFold(trimMediaType(header.Type), "at+jwt") {
+		return ErrUnexpectedType
+	}
+
+	return nil
+}
+
+// checkRequiredClaims returns an error wrapping ErrMissingClaim if any of the
+// claims set by WithAccessTokenProfile is missing from claims, or is null.
+func (c *verifyConfig) checkRequiredClaims(claims map[string]json.RawMessage) error {
+	for _, name := range c.requiredClaims {
+		if raw, ok := claims[name]; !ok || string(raw) == "null" {
+			return fmt.Errorf("%w: %q", ErrMissingClaim, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestWithAccessTokenProfile(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now().Unix()
+	opt := jwt.WithAccessTokenProfile("https://as.example.com", "https://rs.example.com")
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":       "https://as.example.com",
+			"exp":       now + 60,
+			"aud":       []string{"https://rs.example.com", "https://other.example.com"},
+			"sub":       "5ba552d67",
+			"client_id": "s6BhdRkqt3",
+			"iat":       now,
+			"jti":       "dbe39bf3a3ba4238a513f51d6e1691c4",
+		}
+	}
+
+	// The Sign functions always use a "typ" of "JWT", so forge the tokens
+	// instead.
+	sign := func(typ string, claims map[string]interface{}) []byte {
+		header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": typ})
+		assert.NoError(t, err)
+
+		payload, err := json.Marshal(claims)
+		assert.NoError(t, err)
+
+		return forgeToken(string(header), string(payload), func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+			assert.NoError(t, err)
+
+			sig := make([]byte, 64)
+			rb, sb := r.Bytes(), s.Bytes()
+			copy(sig[32-len(rb):32], rb)
+			copy(sig[64-len(sb):], sb)
+			return sig
+		})
+	}
+
+	verify := func(token []byte, opts ...jwt.VerifyOption) error {
+		var claims map[string]interface{}
+		return jwt.VerifyES256(&priv.PublicKey, token, &claims, append([]jwt.VerifyOption{opt}, opts...)...)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, verify(sign("at+jwt", valid())))
+		assert.NoError(t, verify(sign("application/at+jwt", valid())))
+	})
+
+	t.Run("type", func(t *testing.T) {
+		assert.Equal(t, jwt.ErrUnexpectedType, verify(sign("JWT", valid())))
+		assert.Equal(t, jwt.ErrUnexpectedType, verify(sign("", valid())))
+
+		// WithExpectedType can narrow the types WithAccessTokenProfile
+		// accepts, but not widen them.
+		assert.Equal(t, jwt.ErrUnexpectedType, verify(sign("JWT", valid()), jwt.WithExpectedType("JWT")))
+		assert.Equal(t, jwt.ErrUnexpectedType, verify(sign("JWT", valid()), jwt.WithExpectedType("JWT", "")))
+		assert.NoError(t, verify(sign("at+jwt", valid()), jwt.WithExpectedType("JWT", "at+jwt")))
+		assert.Equal(t, jwt.ErrUnexpectedType, verify(sign("at+jwt", valid()), jwt.WithExpectedType("JWT")))
+	})
+
+	t.Run("shared secrets", func(t *testing.T) {
+		secret := []byte("my secret key")
+		header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "at+jwt"})
+		assert.NoError(t, err)
+
+		payload, err := json.Marshal(valid())
+		assert.NoError(t, err)
+
+		var claims map[string]interface{}
+		token := forgeToken(string(header), string(payload), hmacSHA256(secret))
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &claims))
+		assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyHS256(secret, token, &claims, opt))
+
+		_, err = jwt.VerifyAny(token, &claims, jwt.AllowHS256(secret), jwt.AllowES256(&priv.PublicKey), opt)
+		assert.Equal(t, jwt.ErrWrongAlgorithm, err)
+
+		token, err = jwt.SignHS512(secret, valid())
+		assert.NoError(t, err)
+		assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyHS512(secret, token, &claims, opt))
+	})
+
+	t.Run("missing claims", func(t *testing.T) {
+		for _, name := range []string{"iss", "exp", "aud", "sub", "client_id", "iat", "jti"} {
+			claims := valid()
+			delete(claims, name)
+			err := verify(sign("at+jwt", claims))
+			assert.True(t, errors.Is(err, jwt.ErrMissingClaim), name)
+			assert.EqualError(t, err, `jwt: missing required claim: "`+name+`"`)
+
+			claims[name] = nil
+			err = verify(sign("at+jwt", claims))
+			assert.True(t, errors.Is(err, jwt.ErrMissingClaim), name)
+		}
+	})
+
+	t.Run("issuer and audience", func(t *testing.T) {
+		claims := valid()
+		claims["iss"] = "https://evil.example.com"
+		assert.True(t, errors.Is(verify(sign("at+jwt", claims)), jwt.ErrUnexpectedIssuer))
+
+		claims = valid()
+		claims["aud"] = "https://other.example.com"
+		assert.True(t, errors.Is(verify(sign("at+jwt", claims)), jwt.ErrUnexpectedAudience))
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		claims := valid()
+		claims["exp"] = now - 30
+		token := sign("at+jwt", claims)
+		assert.Equal(t, jwt.ErrExpiredToken, verify(token))
+
+		// WithLeeway is honored, whether it comes before or after.
+		assert.NoError(t, verify(token, jwt.WithLeeway(time.Minute)))
+
+		var out json.RawMessage
+		assert.NoError(t, jwt.VerifyES256(&priv.PublicKey, token, &out, jwt.WithLeeway(time.Minute), opt))
+	})
+}
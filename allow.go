@@ -0,0 +1,183 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+)
+
+// AllowedAlgorithm is a pairing of an algorithm and a key that VerifyAny will
+// accept. Construct AllowedAlgorithm values with AllowHS256, AllowRS256, and
+// the other Allow functions in this package.
+//
+// AllowedAlgorithm is a VerifyOption, so that it can be passed to VerifyAny
+// alongside other options. Passing an AllowedAlgorithm to any other Verify
+// function has no effect.
+//
+// The zero AllowedAlgorithm accepts nothing.
+type AllowedAlgorithm struct {
+	alg string
+
+	// fn returns the function that checks signatures, which may depend on
+	// options like WithWeakRSAKeys. It is given the options of each call that
+	// uses a, rather than the options in effect when a was constructed.
+	fn func(c *verifyConfig) func(data, sig []byte) error
+}
+
+func (a AllowedAlgorithm) applyVerify(c *verifyConfig) {
+	c.allowed = append(c.allowed, a)
+}
+
+// Algorithm returns the name of the algorithm a, as it appears in the "alg"
+// header of a JWT.
+func (a AllowedAlgorithm) Algorithm() string {
+	return a.alg
+}
+
+// AllowHS256 permits VerifyAny to accept HS256 tokens signed with secret.
+func AllowHS256(secret []byte) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algHS256, fn: func(c *verifyConfig) func(data, sig []byte) error {
+		return verifyHMAC(secret, algHS256, crypto.SHA256, c.strictSecrets)
+	}}
+}
+
+// AllowHS512 permits VerifyAny to accept HS512 tokens signed with secret.
+func AllowHS512(secret []byte) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algHS512, fn: func(c *verifyConfig) func(data, sig []byte) error {
+		return verifyHMAC(secret, algHS512, crypto.SHA512, c.strictSecrets)
+	}}
+}
+
+// AllowRS256 permits VerifyAny to accept RS256 tokens signed with the private
+// key corresponding to pub.
+func AllowRS256(pub *rsa.PublicKey) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algRS256, fn: func(c *verifyConfig) func(data, sig []byte) error {
+		return verifyRSA(pub, algRS256, crypto.SHA256, false, c.weakRSAKeys)
+	}}
+}
+
+// AllowRS384 permits VerifyAny to accept RS384 tokens signed with the private
+// key corresponding to pub.
+func AllowRS384(pub *rsa.PublicKey) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algRS384, fn: func(c *verifyConfig) func(data, sig []byte) error {
+		return verifyRSA(pub, algRS384, crypto.SHA384, false, c.weakRSAKeys)
+	}}
+}
+
+// AllowPS256 permits VerifyAny to accept PS256 tokens signed with the private
+// key corresponding to pub.
+func AllowPS256(pub *rsa.PublicKey) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algPS256, fn: func(c *verifyConfig) func(data, sig []byte) error {
+		return verifyRSA(pub, algPS256, crypto.SHA256, true, c.weakRSAKeys)
+	}}
+}
+
+// AllowES256 permits VerifyAny to accept ES256 tokens signed with the private
+// key corresponding to pub.
+func AllowES256(pub *ecdsa.PublicKey) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algES256, fn: fixedVerifier(verifyECDSA(pub, algES256, elliptic.P256(), crypto.SHA256))}
+}
+
+// AllowES512 permits VerifyAny to accept ES512 tokens signed with the private
+// key corresponding to pub.
+func AllowES512(pub *ecdsa.PublicKey) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algES512, fn: fixedVerifier(verifyECDSA(pub, algES512, elliptic.P521(), crypto.SHA512))}
+}
+
+// AllowEdDSA permits VerifyAny to accept EdDSA tokens signed with the private
+// key corresponding to pub.
+func AllowEdDSA(pub ed25519.PublicKey) AllowedAlgorithm {
+	return AllowedAlgorithm{alg: algEdDSA, fn: fixedVerifier(verifyEdDSA(pub))}
+}
+
+// AllowCustom permits VerifyAny to accept tokens using the algorithm alg,
+// whose signatures are checked by fn. See VerifyCustom for what fn must do.
+//
+// AllowCustom exists for the benefit of packages that implement algorithms
+// this package leaves out, such as github.com/ucarion/jwt/es256k. If alg is
+// empty or is "none" (in any casing), the returned AllowedAlgorithm accepts
+// nothing.
+func AllowCustom(alg string, fn func(data, sig []byte) error) AllowedAlgorithm {
+	if !isCustomAlgorithm(alg) || fn == nil {
+		return AllowedAlgorithm{}
+	}
+
+	return AllowedAlgorithm{alg: alg, fn: fixedVerifier(fn)}
+}
+
+// fixedVerifier returns a function, suitable for use as the fn of an
+// AllowedAlgorithm, that checks signatures with fn no matter the options.
+func fixedVerifier(fn func(data, sig []byte) error) func(c *verifyConfig) func(data, sig []byte) error {
+	return func(*verifyConfig) func(data, sig []byte) error {
+		return fn
+	}
+}
+
+// VerifyAny verifies a JWT that may use any one of a set of algorithms and
+// keys, chosen ahead of time by the caller. If the JWT is verified, VerifyAny
+// will serialize the claims inside the JWT into v, and return the algorithm
+// that the JWT was verified with.
+//
+// VerifyAny is meant for migrations. For instance, if you are moving from
+// HS256 to RS256, then during the cutover you can accept both:
+//
+//	alg, err := jwt.VerifyAny(token, &claims, jwt.AllowHS256(secret), jwt.AllowRS256(pub))
+//
+// The "alg" header of the JWT selects among only the algorithms in opts. If
+// the JWT uses any other algorithm, VerifyAny returns ErrWrongAlgorithm, just
+// like VerifyHS256 would for a JWT that doesn't use HS256. This is not a way to
+// let the token pick its own algorithm: every algorithm VerifyAny accepts, and
+// the key it is checked with, must be listed in opts.
+//
+// If opts lists the same algorithm more than once, each of the
+// corresponding keys is tried in order, and the first one that verifies the
+// JWT is used.
+//
+// Once a migration is done, prefer going back to the Verify function for the
+// one algorithm you use.
+func VerifyAny(s []byte, v interface{}, opts ...VerifyOption) (string, error) {
+	if err := checkClaimsTarget(v); err != nil {
+		return "", err
+	}
+
+	c := newVerifyConfig(opts)
+
+	alg, header, claims, err := verifySelect(s, v, opts, selectAllowed(&c, c.allowed))
+	if err != nil {
+		return "", err
+	}
+
+	return alg, unmarshalClaims(header, claims, v, opts)
+}
+
+// selectAllowed returns a function, suitable for passing to verifySelect, that
+// selects among the algorithms in allowed, configured by c. If allowed has more
+// than one key for an algorithm, the selected function tries each of them in
+// order.
+func selectAllowed(c *verifyConfig, allowed []AllowedAlgorithm) func(alg string) func(data, sig []byte) error {
+	return func(alg string) func(data, sig []byte) error {
+		var fns []func(data, sig []byte) error
+		for _, a := range allowed {
+			if a.fn != nil && a.alg == alg {
+				fns = append(fns, a.fn(c))
+			}
+		}
+
+		if len(fns) == 0 {
+			return nil
+		}
+
+		return func(data, sig []byte) error {
+			err := ErrBadSignature
+			for _, fn := range fns {
+				if err = fn(data, sig); err == nil {
+					return nil
+				}
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Audience is the "aud" claim of a JWT.
+//
+// RFC7519 permits "aud" to be either a single string, or an array of strings.
+// Many identity providers use the array form. Audience accepts both when it is
+// unmarshalled from JSON, and it is marshalled as a single string if it has
+// exactly one entry, or as an array otherwise.
+//
+// https://tools.ietf.org/html/rfc7519#section-4.1.3
+type Audience []string
+
+// MarshalJSON implements json.Marshaler.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a JSON string, an
+// array of JSON strings, or null.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		*a = nil
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		*a = Audience{s}
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return errors.New("jwt: \"aud\" must be a string or an array of strings")
+	}
+
+	*a = Audience(ss)
+	return nil
+}
+
+// Contains returns whether aud is one of the entries in a.
+func (a Audience) Contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+
+	return false
+}
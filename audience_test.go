@@ -0,0 +1,96 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestAudienceJSON(t *testing.T) {
+	testCases := []struct {
+		in  string
+		aud jwt.Audience
+		out string
+	}{
+		{`"api"`, jwt.Audience{"api"}, `"api"`},
+		{`["api"]`, jwt.Audience{"api"}, `"api"`},
+		{`["api","web"]`, jwt.Audience{"api", "web"}, `["api","web"]`},
+		{`[]`, jwt.Audience{}, `[]`},
+		{`null`, nil, `null`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.in, func(t *testing.T) {
+			var aud jwt.Audience
+			assert.NoError(t, json.Unmarshal([]byte(tt.in), &aud))
+			assert.Equal(t, tt.aud, aud)
+
+			out, err := json.Marshal(aud)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, string(out))
+		})
+	}
+
+	for _, in := range []string{`1`, `true`, `{}`, `[1]`, `["api",null,1]`} {
+		var aud jwt.Audience
+		assert.Error(t, json.Unmarshal([]byte(in), &aud), in)
+	}
+}
+
+func TestAudienceContains(t *testing.T) {
+	aud := jwt.Audience{"api", "web"}
+	assert.True(t, aud.Contains("api"))
+	assert.True(t, aud.Contains("web"))
+	assert.False(t, aud.Contains("API"))
+	assert.False(t, jwt.Audience(nil).Contains(""))
+}
+
+func TestRegisteredClaimsAudience(t *testing.T) {
+	secret := []byte("my secret key")
+
+	// This is the form that, for instance, Auth0 and Okta use for "aud". It
+	// can't be unmarshalled into StandardClaims.
+	token, err := jwt.SignHS256(secret, map[string]interface{}{
+		"sub": "jdoe@example.com",
+		"aud": []string{"https://api.example.com", "https://example.auth0.com/userinfo"},
+	})
+	assert.NoError(t, err)
+
+	var standard jwt.StandardClaims
+	assert.IsType(t, &json.UnmarshalTypeError{}, jwt.VerifyHS256(secret, token, &standard))
+
+	var claims jwt.RegisteredClaims
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &claims))
+	assert.Equal(t, jwt.Audience{"https://api.example.com", "https://example.auth0.com/userinfo"}, claims.Audience)
+	assert.True(t, claims.Audience.Contains("https://api.example.com"))
+
+	// The single-string form works too.
+	token, err = jwt.SignHS256(secret, jwt.StandardClaims{Audience: "https://api.example.com"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &claims))
+	assert.Equal(t, jwt.Audience{"https://api.example.com"}, claims.Audience)
+}
+
+func ExampleRegisteredClaims() {
+	s, _ := json.Marshal(jwt.RegisteredClaims{
+		Subject:  "john@example.com",
+		Audience: jwt.Audience{"api"},
+	})
+
+	fmt.Println(string(s))
+
+	s, _ = json.Marshal(jwt.RegisteredClaims{
+		Subject:  "john@example.com",
+		Audience: jwt.Audience{"api", "web"},
+	})
+
+	fmt.Println(string(s))
+	// Output:
+	//
+	// {"sub":"john@example.com","aud":"api"}
+	// {"sub":"john@example.com","aud":["api","web"]}
+}
@@ -0,0 +1,176 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// IDFormat is the format of the "jti" that WithRandomID generates.
+type IDFormat int
+
+const (
+	// IDBase64URL formats a "jti" as its 128 random bits, base64url-encoded
+	// without padding. It is 22 characters long.
+	IDBase64URL IDFormat = iota
+
+	// IDUUID formats a "jti" as a version 4 (random) UUID, such as
+	// "0b6e42c1-5a3f-4e1d-9c27-8f4b3d2a1e6c". It has 122 random bits.
+	IDUUID
+)
+
+// WithIssuedAtNow makes a Sign function set the "iat" claim of the JWT to the
+// current time, in whole seconds since the Unix epoch.
+//
+// The claim is added to the JSON representation of the claims, so it works the
+// same whether you sign StandardClaims, a struct embedding StandardClaims, or a
+// map; the value you pass in is not modified. If the claims already have an
+// "iat", such as because you set IssuedAt yourself, it is left alone. The
+// claims must be a JSON object.
+//
+// The current time is time.Now(), unless WithNow is also passed.
+func WithIssuedAtNow() SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.issuedAtNow = true
+	})
+}
+
+// WithRandomID makes a Sign function set the "jti" claim of the JWT to 128
+// random bits from crypto/rand, formatted as format.
+//
+// As with WithIssuedAtNow, the claim is added to the JSON representation of
+// the claims, and a "jti" the claims already have is left alone. The claims
+// must be a JSON object.
+//
+// Use WithRandomReader to get random bits from somewhere other than
+// crypto/rand, such as in tests.
+func WithRandomID(format IDFormat) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.randomID = true
+		c.idFormat = format
+	})
+}
+
+// WithRandomReader makes WithRandomID read random bits from r instead of
+// crypto/rand.Reader. It is meant for tests and examples that need their
+// output to be the same every time. It does not change where any other
+// randomness comes from; pass a reader to SignES256WithRand for the nonces of
+// ECDSA signatures.
+func WithRandomReader(r io.Reader) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.rand = r
+	})
+}
+
+// NowOption is the option returned by WithNow and WithClock. It is a
+// SignOption, a VerifyOption, a TransportOption, and a MiddlewareOption.
+type NowOption struct {
+	now func() time.Time
+}
+
+func (o NowOption) applySign(c *signConfig) {
+	c.now = o.now
+}
+
+func (o NowOption) applyVerify(c *verifyConfig) {
+	c.checkTimes = true
+	c.now = o.now
+}
+
+// errClaimsNotObject is returned when WithIssuedAtNow or WithRandomID is used
+// with claims that aren't a JSON object.
+var errClaimsNotObject = errors.New("jwt: WithIssuedAtNow and WithRandomID require the claims to be a JSON object")
+
+// clock returns the current time, according to WithNow or WithClock if either
+// was passed.
+func (c *signConfig) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
+
+// addClaims returns the JSON-encoded claims, with the claims that
+// WithIssuedAtNow and WithRandomID generate added to them.
+//
+// The claims are added to the end of the existing JSON object, rather than by
+// re-encoding it, so the claims that were already there keep their order.
+func (c *signConfig) addClaims(claims []byte) ([]byte, error) {
+	if !c.issuedAtNow && !c.randomID {
+		return claims, nil
+	}
+
+	claims = bytes.TrimSpace(claims)
+
+	var m map[string]json.RawMessage
+	if len(claims) == 0 || claims[0] != '{' {
+		return nil, errClaimsNotObject
+	}
+
+	if err := json.Unmarshal(claims, &m); err != nil {
+		return nil, errClaimsNotObject
+	}
+
+	// Drop the closing brace, and add it back once the new claims are in.
+	out := claims[:len(claims)-1]
+	n := len(m)
+
+	add := func(name string, value []byte) {
+		if n > 0 {
+			out = append(out, ',')
+		}
+
+		out = append(out, '"')
+		out = append(out, name...)
+		out = append(out, '"', ':')
+		out = append(out, value...)
+		n++
+	}
+
+	if _, ok := m["iat"]; c.issuedAtNow && !ok {
+		add("iat", strconv.AppendInt(nil, c.clock().Unix(), 10))
+	}
+
+	if _, ok := m["jti"]; c.randomID && !ok {
+		id, err := c.generateID()
+		if err != nil {
+			return nil, err
+		}
+
+		add("jti", strconv.AppendQuote(nil, id))
+	}
+
+	return append(out, '}'), nil
+}
+
+// generateID returns a random "jti" in c.idFormat.
+func (c *signConfig) generateID() (string, error) {
+	r := c.rand
+	if r == nil {
+		r = rand.Reader
+	}
+
+	var b [16]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return "", fmt.Errorf("jwt: generating \"jti\": %w", err)
+	}
+
+	if c.idFormat != IDUUID {
+		return base64.RawURLEncoding.EncodeToString(b[:]), nil
+	}
+
+	// Set the version to 4, and the variant to RFC4122.
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
@@ -0,0 +1,134 @@
+package jwt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestAutoClaims(t *testing.T) {
+	secret := []byte("my secret key")
+	now := time.Unix(1600000000, 0)
+	clock := jwt.WithNow(func() time.Time { return now })
+
+	t.Run("standard claims", func(t *testing.T) {
+		in := jwt.StandardClaims{Subject: "jdoe@example.com"}
+		token, err := jwt.SignHS256(secret, in, jwt.WithIssuedAtNow(), jwt.WithRandomID(jwt.IDBase64URL), clock)
+		assert.NoError(t, err)
+
+		var out jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &out))
+		assert.Equal(t, "jdoe@example.com", out.Subject)
+		assert.Equal(t, int64(1600000000), out.IssuedAt)
+		assert.Regexp(t, `^[A-Za-z0-9_-]{22}$`, out.ID)
+
+		// The value passed in isn't modified.
+		assert.Equal(t, jwt.StandardClaims{Subject: "jdoe@example.com"}, in)
+
+		// Every token gets a different ID.
+		token, err = jwt.SignHS256(secret, in, jwt.WithRandomID(jwt.IDBase64URL))
+		assert.NoError(t, err)
+
+		var other jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &other))
+		assert.NotEqual(t, out.ID, other.ID)
+	})
+
+	t.Run("values already set are kept", func(t *testing.T) {
+		in := jwt.StandardClaims{IssuedAt: 1234, ID: "my-id"}
+		token, err := jwt.SignHS256(secret, in, jwt.WithIssuedAtNow(), jwt.WithRandomID(jwt.IDUUID), clock)
+		assert.NoError(t, err)
+
+		var out jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &out))
+		assert.Equal(t, in, out)
+	})
+
+	t.Run("embedded and map claims", func(t *testing.T) {
+		type customClaims struct {
+			jwt.StandardClaims
+			Scope string `json:"scope"`
+		}
+
+		token, err := jwt.SignHS256(secret, customClaims{Scope: "read"}, jwt.WithIssuedAtNow(), clock)
+		assert.NoError(t, err)
+
+		var out customClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &out))
+		assert.Equal(t, customClaims{StandardClaims: jwt.StandardClaims{IssuedAt: 1600000000}, Scope: "read"}, out)
+
+		token, err = jwt.SignHS256(secret, map[string]interface{}{}, jwt.WithIssuedAtNow(), jwt.WithRandomID(jwt.IDUUID), clock, jwt.WithRandomReader(zeroReader{}))
+		assert.NoError(t, err)
+
+		var m map[string]interface{}
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &m))
+		assert.Equal(t, map[string]interface{}{"iat": 1600000000.0, "jti": "00000000-0000-4000-8000-000000000000"}, m)
+	})
+
+	t.Run("existing claims keep their order", func(t *testing.T) {
+		token, err := jwt.SignHS256(secret, jwt.StandardClaims{Subject: "a", Issuer: "b"}, jwt.WithIssuedAtNow(), clock)
+		assert.NoError(t, err)
+
+		var raw json.RawMessage
+		assert.NoError(t, jwt.InsecureDecodeClaims(token, &raw))
+		assert.Equal(t, `{"iss":"b","sub":"a","iat":1600000000}`, string(raw))
+	})
+
+	t.Run("uuid format", func(t *testing.T) {
+		token, err := jwt.SignHS256(secret, jwt.StandardClaims{}, jwt.WithRandomID(jwt.IDUUID))
+		assert.NoError(t, err)
+
+		var out jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &out))
+		assert.True(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(out.ID), out.ID)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := jwt.SignHS256(secret, "not an object", jwt.WithIssuedAtNow())
+		assert.EqualError(t, err, "jwt: WithIssuedAtNow and WithRandomID require the claims to be a JSON object")
+
+		_, err = jwt.SignHS256(secret, nil, jwt.WithRandomID(jwt.IDBase64URL))
+		assert.Error(t, err)
+
+		_, err = jwt.SignHS256(secret, jwt.StandardClaims{}, jwt.WithRandomID(jwt.IDBase64URL), jwt.WithRandomReader(bytes.NewReader(make([]byte, 15))))
+		assert.EqualError(t, err, `jwt: generating "jti": unexpected EOF`)
+	})
+
+	t.Run("sign policy uses the same clock", func(t *testing.T) {
+		claims := jwt.StandardClaims{ExpirationTime: now.Add(time.Second).Unix()}
+		policy := jwt.WithSignPolicy(jwt.SignPolicy{RejectExpired: true})
+
+		_, err := jwt.SignHS256(secret, claims, policy, clock)
+		assert.NoError(t, err)
+
+		_, err = jwt.SignHS256(secret, claims, policy, jwt.WithNow(func() time.Time { return now.Add(time.Minute) }))
+		assert.True(t, errors.Is(err, jwt.ErrPolicyViolation))
+	})
+}
+
+func ExampleWithIssuedAtNow() {
+	token, err := jwt.SignHS256([]byte("my secret key"), jwt.StandardClaims{Subject: "jdoe@example.com"},
+		jwt.WithIssuedAtNow(),
+		jwt.WithRandomID(jwt.IDUUID),
+
+		// In tests and examples, fix the clock and the source of randomness so
+		// that the output is always the same.
+		jwt.WithNow(func() time.Time { return time.Unix(1600000000, 0) }),
+		jwt.WithRandomReader(zeroReader{}),
+	)
+
+	var claims jwt.StandardClaims
+	fmt.Println(err, jwt.InsecureDecodeClaims(token, &claims))
+	fmt.Println(claims.IssuedAt, claims.ID)
+	// Output:
+	//
+	// <nil> <nil>
+	// 1600000000 00000000-0000-4000-8000-000000000000
+}
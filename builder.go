@@ -0,0 +1,195 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrConflictingClaims is the error returned by the Sign methods of
+// TokenBuilder when the same claim was set more than once. The returned error
+// wraps ErrConflictingClaims, and names the claim.
+var ErrConflictingClaims = errors.New("jwt: conflicting claims")
+
+// TokenBuilder builds a set of claims, and signs them. It is a more concise
+// way to write the claims of a token than filling in a StandardClaims by hand:
+//
+//	token, err := jwt.NewToken().
+//		Subject("jdoe@example.com").
+//		Audience("api://me").
+//		ExpiresIn(15 * time.Minute).
+//		Claim("role", "admin").
+//		SignHS256(secret)
+//
+// TokenBuilder is only sugar. Its Sign methods pass a map[string]interface{}
+// of the claims to SignHS256, SignRS256, or SignES256, and so return exactly
+// the same bytes as passing that map yourself.
+//
+// Each claim may only be set once, whether by a method named after it or by
+// Claim. Setting a claim twice, such as by calling both ExpiresIn and
+// Claim("exp", ...), makes the Sign methods return an error wrapping
+// ErrConflictingClaims.
+//
+// Times given as durations, as with ExpiresIn, are turned into timestamps when
+// a Sign method is called, so one TokenBuilder can be used to sign many tokens.
+// A TokenBuilder is not safe for concurrent use.
+type TokenBuilder struct {
+	claims   map[string]interface{}
+	relative map[string]time.Duration
+	clock    Clock
+	err      error
+}
+
+// NewToken returns a TokenBuilder with no claims.
+func NewToken() *TokenBuilder {
+	return &TokenBuilder{
+		claims:   map[string]interface{}{},
+		relative: map[string]time.Duration{},
+		clock:    RealClock{},
+	}
+}
+
+// WithClock makes b use c, instead of RealClock, to get the current time. It
+// is meant for tests, and is the TokenBuilder counterpart of the WithClock
+// option. If c is nil, b goes back to using RealClock.
+func (b *TokenBuilder) WithClock(c Clock) *TokenBuilder {
+	if c == nil {
+		c = RealClock{}
+	}
+
+	b.clock = c
+	return b
+}
+
+// Issuer sets the "iss" claim.
+func (b *TokenBuilder) Issuer(iss string) *TokenBuilder {
+	return b.Claim("iss", iss)
+}
+
+// Subject sets the "sub" claim.
+func (b *TokenBuilder) Subject(sub string) *TokenBuilder {
+	return b.Claim("sub", sub)
+}
+
+// Audience sets the "aud" claim. If there is exactly one audience, "aud" is a
+// string; otherwise, it is an array. See Audience.
+func (b *TokenBuilder) Audience(aud ...string) *TokenBuilder {
+	return b.Claim("aud", Audience(aud))
+}
+
+// ID sets the "jti" claim.
+func (b *TokenBuilder) ID(jti string) *TokenBuilder {
+	return b.Claim("jti", jti)
+}
+
+// ExpiresAt sets the "exp" claim to t.
+func (b *TokenBuilder) ExpiresAt(t time.Time) *TokenBuilder {
+	return b.Claim("exp", t.Unix())
+}
+
+// ExpiresIn sets the "exp" claim to d after the time the token is signed.
+func (b *TokenBuilder) ExpiresIn(d time.Duration) *TokenBuilder {
+	return b.relativeClaim("exp", d)
+}
+
+// NotBefore sets the "nbf" claim to t.
+func (b *TokenBuilder) NotBefore(t time.Time) *TokenBuilder {
+	return b.Claim("nbf", t.Unix())
+}
+
+// IssuedNow sets the "iat" claim to the time the token is signed.
+func (b *TokenBuilder) IssuedNow() *TokenBuilder {
+	return b.relativeClaim("iat", 0)
+}
+
+// Claim sets the claim called name to v. v should be compatible with the
+// encoding/json package of the standard library.
+func (b *TokenBuilder) Claim(name string, v interface{}) *TokenBuilder {
+	if b.checkUnset(name) {
+		b.claims[name] = v
+	}
+
+	return b
+}
+
+// relativeClaim sets the claim called name to d after the time the token is
+// signed.
+func (b *TokenBuilder) relativeClaim(name string, d time.Duration) *TokenBuilder {
+	if b.checkUnset(name) {
+		b.relative[name] = d
+	}
+
+	return b
+}
+
+// checkUnset returns whether the claim called name has yet to be set. If it has
+// been set already, checkUnset records an error, which the Sign methods
+// return.
+func (b *TokenBuilder) checkUnset(name string) bool {
+	_, ok := b.claims[name]
+	_, okRelative := b.relative[name]
+	if ok || okRelative {
+		if b.err == nil {
+			b.err = fmt.Errorf("%w: %q is set more than once", ErrConflictingClaims, name)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Claims returns the claims b would sign if a Sign method were called now. It
+// returns an error wrapping ErrConflictingClaims if a claim was set more than
+// once.
+//
+// The returned map is a new one each time; changing it doesn't affect b.
+func (b *TokenBuilder) Claims() (map[string]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	now := b.clock.Now()
+	claims := make(map[string]interface{}, len(b.claims)+len(b.relative))
+	for name, v := range b.claims {
+		claims[name] = v
+	}
+
+	for name, d := range b.relative {
+		claims[name] = now.Add(d).Unix()
+	}
+
+	return claims, nil
+}
+
+// SignHS256 signs the claims in b with SignHS256.
+func (b *TokenBuilder) SignHS256(secret []byte, opts ...SignOption) ([]byte, error) {
+	claims, err := b.Claims()
+	if err != nil {
+		return nil, err
+	}
+
+	return SignHS256(secret, claims, opts...)
+}
+
+// SignRS256 signs the claims in b with SignRS256.
+func (b *TokenBuilder) SignRS256(priv *rsa.PrivateKey, opts ...SignOption) ([]byte, error) {
+	claims, err := b.Claims()
+	if err != nil {
+		return nil, err
+	}
+
+	return SignRS256(priv, claims, opts...)
+}
+
+// SignES256 signs the claims in b with SignES256.
+func (b *TokenBuilder) SignES256(priv *ecdsa.PrivateKey, opts ...SignOption) ([]byte, error) {
+	claims, err := b.Claims()
+	if err != nil {
+		return nil, err
+	}
+
+	return SignES256(priv, claims, opts...)
+}
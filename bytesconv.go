@@ -0,0 +1,35 @@
+package jwt
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// stringBytes returns the bytes of s without copying them.
+//
+// The returned slice shares memory with s, which Go assumes never changes. It
+// must only be read, and must not be kept after the call that needed it
+// returns. The Verify functions satisfy both: they only ever read the token,
+// and everything they keep from it is a freshly-decoded copy.
+func stringBytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+
+	var b []byte
+	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	bh.Data = sh.Data
+	bh.Len = sh.Len
+	bh.Cap = sh.Len
+	return b
+}
+
+// bytesString returns b as a string without copying it.
+//
+// b must never be modified afterwards. The Sign functions satisfy this, because
+// the buffer they build the token in is not referenced by anything else once
+// they return.
+func bytesString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
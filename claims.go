@@ -0,0 +1,119 @@
+package jwt
+
+import "time"
+
+// RegisteredClaims is the set of claims registered by RFC7519.
+//
+// RegisteredClaims is like StandardClaims, with two differences:
+//
+// * Its Audience field can hold either of the two forms RFC7519 allows for
+// "aud": a single string, or an array of strings. Tokens from identity
+// providers that use the array form can't be unmarshalled into StandardClaims,
+// but they can be unmarshalled into RegisteredClaims.
+//
+// * Its timestamps are NumericDate values, rather than int64 values. This
+// makes it impossible to mistakenly use milliseconds or nanoseconds instead of
+// seconds.
+//
+// StandardClaims remains for compatibility. New code should prefer
+// RegisteredClaims.
+//
+// As with StandardClaims, all fields of this struct are omitted if left to
+// their zero values, and you can embed RegisteredClaims in your own struct to
+// add claims of your own. For more details on the standard JWT claims, see:
+//
+// https://tools.ietf.org/html/rfc7519#section-4.1
+type RegisteredClaims struct {
+	// Issuer identifies who issued the JWT.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.1
+	Issuer string `json:"iss,omitempty"`
+
+	// Subject identifies who the JWT is about.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.2
+	Subject string `json:"sub,omitempty"`
+
+	// Audience identifies who is meant to process the JWT.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.3
+	Audience Audience `json:"aud,omitempty"`
+
+	// ExpirationTime indicates when the JWT expires.
+	//
+	// VerifyExpirationTime can help you verify whether tokens have expired.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.4
+	ExpirationTime *NumericDate `json:"exp,omitempty"`
+
+	// NotBefore indicates when the JWT becomes valid.
+	//
+	// VerifyNotBefore can help you verify whether a token is valid yet.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.5
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+
+	// IssuedAt indicates when the JWT was issued.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.6
+	IssuedAt *NumericDate `json:"iat,omitempty"`
+
+	// ID is a unique identifier for the JWT.
+	//
+	// https://tools.ietf.org/html/rfc7519#section-4.1.7
+	ID string `json:"jti,omitempty"`
+}
+
+// VerifyExpirationTime checks ExpirationTime ("exp") to see if a JWT has
+// expired, and returns ErrExpiredToken if the token is expired.
+//
+// In production, you should usually pass time.Now() as the now argument to this
+// function. But in your tests you may want to use a hard-coded time instead.
+//
+// If ExpirationTime is nil, then the token has no expiration time, and
+// VerifyExpirationTime returns nil.
+func (c *RegisteredClaims) VerifyExpirationTime(now time.Time) error {
+	if c.ExpirationTime != nil && now.After(c.ExpirationTime.Time()) {
+		return ErrExpiredToken
+	}
+
+	return nil
+}
+
+// VerifyNotBefore checks NotBefore ("nbf") to see if a JWT is not yet valid,
+// and returns ErrNotYetValid if the token is not yet valid.
+//
+// In production, you should usually pass time.Now() as the now argument to this
+// function. But in your tests you may want to use a hard-coded time instead.
+//
+// If NotBefore is nil, then the token has no "not before" time, and
+// VerifyNotBefore returns nil.
+func (c *RegisteredClaims) VerifyNotBefore(now time.Time) error {
+	if c.NotBefore != nil && now.Before(c.NotBefore.Time()) {
+		return ErrNotYetValid
+	}
+
+	return nil
+}
+
+// Valid checks both ExpirationTime ("exp") and NotBefore ("nbf"), and returns
+// the first error it finds. It returns ErrExpiredToken if the token is
+// expired, and ErrNotYetValid if the token is not yet valid. Nil fields are
+// not checked.
+func (c *RegisteredClaims) Valid(now time.Time) error {
+	if err := c.VerifyExpirationTime(now); err != nil {
+		return err
+	}
+
+	return c.VerifyNotBefore(now)
+}
+
+// RequireClaims checks that each of ExpirationTime ("exp"), NotBefore
+// ("nbf"), and IssuedAt ("iat") is present, if the corresponding argument is
+// true. It returns an error wrapping ErrMissingClaim for the first one that is
+// missing. A claim is considered missing if it is nil.
+//
+// See StandardClaims.RequireClaims for more details.
+func (c *RegisteredClaims) RequireClaims(exp, nbf, iat bool) error {
+	return requireClaims(exp && c.ExpirationTime == nil, nbf && c.NotBefore == nil, iat && c.IssuedAt == nil)
+}
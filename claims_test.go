@@ -0,0 +1,100 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestRegisteredClaimsVerifyExpirationTime(t *testing.T) {
+	claims := jwt.RegisteredClaims{ExpirationTime: jwt.NumericDateFromTime(time.Unix(1, 0))}
+	assert.NoError(t, claims.VerifyExpirationTime(time.Unix(0, 0)))
+	assert.NoError(t, claims.VerifyExpirationTime(time.Unix(1, 0)))
+	assert.Equal(t, jwt.ErrExpiredToken, claims.VerifyExpirationTime(time.Unix(2, 0)))
+
+	// No exp means no expiration.
+	claims = jwt.RegisteredClaims{}
+	assert.NoError(t, claims.VerifyExpirationTime(time.Unix(2, 0)))
+}
+
+func TestRegisteredClaimsVerifyNotBefore(t *testing.T) {
+	claims := jwt.RegisteredClaims{NotBefore: jwt.NumericDateFromTime(time.Unix(1, 0))}
+	assert.Equal(t, jwt.ErrNotYetValid, claims.VerifyNotBefore(time.Unix(0, 0)))
+	assert.NoError(t, claims.VerifyNotBefore(time.Unix(1, 0)))
+	assert.NoError(t, claims.VerifyNotBefore(time.Unix(2, 0)))
+
+	// No nbf means the token is valid from the beginning of time.
+	claims = jwt.RegisteredClaims{}
+	assert.NoError(t, claims.VerifyNotBefore(time.Unix(0, 0)))
+}
+
+func TestRegisteredClaimsValid(t *testing.T) {
+	nbf := jwt.NumericDateFromTime(time.Unix(10, 0))
+	exp := jwt.NumericDateFromTime(time.Unix(20, 0))
+
+	claims := jwt.RegisteredClaims{NotBefore: nbf, ExpirationTime: exp}
+	assert.Equal(t, jwt.ErrNotYetValid, claims.Valid(time.Unix(5, 0)))
+	assert.NoError(t, claims.Valid(time.Unix(15, 0)))
+	assert.Equal(t, jwt.ErrExpiredToken, claims.Valid(time.Unix(25, 0)))
+
+	claims = jwt.RegisteredClaims{}
+	assert.NoError(t, claims.Valid(time.Unix(0, 0)))
+}
+
+func TestRegisteredClaimsRequireClaims(t *testing.T) {
+	now := jwt.NumericDateFromTime(time.Unix(0, 0))
+
+	// Unlike with StandardClaims, a timestamp of zero is not the same thing as
+	// a missing one.
+	claims := jwt.RegisteredClaims{ExpirationTime: now, NotBefore: now, IssuedAt: now}
+	assert.NoError(t, claims.RequireClaims(true, true, true))
+
+	claims = jwt.RegisteredClaims{}
+	assert.NoError(t, claims.RequireClaims(false, false, false))
+	assert.True(t, errors.Is(claims.RequireClaims(true, false, false), jwt.ErrMissingClaim))
+	assert.True(t, errors.Is(claims.RequireClaims(false, true, false), jwt.ErrMissingClaim))
+	assert.True(t, errors.Is(claims.RequireClaims(false, false, true), jwt.ErrMissingClaim))
+}
+
+func TestRegisteredClaimsJSON(t *testing.T) {
+	secret := []byte("my secret key")
+
+	token, err := jwt.SignHS256(secret, map[string]interface{}{
+		"sub": "jdoe@example.com",
+		"exp": 1300819380,
+		"nbf": 1300819380.5,
+	})
+	assert.NoError(t, err)
+
+	var claims jwt.RegisteredClaims
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &claims))
+	assert.Equal(t, time.Unix(1300819380, 0), claims.ExpirationTime.Time())
+	assert.Equal(t, time.Unix(1300819380, 5e8), claims.NotBefore.Time())
+	assert.Nil(t, claims.IssuedAt)
+
+	out, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"sub":"jdoe@example.com","exp":1300819380,"nbf":1300819380.5}`, string(out))
+}
+
+func ExampleRegisteredClaims_VerifyExpirationTime() {
+	exp, _ := time.Parse(time.RFC3339, "2015-05-19T16:45:40-07:00")
+	claims := jwt.RegisteredClaims{ExpirationTime: jwt.NumericDateFromTime(exp)}
+
+	// nowBeforeExp is one second before exp
+	nowBeforeExp, _ := time.Parse(time.RFC3339, "2015-05-19T16:45:39-07:00")
+	fmt.Println(claims.VerifyExpirationTime(nowBeforeExp))
+
+	// nowAfterExp is one second after exp
+	nowAfterExp, _ := time.Parse(time.RFC3339, "2015-05-19T16:45:41-07:00")
+	fmt.Println(claims.VerifyExpirationTime(nowAfterExp))
+	// Output:
+	//
+	// <nil>
+	// jwt: expired token
+}
@@ -0,0 +1,44 @@
+package jwt
+
+import "time"
+
+// Clock tells the current time. This package asks a Clock what time it is when
+// checking "exp" and "nbf", when setting "iat", and when deciding whether a
+// cached token is due to be refreshed.
+//
+// Unless it is given a Clock with WithClock, this package uses RealClock. Tests
+// can instead use a Clock that always returns the same time, such as
+// jwttest.FixedClock, to check how tokens are treated just before and just
+// after they expire.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is a Clock that returns the actual current time.
+type RealClock struct{}
+
+// Now returns time.Now().
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock is like WithNow, except that it gets the current time from c. Like
+// WithNow, it makes a Verify function check the "exp" and "nbf" claims of JWTs.
+//
+// WithClock can be passed to a Sign function, a Verify function, NewTransport,
+// or Middleware, so that all of them can be given the same Clock. The same
+// Clock can also be given to TokenBuilder.WithClock, and to a
+// MemoryReplayStore.
+func WithClock(c Clock) NowOption {
+	return NowOption{now: c.Now}
+}
+
+// clock returns the current time, according to WithNow or WithClock if either
+// was passed.
+func (c *verifyConfig) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
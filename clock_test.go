@@ -0,0 +1,129 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// fixedClock is a jwt.Clock that is stopped at a particular time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestRealClock(t *testing.T) {
+	before := time.Now()
+	now := jwt.RealClock{}.Now()
+	assert.False(t, now.Before(before))
+	assert.False(t, now.After(time.Now()))
+}
+
+func TestWithClock(t *testing.T) {
+	secret := []byte("my secret key")
+	then := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := fixedClock(then)
+
+	t.Run("sign", func(t *testing.T) {
+		token, err := jwt.SignHS256(secret, jwt.StandardClaims{}, jwt.WithIssuedAtNow(), jwt.WithClock(clock))
+		assert.NoError(t, err)
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &claims))
+		assert.Equal(t, then.Unix(), claims.IssuedAt)
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		token, err := jwt.SignHS256(secret, jwt.StandardClaims{
+			NotBefore:      then.Add(-time.Minute).Unix(),
+			ExpirationTime: then.Add(time.Minute).Unix(),
+		})
+
+		assert.NoError(t, err)
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &claims, jwt.WithClock(clock)))
+
+		// The token expired long ago, and WithClock makes the Verify functions
+		// check "exp" even without WithLeeway.
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, token, &claims, jwt.WithClock(jwt.RealClock{})))
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, token, &claims, jwt.WithClock(fixedClock(then.Add(2*time.Minute)))))
+		assert.Equal(t, jwt.ErrNotYetValid, jwt.VerifyHS256(secret, token, &claims, jwt.WithClock(fixedClock(then.Add(-2*time.Minute)))))
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		token, err := jwt.SignHS256(secret, jwt.StandardClaims{ExpirationTime: then.Add(time.Minute).Unix()})
+		assert.NoError(t, err)
+
+		serve := func(opts ...jwt.MiddlewareOption) int {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "Bearer "+string(token))
+
+			w := httptest.NewRecorder()
+			next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+			jwt.Middleware(next, append([]jwt.MiddlewareOption{jwt.WithHS256Secret(secret)}, opts...)...).ServeHTTP(w, r)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusUnauthorized, serve())
+		assert.Equal(t, http.StatusOK, serve(jwt.WithClock(clock)))
+		assert.Equal(t, http.StatusOK, serve(jwt.WithVerifyOptions(jwt.WithClock(clock))))
+	})
+
+	t.Run("x5c", func(t *testing.T) {
+		rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+
+		leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+
+		// Certificates that were valid only around then.
+		validity := x509.Certificate{NotBefore: then.Add(-time.Hour), NotAfter: then.Add(time.Hour)}
+		root := newCert(t, &rootKey.PublicKey, nil, rootKey, validity)
+		leaf := newCert(t, &leafKey.PublicKey, root, rootKey, validity)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+
+		token, err := jwt.SignES256(leafKey, jwt.StandardClaims{Subject: "jdoe"}, x5c(leaf))
+		assert.NoError(t, err)
+
+		var claims jwt.StandardClaims
+		_, err = jwt.VerifyX5C(roots, "ES256", token, &claims)
+		assert.Equal(t, jwt.ErrBadSignature, err)
+
+		_, err = jwt.VerifyX5C(roots, "ES256", token, &claims, jwt.WithClock(clock))
+		assert.NoError(t, err)
+		assert.Equal(t, "jdoe", claims.Subject)
+	})
+
+	t.Run("replay store", func(t *testing.T) {
+		ctx := context.Background()
+		store := jwt.MemoryReplayStore{Clock: clock}
+
+		// The store's clock, not the real one, decides whether a token ID can
+		// be forgotten.
+		seen, err := store.Seen(ctx, "a", then.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, seen)
+
+		seen, err = store.Seen(ctx, "a", then.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, seen)
+
+		store.Clock = fixedClock(then.Add(2 * time.Minute))
+		seen, err = store.Seen(ctx, "a", then.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, seen)
+	})
+}
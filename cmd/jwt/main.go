@@ -0,0 +1,484 @@
+// Command jwt signs, verifies, and inspects JWTs from the command line, so
+// that debugging a token never means pasting it into a website.
+//
+// Usage:
+//
+//	jwt decode [token]
+//	jwt verify -alg ALG -key FILE [-iss ISS] [-aud AUD] [-leeway D] [token]
+//	jwt sign -alg ALG -key FILE [-claims FILE] [-claim NAME=VALUE]... [-sub SUB] [-iss ISS] [-aud AUD] [-exp D] [-kid KID]
+//
+// If token is left out, it is read from standard input. Whitespace around it
+// is ignored.
+//
+// decode prints the header and claims of a token as JSON, without verifying
+// it. Its output says that it is untrusted, because it is: anyone can make a
+// token that says anything.
+//
+// verify verifies a token, and prints its claims as JSON. The algorithm must
+// always be given with -alg; verify never uses the token's "alg" to decide how
+// to verify it. It exits with status 1 if the token is rejected. Unlike the
+// Verify functions in the jwt package, verify always checks "exp" and "nbf".
+//
+// sign prints a new token. Claims are read from the JSON object in -claims, if
+// any, and then from the other flags; setting the same claim twice is an
+// error. With -exp, the token expires that long after it is signed. -claim
+// values are used as JSON if they are valid JSON, and as strings otherwise.
+//
+// For verify, -key is a PEM-encoded public key or certificate, or a JWK. For
+// sign, it is a PEM-encoded private key, or a JWK. For HS256 and HS512, -key
+// may also be a file containing the secret in hex or base64; see
+// jwt.DecodeSecret.
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ucarion/jwt"
+)
+
+// errUsage is returned by subcommands when their arguments are wrong. The flag
+// package has already said why by the time it's returned.
+var errUsage = errors.New("usage")
+
+// errRejected is returned by verify when the token is rejected, to tell it
+// apart from errors in how verify was invoked.
+var errRejected = errors.New("token rejected")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the jwt command with args, and returns the status it should exit
+// with.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: jwt decode|verify|sign [flags] [token]")
+		return 2
+	}
+
+	commands := map[string]func([]string, io.Reader, io.Writer, io.Writer) error{
+		"decode": decode,
+		"verify": verify,
+		"sign":   sign,
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "jwt: unknown command %q\nusage: jwt decode|verify|sign [flags] [token]\n", args[0])
+		return 2
+	}
+
+	err := cmd(args[1:], stdin, stdout, stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errRejected):
+		fmt.Fprintln(stderr, err)
+		return 1
+	default:
+		fmt.Fprintln(stderr, "jwt:", err)
+		return 1
+	}
+}
+
+// decodedToken is what decode prints.
+type decodedToken struct {
+	Warning string          `json:"warning"`
+	Header  json.RawMessage `json:"header"`
+	Claims  json.RawMessage `json:"claims"`
+}
+
+// untrustedWarning is the warning that decode prints alongside what it
+// decodes.
+const untrustedWarning = "UNTRUSTED: this token has not been verified, and anyone could have made it"
+
+func decode(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("decode", stderr)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	token, err := readToken(fs, stdin)
+	if err != nil {
+		return err
+	}
+
+	// PeekHeader and InsecureDecodeClaims reject anything the Verify functions
+	// would reject as malformed. Once they've accepted the token, its parts are
+	// printed as they are, rather than as the jwt package would re-encode them.
+	if _, err := jwt.PeekHeader(token); err != nil {
+		return err
+	}
+
+	var claims json.RawMessage
+	if err := jwt.InsecureDecodeClaims(token, &claims); err != nil {
+		return err
+	}
+
+	encodedHeader, _, _, err := jwt.Split(token)
+	if err != nil {
+		return err
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(string(encodedHeader))
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(stdout, decodedToken{Warning: untrustedWarning, Header: header, Claims: claims})
+}
+
+func verify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("verify", stderr)
+	alg := fs.String("alg", "", "the algorithm the token must be signed with (required)")
+	keyFile := fs.String("key", "", "the file containing the public key or secret to verify with (required)")
+	iss := fs.String("iss", "", "if set, the token's \"iss\" must be this")
+	aud := fs.String("aud", "", "if set, the token's \"aud\" must include this")
+	leeway := fs.Duration("leeway", 0, "how much clock skew to allow when checking \"exp\" and \"nbf\"")
+
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *alg == "" || *keyFile == "" {
+		fmt.Fprintln(stderr, "jwt verify: -alg and -key are required")
+		fs.Usage()
+		return errUsage
+	}
+
+	if !isSupported(*alg) {
+		return fmt.Errorf("%w: %q", jwt.ErrUnsupportedAlgorithm, *alg)
+	}
+
+	key, err := readKey(*keyFile, *alg, false)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := allowKey(*alg, key)
+	if err != nil {
+		return err
+	}
+
+	token, err := readToken(fs, stdin)
+	if err != nil {
+		return err
+	}
+
+	opts := []jwt.VerifyOption{allowed, jwt.WithLeeway(*leeway)}
+	if *iss != "" {
+		opts = append(opts, jwt.WithExpectedIssuer(*iss))
+	}
+
+	if *aud != "" {
+		opts = append(opts, jwt.WithExpectedAudience(*aud))
+	}
+
+	var claims json.RawMessage
+	if _, err := jwt.VerifyAny(token, &claims, opts...); err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+
+	return writeJSON(stdout, claims)
+}
+
+func sign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("sign", stderr)
+	alg := fs.String("alg", "", "the algorithm to sign the token with (required)")
+	keyFile := fs.String("key", "", "the file containing the private key or secret to sign with (required)")
+	claimsFile := fs.String("claims", "", "a file containing a JSON object of claims, or - for standard input")
+	sub := fs.String("sub", "", "the \"sub\" claim")
+	iss := fs.String("iss", "", "the \"iss\" claim")
+	aud := fs.String("aud", "", "the \"aud\" claim")
+	exp := fs.Duration("exp", 0, "if set, the token expires this long after it is signed")
+	kid := fs.String("kid", "", "the \"kid\" header parameter")
+
+	var extra claimFlags
+	fs.Var(&extra, "claim", "a claim, as NAME=VALUE; may be repeated")
+
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *alg == "" || *keyFile == "" || fs.NArg() != 0 {
+		fmt.Fprintln(stderr, "jwt sign: -alg and -key are required, and there are no arguments")
+		fs.Usage()
+		return errUsage
+	}
+
+	if !isSupported(*alg) {
+		return fmt.Errorf("%w: %q", jwt.ErrUnsupportedAlgorithm, *alg)
+	}
+
+	key, err := readKey(*keyFile, *alg, true)
+	if err != nil {
+		return err
+	}
+
+	if err := jwt.CheckPrivateKey(*alg, key); err != nil {
+		return err
+	}
+
+	b := jwt.NewToken()
+	if *claimsFile != "" {
+		data, err := readFile(*claimsFile, stdin)
+		if err != nil {
+			return err
+		}
+
+		// Numbers are kept as they are written, rather than turned into float64,
+		// so that large ones don't lose precision.
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+
+		var claims map[string]interface{}
+		if err := dec.Decode(&claims); err != nil {
+			return fmt.Errorf("%s: claims must be a JSON object: %w", *claimsFile, err)
+		}
+
+		for name, v := range claims {
+			b.Claim(name, v)
+		}
+	}
+
+	for _, c := range []struct{ name, value string }{{"sub", *sub}, {"iss", *iss}, {"aud", *aud}} {
+		if c.value != "" {
+			b.Claim(c.name, c.value)
+		}
+	}
+
+	for _, c := range extra {
+		b.Claim(c.name, c.value)
+	}
+
+	if *exp != 0 {
+		b.ExpiresIn(*exp)
+	}
+
+	claims, err := b.Claims()
+	if err != nil {
+		return err
+	}
+
+	token, err := signKey(*alg, key, claims, jwt.WithKeyID(*kid))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(stdout, "%s\n", token)
+	return err
+}
+
+// newFlagSet returns a FlagSet for the subcommand name, which reports errors to
+// stderr rather than exiting.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("jwt "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// claimFlags is the value of the repeatable -claim flag of sign.
+type claimFlags []struct {
+	name  string
+	value interface{}
+}
+
+func (c *claimFlags) String() string {
+	return ""
+}
+
+func (c *claimFlags) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return errors.New("must be NAME=VALUE")
+	}
+
+	name, raw := s[:i], s[i+1:]
+
+	var value interface{} = raw
+	if json.Valid([]byte(raw)) {
+		value = json.RawMessage(raw)
+	}
+
+	*c = append(*c, struct {
+		name  string
+		value interface{}
+	}{name, value})
+
+	return nil
+}
+
+// readToken returns the token given as the only argument in fs, or read from
+// stdin if there are no arguments.
+func readToken(fs *flag.FlagSet, stdin io.Reader) ([]byte, error) {
+	switch fs.NArg() {
+	case 0:
+		b, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.TrimSpace(b), nil
+	case 1:
+		return []byte(strings.TrimSpace(fs.Arg(0))), nil
+	default:
+		fs.Usage()
+		return nil, errUsage
+	}
+}
+
+// readFile returns the contents of the file at path, or of stdin if path is
+// "-".
+func readFile(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+// readKey reads the key in the file at path. It is a private key if private
+// is true, and a public key otherwise. Secrets for alg HS256 and HS512 may be
+// in hex or base64, as well as in a JWK.
+func readKey(path, alg string, private bool) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		key, err = jwt.ParseJWK(trimmed)
+	case bytes.Contains(trimmed, []byte("-----BEGIN")):
+		if private {
+			key, err = jwt.ParsePrivateKeyPEM(trimmed)
+		} else {
+			key, err = parsePublicKeyPEM(trimmed)
+		}
+	case alg == "HS256" || alg == "HS512":
+		key, err = jwt.DecodeSecret(string(trimmed))
+	default:
+		return nil, fmt.Errorf("%s: key must be PEM or a JWK", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// A private key can be used to verify, as if it were its public key.
+	if signer, ok := key.(crypto.Signer); ok && !private {
+		key = signer.Public()
+	}
+
+	return key, nil
+}
+
+// parsePublicKeyPEM parses a PEM-encoded public key or certificate, or the
+// public key of a PEM-encoded private key.
+func parsePublicKeyPEM(data []byte) (interface{}, error) {
+	key, err := jwt.ParsePublicKeyPEM(data)
+	if err == nil {
+		return key, nil
+	}
+
+	if priv, privErr := jwt.ParsePrivateKeyPEM(data); privErr == nil {
+		return priv, nil
+	}
+
+	return nil, err
+}
+
+// allowKey returns the jwt.AllowedAlgorithm for alg and key, or an error if
+// key can't be used with alg. alg must be supported; see isSupported.
+func allowKey(alg string, key interface{}) (jwt.AllowedAlgorithm, error) {
+	switch k := key.(type) {
+	case []byte:
+		switch alg {
+		case "HS256":
+			return jwt.AllowHS256(k), nil
+		case "HS512":
+			return jwt.AllowHS512(k), nil
+		}
+	case *rsa.PublicKey:
+		switch alg {
+		case "RS256":
+			return jwt.AllowRS256(k), nil
+		case "RS384":
+			return jwt.AllowRS384(k), nil
+		case "PS256":
+			return jwt.AllowPS256(k), nil
+		}
+	case *ecdsa.PublicKey:
+		switch alg {
+		case "ES256":
+			return jwt.AllowES256(k), nil
+		case "ES512":
+			return jwt.AllowES512(k), nil
+		}
+	case ed25519.PublicKey:
+		if alg == "EdDSA" {
+			return jwt.AllowEdDSA(k), nil
+		}
+	}
+
+	return jwt.AllowedAlgorithm{}, fmt.Errorf("%w: %s can't be used with a %T", jwt.ErrInvalidKey, alg, key)
+}
+
+// signKey signs claims with alg and key. alg must be supported, and key must
+// already have been checked with jwt.CheckPrivateKey.
+func signKey(alg string, key interface{}, claims interface{}, opts ...jwt.SignOption) ([]byte, error) {
+	switch alg {
+	case "HS256":
+		return jwt.SignHS256(key.([]byte), claims, opts...)
+	case "HS512":
+		return jwt.SignHS512(key.([]byte), claims, opts...)
+	case "RS256":
+		return jwt.SignRS256(key.(*rsa.PrivateKey), claims, opts...)
+	case "RS384":
+		return jwt.SignRS384(key.(*rsa.PrivateKey), claims, opts...)
+	case "PS256":
+		return jwt.SignPS256(key.(*rsa.PrivateKey), claims, opts...)
+	case "ES256":
+		return jwt.SignES256(key.(*ecdsa.PrivateKey), claims, opts...)
+	case "ES512":
+		return jwt.SignES512(key.(*ecdsa.PrivateKey), claims, opts...)
+	case "EdDSA":
+		return jwt.SignEdDSA(key.(ed25519.PrivateKey), claims, opts...)
+	}
+
+	return nil, fmt.Errorf("%w: %q", jwt.ErrUnsupportedAlgorithm, alg)
+}
+
+// isSupported returns whether alg is one of the algorithms this command
+// supports.
+func isSupported(alg string) bool {
+	switch alg {
+	case "HS256", "HS512", "RS256", "RS384", "PS256", "ES256", "ES512", "EdDSA":
+		return true
+	}
+
+	return false
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// runJWT runs the jwt command with args and stdin, and returns its exit status
+// and what it wrote to stdout and stderr.
+func runJWT(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFiles writes files into a new temporary directory, and returns the
+// directory.
+func writeFiles(t *testing.T, files map[string][]byte) string {
+	dir, err := ioutil.TempDir("", "jwt")
+	assert.NoError(t, err)
+
+	for name, data := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+
+	return dir
+}
+
+func TestSignVerifyDecode(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	assert.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+
+	pubJWK, err := jwt.MarshalJWK(&priv.PublicKey)
+	assert.NoError(t, err)
+
+	dir := writeFiles(t, map[string][]byte{
+		"priv.pem":    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		"pub.pem":     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}),
+		"pub.json":    pubJWK,
+		"secret":      []byte("00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff\n"),
+		"claims.json": []byte(`{"role":"admin","n":12345678901234567890}`),
+	})
+
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	code, token, stderr := runJWT("", "sign", "-alg", "ES256", "-key", path("priv.pem"), "-claims", path("claims.json"), "-sub", "jdoe", "-claim", "tenant=acme", "-claim", "level=3", "-exp", "1h", "-kid", "k1")
+	assert.Equal(t, 0, code, stderr)
+
+	for _, key := range []string{"pub.pem", "pub.json", "priv.pem"} {
+		code, stdout, stderr := runJWT(token, "verify", "-alg", "ES256", "-key", path(key))
+		assert.Equal(t, 0, code, stderr)
+
+		var claims map[string]interface{}
+		dec := json.NewDecoder(strings.NewReader(stdout))
+		dec.UseNumber()
+		assert.NoError(t, dec.Decode(&claims))
+		assert.Equal(t, "jdoe", claims["sub"])
+		assert.Equal(t, "admin", claims["role"])
+		assert.Equal(t, "acme", claims["tenant"])
+		assert.Equal(t, json.Number("3"), claims["level"])
+		assert.Equal(t, json.Number("12345678901234567890"), claims["n"])
+		assert.Contains(t, claims, "exp")
+	}
+
+	// The token can be an argument instead.
+	code, _, stderr = runJWT("", "verify", "-alg", "ES256", "-key", path("pub.pem"), strings.TrimSpace(token))
+	assert.Equal(t, 0, code, stderr)
+
+	code, stdout, stderr := runJWT(token, "decode")
+	assert.Equal(t, 0, code, stderr)
+
+	var decoded struct {
+		Warning string
+		Header  jwt.Header
+		Claims  map[string]interface{}
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &decoded))
+	assert.Contains(t, decoded.Warning, "UNTRUSTED")
+	assert.Equal(t, jwt.Header{Type: "JWT", Algorithm: "ES256", KeyID: "k1"}, decoded.Header)
+	assert.Equal(t, "jdoe", decoded.Claims["sub"])
+
+	// HS256, with a secret in hex.
+	code, token, stderr = runJWT("", "sign", "-alg", "HS256", "-key", path("secret"), "-iss", "me")
+	assert.Equal(t, 0, code, stderr)
+
+	code, stdout, stderr = runJWT(token, "verify", "-alg", "HS256", "-key", path("secret"), "-iss", "me")
+	assert.Equal(t, 0, code, stderr)
+	assert.JSONEq(t, `{"iss":"me"}`, stdout)
+
+	code, _, stderr = runJWT(token, "verify", "-alg", "HS256", "-key", path("secret"), "-iss", "you")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "token rejected")
+
+	// The algorithm is never taken from the token.
+	code, stdout, stderr = runJWT(token, "verify", "-alg", "HS512", "-key", path("secret"))
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "", stdout)
+	assert.Equal(t, "token rejected: jwt: wrong algorithm\n", stderr)
+
+	code, _, stderr = runJWT(token, "verify", "-key", path("secret"))
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "-alg and -key are required")
+
+	code, _, stderr = runJWT(token, "verify", "-alg", "ES256", "-key", path("secret"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "key must be PEM or a JWK")
+
+	code, _, stderr = runJWT(token, "verify", "-alg", "HS256", "-key", path("pub.pem"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid key")
+
+	code, _, stderr = runJWT(token, "verify", "-alg", "none", "-key", path("secret"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "unsupported algorithm")
+
+	// Expired tokens are rejected.
+	code, token, stderr = runJWT("", "sign", "-alg", "HS256", "-key", path("secret"), "-exp", "-1h")
+	assert.Equal(t, 0, code, stderr)
+
+	code, _, stderr = runJWT(token, "verify", "-alg", "HS256", "-key", path("secret"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "expired")
+
+	code, _, stderr = runJWT(token, "verify", "-alg", "HS256", "-key", path("secret"), "-leeway", "2h")
+	assert.Equal(t, 0, code, stderr)
+
+	// Claims can't be set twice.
+	code, _, stderr = runJWT(`{"sub":"a"}`, "sign", "-alg", "HS256", "-key", path("secret"), "-claims", "-", "-sub", "b")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "conflicting claims")
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	for _, token := range []string{"", "a.b", "bm90IGpzb24.e30.AAAA", "e30.bm90IGpzb24.AAAA"} {
+		code, stdout, stderr := runJWT(token, "decode")
+		assert.Equal(t, 1, code, token)
+		assert.Equal(t, "", stdout, token)
+		assert.Contains(t, stderr, "malformed token", token)
+	}
+
+	code, _, _ := runJWT("", "frobnicate")
+	assert.Equal(t, 2, code)
+
+	code, _, _ = runJWT("")
+	assert.Equal(t, 2, code)
+}
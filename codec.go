@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+// errInvalidCodecJSON is the error returned when signing, if the function set
+// with SetJSONCodec produces something that isn't JSON.
+var errInvalidCodecJSON = errors.New("jwt: JSON codec produced invalid JSON")
+
+// jsonCodec is the pair of functions set with SetJSONCodec.
+type jsonCodec struct {
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+}
+
+// codec holds the jsonCodec set with SetJSONCodec, if any.
+var codec atomic.Value
+
+// SetJSONCodec makes this package encode and decode claims using marshal and
+// unmarshal, instead of json.Marshal and json.Unmarshal. Use it to plug in a
+// faster JSON library, or code generated for your claims types.
+//
+// marshal is used on the claims passed to the Sign functions, and unmarshal is
+// used to decode the claims of a verified JWT into the value passed to the
+// Verify functions. Both must work with standard JSON: a Sign function returns
+// an error if marshal produces anything else.
+//
+// Only the claims values you pass in are affected. This package always uses
+// encoding/json for JWT headers, so that a codec can't change what "alg" or
+// "typ" a token has, and for the claims it checks itself, such as "exp" and
+// "aud", so that a codec can't change what those checks decide. Decoding
+// fractional "exp", "nbf", and "iat" claims into integer fields (see
+// VerifyHS256) only works with a codec that, like json.Unmarshal, returns a
+// *json.UnmarshalTypeError when it can't fit a number into a field.
+// WithJSONNumber has no effect on an unmarshal function, which decides for
+// itself how to decode numbers.
+//
+// If marshal or unmarshal is nil, json.Marshal or json.Unmarshal is used in its
+// place. SetJSONCodec is safe to call concurrently with the rest of this
+// package, but is meant to be called once, before any tokens are signed or
+// verified.
+func SetJSONCodec(marshal func(v interface{}) ([]byte, error), unmarshal func(data []byte, v interface{}) error) {
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+
+	codec.Store(jsonCodec{marshal: marshal, unmarshal: unmarshal})
+}
+
+// marshalClaims encodes the claims v with the codec set with SetJSONCodec.
+func marshalClaims(v interface{}) ([]byte, error) {
+	c, ok := codec.Load().(jsonCodec)
+	if !ok {
+		return json.Marshal(v)
+	}
+
+	data, err := c.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Whatever goes into a token must be JSON, even if a codec is buggy.
+	if !json.Valid(data) {
+		return nil, errInvalidCodecJSON
+	}
+
+	return data, nil
+}
+
+// unmarshalClaimsJSON decodes data into the claims target v with the codec set
+// with SetJSONCodec. Without one, numbers are decoded as json.Number if
+// useNumber is true.
+func unmarshalClaimsJSON(data []byte, v interface{}, useNumber bool) error {
+	if c, ok := codec.Load().(jsonCodec); ok && c.unmarshal != nil {
+		return c.unmarshal(data, v)
+	}
+
+	if useNumber {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		return dec.Decode(v)
+	}
+
+	return json.Unmarshal(data, v)
+}
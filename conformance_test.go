@@ -0,0 +1,192 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// jwsVector is one of the examples in testdata/jws_vectors.json: a token, the
+// payload it carries, and the JWK of the key it was signed with.
+type jwsVector struct {
+	Name      string          `json:"name"`
+	Reference string          `json:"reference"`
+	Alg       string          `json:"alg"`
+	Key       json.RawMessage `json:"key"`
+	Payload   string          `json:"payload"`
+	Token     string          `json:"token"`
+}
+
+// loadJWSVectors reads testdata/jws_vectors.json.
+//
+// The vectors are the JWS examples from RFC7515, Appendix A, and the examples
+// from RFC7520, section 4, that use an algorithm this package supports with
+// the compact serialization, plus the EdDSA example from RFC8037. RFC7520
+// sections 4.2 (PS384), 4.5 (a detached, base64url-encoded payload), and 4.6
+// to 4.8 (the JSON serialization) are left out.
+func loadJWSVectors(t *testing.T) []jwsVector {
+	data, err := ioutil.ReadFile("testdata/jws_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors []jwsVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	return vectors
+}
+
+// allowVector returns the AllowedAlgorithm for verifying tokens signed with
+// alg using key, which may be a private key. It returns false if alg isn't
+// one this package supports for key.
+func allowVector(alg string, key interface{}) (jwt.AllowedAlgorithm, bool) {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+
+	switch key := key.(type) {
+	case []byte:
+		switch alg {
+		case "HS256":
+			return jwt.AllowHS256(key), true
+		case "HS512":
+			return jwt.AllowHS512(key), true
+		}
+	case *rsa.PublicKey:
+		switch alg {
+		case "RS256":
+			return jwt.AllowRS256(key), true
+		case "RS384":
+			return jwt.AllowRS384(key), true
+		case "PS256":
+			return jwt.AllowPS256(key), true
+		}
+	case *ecdsa.PublicKey:
+		switch alg {
+		case "ES256":
+			return jwt.AllowES256(key), true
+		case "ES512":
+			return jwt.AllowES512(key), true
+		}
+	case ed25519.PublicKey:
+		if alg == "EdDSA" {
+			return jwt.AllowEdDSA(key), true
+		}
+	}
+
+	return jwt.AllowedAlgorithm{}, false
+}
+
+func TestJWSVectors(t *testing.T) {
+	vectors := loadJWSVectors(t)
+
+	// Every key in the file, so that the unsecured examples can be checked
+	// against all of them.
+	var allowAll []jwt.VerifyOption
+
+	for _, v := range vectors {
+		if v.Alg == "none" {
+			continue
+		}
+
+		key, err := jwt.ParseJWK(v.Key)
+		if err != nil {
+			t.Fatalf("%s: %v", v.Name, err)
+		}
+
+		for _, alg := range []string{"HS256", "HS512", "RS256", "RS384", "PS256", "ES256", "ES512", "EdDSA"} {
+			if allow, ok := allowVector(alg, key); ok {
+				allowAll = append(allowAll, allow)
+			}
+		}
+	}
+
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			token := []byte(v.Token)
+
+			// Unsecured tokens are never accepted, whatever keys are on hand.
+			// Their signature is empty, so they aren't even well-formed.
+			if v.Alg == "none" {
+				_, _, _, err := jwt.Split(token)
+				assert.Equal(t, jwt.ErrMalformedToken, err)
+
+				var claims map[string]interface{}
+				_, err = jwt.VerifyAny(token, &claims, allowAll...)
+				assert.True(t, errors.Is(err, jwt.ErrMalformedToken), err)
+				assert.Nil(t, claims)
+				return
+			}
+
+			_, payload, _, err := jwt.Split(token)
+			assert.NoError(t, err)
+			assert.Equal(t, base64.RawURLEncoding.EncodeToString([]byte(v.Payload)), string(payload))
+
+			key, err := jwt.ParseJWK(v.Key)
+			assert.NoError(t, err)
+
+			allow, ok := allowVector(v.Alg, key)
+			if !assert.True(t, ok, "unsupported algorithm %s", v.Alg) {
+				return
+			}
+
+			var header jwt.Header
+			alg, err := jwt.VerifyAny(token, nil, allow, jwt.WithHeader(&header))
+			assert.NoError(t, err)
+			assert.Equal(t, v.Alg, alg)
+			assert.Equal(t, v.Alg, header.Algorithm)
+
+			var jwk struct {
+				KeyID string `json:"kid"`
+			}
+
+			assert.NoError(t, json.Unmarshal(v.Key, &jwk))
+			assert.Equal(t, jwk.KeyID, header.KeyID)
+
+			// Not every example's payload is a set of claims.
+			var claims map[string]interface{}
+			_, err = jwt.VerifyAny(token, &claims, allow)
+			if json.Valid([]byte(v.Payload)) {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, jwt.ErrMalformedToken), err)
+			}
+
+			// Flipping a bit anywhere in the signature makes it invalid.
+			_, _, encodedSig, err := jwt.Split(token)
+			assert.NoError(t, err)
+
+			sig, err := base64.RawURLEncoding.DecodeString(string(encodedSig))
+			assert.NoError(t, err)
+
+			signingInput := token[:len(token)-len(encodedSig)-1]
+			for _, i := range []int{0, len(sig) / 2, len(sig) - 1} {
+				tampered := append([]byte(nil), sig...)
+				tampered[i] ^= 1
+
+				s := string(signingInput) + "." + base64.RawURLEncoding.EncodeToString(tampered)
+				_, err := jwt.VerifyAny([]byte(s), nil, allow)
+				assert.Equal(t, jwt.ErrBadSignature, err, i)
+			}
+
+			// Where the signature is deterministic, signing the same input must
+			// reproduce it byte for byte.
+			if sign := jwt.SigningInputSigner(v.Alg, key); sign != nil {
+				out, err := sign(signingInput)
+				assert.NoError(t, err)
+				assert.Equal(t, string(encodedSig), base64.RawURLEncoding.EncodeToString(out))
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package jwt
+
+import "context"
+
+// claimsContextKey is the key that NewContext stores claims under in a
+// context. Because it is unexported, no other package can store a value under
+// it, and so nothing but NewContext can put claims where ClaimsFromContext
+// will find them.
+type claimsContextKey struct{}
+
+// rawTokenContextKey is the key that NewRawTokenContext stores a token under
+// in a context.
+type rawTokenContextKey struct{}
+
+// NewContext returns a copy of ctx that carries claims, which
+// ClaimsFromContext will return. Middleware uses it to pass the claims of a
+// verified token to the handler it wraps, and other integrations, such as the
+// interceptors in github.com/ucarion/jwt/jwtgrpc, use it to do the same. If
+// you verify tokens yourself, use it to pass their claims to the code that
+// handles the request.
+//
+// Only ever pass NewContext claims from a token you have verified. Code that
+// calls ClaimsFromContext trusts whatever it gets back, and has no way to tell
+// whether the claims were verified.
+func NewContext(ctx context.Context, claims interface{}) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims that a Middleware, or NewContext, added
+// to ctx. For a Middleware, the claims are whatever the factory passed to
+// WithClaims returned, or a *StandardClaims by default. If ctx has no claims,
+// ok is false.
+func ClaimsFromContext(ctx context.Context) (claims interface{}, ok bool) {
+	claims = ctx.Value(claimsContextKey{})
+	return claims, claims != nil
+}
+
+// StandardClaimsFromContext is like ClaimsFromContext, except that ok is also
+// false if the claims in ctx aren't a *StandardClaims. It saves a type
+// assertion when you use the default claims of a Middleware.
+func StandardClaimsFromContext(ctx context.Context) (claims *StandardClaims, ok bool) {
+	claims, ok = ctx.Value(claimsContextKey{}).(*StandardClaims)
+	return claims, ok && claims != nil
+}
+
+// NewRawTokenContext returns a copy of ctx that carries token, which
+// RawTokenFromContext will return. It is for services that need to forward the
+// token they were called with to another service.
+//
+// As with NewContext, only ever pass NewRawTokenContext a token you have
+// verified.
+func NewRawTokenContext(ctx context.Context, token []byte) context.Context {
+	return context.WithValue(ctx, rawTokenContextKey{}, token)
+}
+
+// RawTokenFromContext returns the token that a Middleware, or
+// NewRawTokenContext, added to ctx. The token is exactly as it was sent, and is
+// shared by everything that gets it from ctx; don't modify it. If ctx has no
+// token, ok is false.
+func RawTokenFromContext(ctx context.Context) (token []byte, ok bool) {
+	token, ok = ctx.Value(rawTokenContextKey{}).([]byte)
+	return token, ok
+}
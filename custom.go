@@ -0,0 +1,90 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnsupportedAlgorithm is returned by SignCustom and VerifyCustom if they
+// are asked to use the "none" algorithm, or an empty algorithm name.
+var ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+
+// SignCustom takes an algorithm name, a set of claims, and a function that
+// computes a signature, and returns a JWT signed with that algorithm.
+//
+// SignCustom exists so that algorithms this package intentionally leaves out
+// can be implemented in separate, opt-in packages, such as
+// github.com/ucarion/jwt/es256k. Most applications should not call SignCustom
+// directly; use SignHS256, SignRS256, SignES256, or one of the other Sign
+// functions instead.
+//
+// alg is used as the "alg" header of the returned JWT. fn is given the data to
+// sign, and must return the raw bytes of the signature, as they are meant to
+// appear, base64url-encoded, in the JWT.
+//
+// SignCustom returns ErrUnsupportedAlgorithm if alg is empty or is "none" (in
+// any casing). It returns an error if calling json.Marshal on v returns an
+// error, if one of opts rejects the claims, or if fn returns an error.
+func SignCustom(alg string, v interface{}, fn func(data []byte) ([]byte, error), opts ...SignOption) ([]byte, error) {
+	return SignCustomSize(alg, 0, v, fn, opts...)
+}
+
+// SignCustomSize is like SignCustom, for algorithms whose signatures are
+// always, or usually, size bytes long. The JWT is built in a buffer with room
+// for a signature of that size, so that it doesn't have to be copied into a
+// larger one once fn returns. If fn returns a signature of some other size,
+// the JWT is still correct, just built less efficiently.
+//
+// For instance, github.com/ucarion/jwt/es256k passes 64, because an ES256K
+// signature is two 32-byte integers.
+func SignCustomSize(alg string, size int, v interface{}, fn func(data []byte) ([]byte, error), opts ...SignOption) ([]byte, error) {
+	if !isCustomAlgorithm(alg) {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if size < 0 {
+		size = 0
+	}
+
+	return sign(alg, size, v, opts, fn)
+}
+
+// VerifyCustom verifies a JWT that is expected to use the algorithm alg. If
+// the JWT is verified, VerifyCustom will serialize the claims inside the JWT
+// into v.
+//
+// VerifyCustom is the counterpart to SignCustom. Like SignCustom, most
+// applications should not call it directly.
+//
+// VerifyCustom performs all the same checks as the other Verify functions in
+// this package. In particular, the JWT is rejected with ErrWrongAlgorithm if
+// its "alg" header is not exactly alg. The token never decides what algorithm
+// is used. fn is given the data that was supposed to be signed and the decoded
+// signature in the JWT, and must return an error if the signature is not
+// valid. If fn returns ErrInvalidSignature, VerifyCustom returns
+// ErrBadSignature in its place; any other error is returned as it is.
+//
+// VerifyCustom returns ErrUnsupportedAlgorithm if alg is empty or is "none"
+// (in any casing).
+func VerifyCustom(alg string, s []byte, v interface{}, fn func(data, sig []byte) error, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	if !isCustomAlgorithm(alg) {
+		return ErrUnsupportedAlgorithm
+	}
+
+	header, claims, err := verify(alg, s, v, opts, fn)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
+
+// isCustomAlgorithm returns whether alg may be used with SignCustom and
+// VerifyCustom.
+func isCustomAlgorithm(alg string) bool {
+	return alg != "" && !strings.EqualFold(alg, "none")
+}
@@ -0,0 +1,100 @@
+package jwt_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestCustom(t *testing.T) {
+	// A toy algorithm whose "signature" is the data reversed. Real algorithms
+	// would of course do something cryptographically meaningful.
+	reverse := func(data []byte) []byte {
+		out := make([]byte, len(data))
+		for i, b := range data {
+			out[len(data)-1-i] = b
+		}
+
+		return out
+	}
+
+	signFn := func(data []byte) ([]byte, error) {
+		return reverse(data), nil
+	}
+
+	verifyFn := func(data, sig []byte) error {
+		if !bytes.Equal(reverse(data), sig) {
+			return jwt.ErrInvalidSignature
+		}
+
+		return nil
+	}
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+	token, err := jwt.SignCustom("X-REV", claims, signFn)
+	assert.NoError(t, err)
+
+	var out jwt.StandardClaims
+	assert.NoError(t, jwt.VerifyCustom("X-REV", token, &out, verifyFn))
+	assert.Equal(t, claims, out)
+
+	// The alg is pinned by the caller, not by the token.
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyCustom("X-OTHER", token, &out, verifyFn))
+
+	hs256, err := jwt.SignHS256([]byte("secret"), claims)
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyCustom("X-REV", hs256, &out, verifyFn))
+
+	// Sign options apply as usual.
+	_, err = jwt.SignCustom("X-REV", claims, signFn, jwt.WithSignPolicy(jwt.SignPolicy{RequireIssuer: true}))
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation))
+
+	// "none" can never be used.
+	for _, alg := range []string{"", "none", "None", "NONE"} {
+		_, err := jwt.SignCustom(alg, claims, signFn)
+		assert.Equal(t, jwt.ErrUnsupportedAlgorithm, err, alg)
+		assert.Equal(t, jwt.ErrUnsupportedAlgorithm, jwt.VerifyCustom(alg, token, &out, verifyFn), alg)
+	}
+
+	// SignCustomSize makes the same tokens, whether or not it's given the
+	// right size.
+	for _, size := range []int{-1, 0, 10, len(token)} {
+		sized, err := jwt.SignCustomSize("X-REV", size, claims, signFn)
+		assert.NoError(t, err)
+		assert.Equal(t, token, sized, size)
+	}
+
+	_, err = jwt.SignCustomSize("none", 64, claims, signFn)
+	assert.Equal(t, jwt.ErrUnsupportedAlgorithm, err)
+}
+
+func TestSignCustomSizeAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations aren't counted with the race detector on")
+	}
+
+	claims := json.RawMessage(`{"sub":"jdoe@example.com"}`)
+	sig := make([]byte, 64)
+	signFn := func(data []byte) ([]byte, error) {
+		return sig, nil
+	}
+
+	unsized := testing.AllocsPerRun(100, func() {
+		if _, err := jwt.SignCustom("X-FIXED", claims, signFn); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	sized := testing.AllocsPerRun(100, func() {
+		if _, err := jwt.SignCustomSize("X-FIXED", len(sig), claims, signFn); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// Knowing the size saves building the token a second time.
+	assert.Equal(t, unsized-1, sized)
+}
@@ -0,0 +1,146 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// SignDetachedHS256 takes a secret and a payload, and returns an HS256
+// signature of payload in the form of a JWS with a detached, unencoded
+// payload, as described by RFC7797.
+//
+// The returned token looks like a JWT with its middle part left out: a header,
+// two periods, and a signature. The payload is signed as it is, rather than
+// base64url-encoded, so that large payloads, such as the body of an HTTP
+// request, can be signed without sending them twice, or making them a third
+// bigger. Send the payload alongside the token, unchanged, and verify the two
+// together with VerifyDetachedHS256.
+//
+// The header of the returned token has a "b64" header parameter of false, and
+// a "crit" header parameter listing "b64", as RFC7797 requires. This way,
+// implementations that don't support RFC7797 reject the token, rather than
+// check its signature the wrong way. It has no "typ", because payload can be
+// anything, not just the claims of a JWT.
+//
+// opts can be used to further configure how the token is signed, except that
+// options that add or check claims, such as WithIssuedAtNow, make
+// SignDetachedHS256 return an error. "b64" and "crit" can't be set with
+// WithHeaderParams.
+//
+// https://tools.ietf.org/html/rfc7797
+func SignDetachedHS256(secret, payload []byte, opts ...SignOption) ([]byte, error) {
+	config := newSignConfig(opts)
+	if err := config.checkNoClaims(); err != nil {
+		return nil, err
+	}
+
+	h, err := config.marshalHeaderParams("", algHS256, map[string]interface{}{"b64": false, "crit": []string{"b64"}})
+	if err != nil {
+		return nil, err
+	}
+
+	encodedHeader := base64.RawURLEncoding.EncodeToString(h)
+
+	sig, err := signHMAC(secret, algHS256, crypto.SHA256, config.strictSecrets)(detachedSigningInput(encodedHeader, payload))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(sig)), nil
+}
+
+// VerifyDetachedHS256 verifies a token produced by SignDetachedHS256, using a
+// secret and the payload that was sent alongside the token. It returns nil
+// only if the token is a signature of exactly payload.
+//
+// Only tokens with a detached, unencoded payload are accepted: the middle part
+// of the token must be empty, and its header must have a "b64" of false that
+// is listed in "crit". Ordinary JWTs, such as those produced by SignHS256, are
+// rejected with an error wrapping ErrMalformedToken, as are tokens whose
+// payload is detached but base64url-encoded. Likewise, VerifyHS256 and the
+// other Verify functions in this package reject tokens produced by
+// SignDetachedHS256.
+//
+// opts can be used to further configure how the token is verified, except that
+// options that check claims, such as WithExpectedIssuer, make
+// VerifyDetachedHS256 return an error wrapping ErrInvalidClaimsTarget, because
+// the payload isn't claims. Use WithHeader to get the token's header.
+//
+// VerifyDetachedHS256 will return an error wrapping ErrMalformedToken if the
+// token is malformed. It will return ErrWrongAlgorithm if the token uses any
+// algorithm other than HS256, and ErrBadSignature if it is not a signature of
+// payload with the given secret.
+func VerifyDetachedHS256(secret, s, payload []byte, opts ...VerifyOption) error {
+	c := newVerifyConfig(opts)
+	c.detached = true
+
+	encodedHeader, encodedPayload, encodedSignature, ok := splitToken(s)
+	if !ok || len(encodedHeader) == 0 || len(encodedSignature) == 0 || !isBase64URL(encodedHeader) || !isBase64URL(encodedSignature) {
+		return ErrMalformedToken
+	}
+
+	if len(encodedPayload) != 0 {
+		return malformedToken("payload", errors.New("must be detached"))
+	}
+
+	header, _, err := decodeHeaderPart(nil, encodedHeader)
+	if err != nil {
+		return malformedToken("header", err)
+	}
+
+	var h struct {
+		Algorithm string   `json:"alg"`
+		B64       *bool    `json:"b64"`
+		Critical  []string `json:"crit"`
+	}
+
+	if err := json.Unmarshal(header, &h); err != nil {
+		return malformedToken("header", err)
+	}
+
+	if h.Algorithm != algHS256 {
+		return ErrWrongAlgorithm
+	}
+
+	if h.B64 == nil || *h.B64 || !containsString(h.Critical, "b64") {
+		return malformedToken("header", errors.New("\"b64\" must be false, and listed in \"crit\""))
+	}
+
+	sig, err := decodeNewPart(encodedSignature)
+	if err != nil {
+		return malformedToken("signature", err)
+	}
+
+	if len(sig) != sha256.Size {
+		return ErrBadSignature
+	}
+
+	if err := verifyHMAC(secret, algHS256, crypto.SHA256, c.strictSecrets)(detachedSigningInput(string(encodedHeader), payload), sig); err != nil {
+		return err
+	}
+
+	return c.unmarshalClaims(header, nil, nil, false)
+}
+
+// detachedSigningInput returns what a JWS with an unencoded payload is signed
+// over: the encoded header, a period, and the payload, as it is.
+func detachedSigningInput(encodedHeader string, payload []byte) []byte {
+	data := make([]byte, 0, len(encodedHeader)+1+len(payload))
+	data = append(data, encodedHeader...)
+	data = append(data, '.')
+	return append(data, payload...)
+}
+
+// containsString returns whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
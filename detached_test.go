@@ -0,0 +1,136 @@
+package jwt_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestSignDetachedHS256(t *testing.T) {
+	// The example in RFC7797, section 4.2, which uses the HMAC key from RFC7515,
+	// Appendix A.1.
+	secret, err := base64.RawURLEncoding.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	assert.NoError(t, err)
+
+	payload := []byte("$.02")
+	rfcToken := "eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY"
+
+	token, err := jwt.SignDetachedHS256(secret, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, rfcToken, string(token))
+
+	var header jwt.Header
+	assert.NoError(t, jwt.VerifyDetachedHS256(secret, token, payload, jwt.WithHeader(&header)))
+	assert.Equal(t, "HS256", header.Algorithm)
+	assert.Equal(t, map[string]interface{}{"b64": false, "crit": []interface{}{"b64"}}, header.Extra)
+
+	assert.NoError(t, jwt.VerifyDetachedHS256(secret, []byte(" "+rfcToken+"\n"), payload))
+
+	// The payload is part of what's signed.
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyDetachedHS256(secret, token, []byte("$.03")))
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyDetachedHS256(secret, token, nil))
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyDetachedHS256([]byte("other"), token, payload))
+
+	// The payload isn't base64url-encoded, so it can be anything at all.
+	for _, payload := range []string{"", ".", "a.b.c", "{\"sub\":\"jdoe\"}\n", "\x00\xff"} {
+		token, err := jwt.SignDetachedHS256(secret, []byte(payload), jwt.WithKeyID("k"))
+		assert.NoError(t, err)
+		assert.NoError(t, jwt.VerifyDetachedHS256(secret, token, []byte(payload)), payload)
+	}
+
+	_, err = jwt.SignDetachedHS256(secret, payload, jwt.WithHeaderParams(map[string]interface{}{"b64": true}))
+	assert.True(t, errors.Is(err, jwt.ErrReservedHeaderParam))
+
+	_, err = jwt.SignDetachedHS256(secret, payload, jwt.WithHeaderParams(map[string]interface{}{"crit": []string{"x"}}))
+	assert.True(t, errors.Is(err, jwt.ErrReservedHeaderParam))
+
+	_, err = jwt.SignDetachedHS256(secret, payload, jwt.WithIssuedAtNow())
+	assert.Error(t, err)
+
+	_, err = jwt.SignDetachedHS256([]byte("short"), payload, jwt.WithStrictSecrets())
+	assert.True(t, errors.Is(err, jwt.ErrWeakSecret))
+
+	err = jwt.VerifyDetachedHS256(secret, token, payload, jwt.WithExpectedIssuer("me"))
+	assert.True(t, errors.Is(err, jwt.ErrInvalidClaimsTarget))
+}
+
+func TestDetachedAndAttachedAreNotInterchangeable(t *testing.T) {
+	secret := []byte("my secret key")
+	payload := []byte(`{"sub":"jdoe@example.com"}`)
+
+	detached, err := jwt.SignDetachedHS256(secret, payload)
+	assert.NoError(t, err)
+
+	attached, err := jwt.SignHS256(secret, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+
+	// A detached token isn't a JWT, even with its payload put back in.
+	parts := strings.Split(string(detached), ".")
+	var claims jwt.StandardClaims
+	for _, token := range []string{
+		string(detached),
+		parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2],
+	} {
+		err := jwt.VerifyHS256(secret, []byte(token), &claims)
+		assert.True(t, errors.Is(err, jwt.ErrInvalidSignature), token)
+		assert.NotEqual(t, "jdoe@example.com", claims.Subject)
+
+		err = jwt.VerifyHS256(secret, []byte(token), &claims, jwt.WithCriticalHeaders("b64"))
+		assert.True(t, errors.Is(err, jwt.ErrInvalidSignature), token)
+	}
+
+	// An unencoded payload can't be attached either, even if it happens to look
+	// like base64url.
+	unencoded, err := jwt.SignDetachedHS256(secret, []byte("e30"))
+	assert.NoError(t, err)
+
+	parts = strings.Split(string(unencoded), ".")
+	err = jwt.VerifyHS256(secret, []byte(parts[0]+".e30."+parts[2]), nil, jwt.WithCriticalHeaders("b64"))
+	assert.True(t, errors.Is(err, jwt.ErrUnsupportedCritical))
+
+	// A JWT isn't a detached token, with or without its payload.
+	parts = strings.Split(string(attached), ".")
+	for _, token := range []string{
+		string(attached),
+		parts[0] + ".." + parts[2],
+	} {
+		err := jwt.VerifyDetachedHS256(secret, []byte(token), []byte(parts[1]))
+		assert.True(t, errors.Is(err, jwt.ErrMalformedToken), token)
+	}
+
+	// Nor is a token whose header doesn't say its payload is unencoded, or
+	// doesn't make that critical.
+	for _, header := range []string{
+		`{"alg":"HS256"}`,
+		`{"alg":"HS256","b64":true,"crit":["b64"]}`,
+		`{"alg":"HS256","b64":false}`,
+		`{"alg":"HS256","b64":"false","crit":["b64"]}`,
+		`{"alg":"HS256","b64":false,"crit":["x"],"x":1}`,
+	} {
+		h := base64.RawURLEncoding.EncodeToString([]byte(header))
+		token := h + ".." + base64.RawURLEncoding.EncodeToString(hmacSHA256(secret)([]byte(h+"."+string(payload))))
+
+		err := jwt.VerifyDetachedHS256(secret, []byte(token), payload)
+		assert.True(t, errors.Is(err, jwt.ErrMalformedToken), header)
+	}
+
+	for _, header := range []string{
+		`{"alg":"HS512","b64":false,"crit":["b64"]}`,
+		`{"alg":"none","b64":false,"crit":["b64"]}`,
+		`{"b64":false,"crit":["b64"]}`,
+	} {
+		h := base64.RawURLEncoding.EncodeToString([]byte(header))
+		token := h + ".." + base64.RawURLEncoding.EncodeToString(hmacSHA256(secret)([]byte(h+"."+string(payload))))
+		assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyDetachedHS256(secret, []byte(token), payload), header)
+	}
+
+	// Other critical header parameters still have to be understood.
+	h := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","b64":false,"crit":["b64","x"],"x":1}`))
+	token := []byte(h + ".." + base64.RawURLEncoding.EncodeToString(hmacSHA256(secret)([]byte(h+"."+string(payload)))))
+	assert.True(t, errors.Is(jwt.VerifyDetachedHS256(secret, token, payload), jwt.ErrUnsupportedCritical))
+	assert.NoError(t, jwt.VerifyDetachedHS256(secret, token, payload, jwt.WithCriticalHeaders("x")))
+}
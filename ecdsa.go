@@ -0,0 +1,256 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// ecdsaKeySize returns the number of bytes needed to hold a coordinate, or
+// either of the two signature values, on curve.
+//
+// For P-256 this is 32, and for P-521 it is 66. The latter is why this can't
+// just be BitSize/8.
+func ecdsaKeySize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// checkECDSACurve returns an error wrapping ErrInvalidKey if a key on the curve
+// have can't be used with alg, which requires the curve want.
+//
+// Without this check, a key on a larger curve would produce values of R and S
+// too long for the fixed-width signature format of alg.
+func checkECDSACurve(alg string, have, want elliptic.Curve) error {
+	if have == want {
+		return nil
+	}
+
+	if have == nil {
+		return fmt.Errorf("%w: %s requires an ECDSA key on %s, but the key has no curve", ErrInvalidKey, alg, want.Params().Name)
+	}
+
+	return fmt.Errorf("%w: %s requires an ECDSA key on %s, not %s", ErrInvalidKey, alg, want.Params().Name, have.Params().Name)
+}
+
+// signECDSA returns a function suitable for passing to sign. The returned
+// function signs data with priv, and encodes the signature as the fixed-width
+// concatenation of R and S described in RFC7518, Section 3.4.
+//
+// The nonce is drawn from random, or from crypto/rand.Reader if random is nil.
+//
+// If priv is not on curve, the returned function returns an error wrapping
+// ErrInvalidKey. alg is used only in that error.
+func signECDSA(random io.Reader, priv *ecdsa.PrivateKey, alg string, curve elliptic.Curve, hash crypto.Hash) func(data []byte) ([]byte, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+
+	return func(data []byte) ([]byte, error) {
+		if err := checkECDSACurve(alg, priv.Curve, curve); err != nil {
+			return nil, err
+		}
+
+		h := hash.New()
+		h.Write(data)
+
+		return signECDSADigest(random, priv, h.Sum(nil), ecdsaKeySize(curve))
+	}
+}
+
+// signECDSASigner is like signECDSA, except that the signature is made by
+// signer, whose public key must be on curve.
+//
+// crypto.Signer implementations for ECDSA return ASN.1 DER signatures, so the
+// returned function converts the signature to the fixed-width form JWTs use.
+// See signDigest for how ctx is used.
+func signECDSASigner(ctx context.Context, signer crypto.Signer, curve elliptic.Curve, hash crypto.Hash) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		h := hash.New()
+		h.Write(data)
+
+		der, err := signDigest(ctx, signer, h.Sum(nil), hash)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, ok := ecdsaSignatureFromDER(der, ecdsaKeySize(curve))
+		if !ok {
+			return nil, fmt.Errorf("%w: signer returned a malformed ECDSA signature", ErrInvalidKey)
+		}
+
+		return sig, nil
+	}
+}
+
+// verifyECDSA returns a function suitable for passing to verify. It is the
+// counterpart of signECDSA.
+//
+// If pub is not on curve, the returned function returns an error wrapping
+// ErrInvalidKey. If the signature is not exactly as long as signECDSA would
+// have made it, or if R or S isn't in the range [1, N-1], where N is the order
+// of curve, the returned function returns ErrBadSignature.
+func verifyECDSA(pub *ecdsa.PublicKey, alg string, curve elliptic.Curve, hash crypto.Hash) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		if err := checkECDSACurve(alg, pub.Curve, curve); err != nil {
+			return err
+		}
+
+		keySize := ecdsaKeySize(curve)
+		if len(sig) != 2*keySize {
+			return ErrBadSignature
+		}
+
+		// crypto/ecdsa rejects these values too, but there's no need to rely on
+		// that, or on the edge cases of the code that gets to them.
+		n := ecdsaOrder(curve)
+		if !ecdsaScalarInRange(sig[:keySize], n) || !ecdsaScalarInRange(sig[keySize:], n) {
+			return ErrBadSignature
+		}
+
+		h := hash.New()
+		h.Write(data)
+
+		if !verifyECDSADigest(pub, h.Sum(nil), sig) {
+			return ErrBadSignature
+		}
+
+		return nil
+	}
+}
+
+// The orders of the curves this package uses, as ecdsaOrder returns them.
+var (
+	p256Order = encodeECDSAOrder(elliptic.P256())
+	p521Order = encodeECDSAOrder(elliptic.P521())
+)
+
+// ecdsaOrder returns N, the order of curve, as a big-endian integer
+// left-padded with zeros to ecdsaKeySize(curve) bytes.
+func ecdsaOrder(curve elliptic.Curve) []byte {
+	switch curve {
+	case elliptic.P256():
+		return p256Order
+	case elliptic.P521():
+		return p521Order
+	default:
+		return encodeECDSAOrder(curve)
+	}
+}
+
+// encodeECDSAOrder computes what ecdsaOrder returns.
+func encodeECDSAOrder(curve elliptic.Curve) []byte {
+	n := curve.Params().N.Bytes()
+	out := make([]byte, ecdsaKeySize(curve))
+	copy(out[len(out)-len(n):], n)
+	return out
+}
+
+// ecdsaScalarInRange reports whether b, a big-endian integer, is in the range
+// [1, n-1], where n is a big-endian integer as long as b. Both R and S must be,
+// for an ECDSA signature to be valid.
+//
+// Because b and n are the same length, comparing them as byte strings compares
+// them as integers, and nothing needs to be converted to a big.Int.
+func ecdsaScalarInRange(b, n []byte) bool {
+	zero := true
+	for _, c := range b {
+		if c != 0 {
+			zero = false
+			break
+		}
+	}
+
+	return !zero && bytes.Compare(b, n) < 0
+}
+
+// ecdsaSignatureFromDER converts an ASN.1 DER-encoded ECDSA signature, as
+// crypto.Signer and ecdsa.SignASN1 return them, to the fixed-width form JWTs
+// use: R and S, each left-padded with zeros to keySize bytes. It returns false
+// unless der is the DER encoding of two positive integers that each fit in
+// keySize bytes.
+//
+// Working on the bytes directly, rather than going through asn1.Unmarshal and
+// big.Int, saves several allocations per signature.
+func ecdsaSignatureFromDER(der []byte, keySize int) ([]byte, bool) {
+	seq, rest, ok := derElement(der, 0x30)
+	if !ok || len(rest) != 0 {
+		return nil, false
+	}
+
+	r, seq, ok := derElement(seq, 0x02)
+	if !ok {
+		return nil, false
+	}
+
+	s, seq, ok := derElement(seq, 0x02)
+	if !ok || len(seq) != 0 {
+		return nil, false
+	}
+
+	sig := make([]byte, 2*keySize)
+	if !putDERInteger(sig[:keySize], r) || !putDERInteger(sig[keySize:], s) {
+		return nil, false
+	}
+
+	return sig, true
+}
+
+// derElement reads a DER element with the given tag from the start of b. It
+// returns the element's contents, and what follows the element in b.
+//
+// Only lengths up to 255 bytes are supported, which is plenty for an ECDSA
+// signature on any of the curves this package uses.
+func derElement(b []byte, tag byte) (contents, rest []byte, ok bool) {
+	if len(b) < 2 || b[0] != tag {
+		return nil, nil, false
+	}
+
+	n, b := int(b[1]), b[2:]
+	switch {
+	case n < 0x80:
+	case n == 0x81 && len(b) > 0 && b[0] >= 0x80:
+		// DER requires the short form for lengths below 128, so 0x81 must be
+		// followed by a length of at least 128.
+		n, b = int(b[0]), b[1:]
+	default:
+		return nil, nil, false
+	}
+
+	if len(b) < n {
+		return nil, nil, false
+	}
+
+	return b[:n], b[n:], true
+}
+
+// putDERInteger writes the contents of a DER-encoded INTEGER into out,
+// left-padded with zeros. It returns false if the integer isn't positive, isn't
+// minimally encoded, or doesn't fit in out.
+func putDERInteger(out, i []byte) bool {
+	if len(i) == 0 || i[0]&0x80 != 0 {
+		return false
+	}
+
+	// A leading zero is only allowed, and only needed, to keep an integer whose
+	// next byte has its high bit set from being negative. An integer that is
+	// just a zero isn't positive.
+	if i[0] == 0 {
+		if len(i) == 1 || i[1]&0x80 == 0 {
+			return false
+		}
+
+		i = i[1:]
+	}
+
+	if len(i) > len(out) {
+		return false
+	}
+
+	copy(out[len(out)-len(i):], i)
+	return true
+}
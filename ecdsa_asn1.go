@@ -0,0 +1,91 @@
+//go:build go1.15
+// +build go1.15
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"io"
+)
+
+// signECDSADigest signs digest with priv, reading the nonce from random, and
+// returns the signature in the fixed-width form JWTs use, with R and S each
+// keySize bytes.
+//
+// ecdsa.Sign produces an ASN.1 signature and then decodes it into big.Ints,
+// which would then have to be encoded again. Starting from ecdsa.SignASN1
+// skips the big.Ints altogether.
+func signECDSADigest(random io.Reader, priv *ecdsa.PrivateKey, digest []byte, keySize int) ([]byte, error) {
+	der, err := ecdsa.SignASN1(random, priv, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, ok := ecdsaSignatureFromDER(der, keySize)
+	if !ok {
+		// ecdsa.SignASN1 never returns a signature that doesn't fit the curve.
+		panic("jwt: ecdsa.SignASN1 returned a malformed signature")
+	}
+
+	return sig, nil
+}
+
+// verifyECDSADigest reports whether sig, in the fixed-width form JWTs use, is a
+// valid signature of digest by pub. sig must have an even length.
+func verifyECDSADigest(pub *ecdsa.PublicKey, digest, sig []byte) bool {
+	return ecdsa.VerifyASN1(pub, digest, ecdsaSignatureToDER(sig))
+}
+
+// ecdsaSignatureToDER is the inverse of ecdsaSignatureFromDER. The first half
+// of sig is R, and the second half is S.
+func ecdsaSignatureToDER(sig []byte) []byte {
+	r := trimLeadingZeros(sig[:len(sig)/2])
+	s := trimLeadingZeros(sig[len(sig)/2:])
+
+	n := 2 + derIntegerLen(r) + 2 + derIntegerLen(s)
+	der := make([]byte, 0, 3+n)
+
+	der = append(der, 0x30)
+	if n >= 0x80 {
+		der = append(der, 0x81)
+	}
+
+	der = append(der, byte(n))
+	der = appendDERInteger(der, r)
+	der = appendDERInteger(der, s)
+
+	return der
+}
+
+// trimLeadingZeros returns b without its leading zero bytes.
+func trimLeadingZeros(b []byte) []byte {
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+
+	return b
+}
+
+// derIntegerLen returns the length of the contents of the DER encoding of the
+// unsigned integer i, which has no leading zeros.
+func derIntegerLen(i []byte) int {
+	// Zero is encoded as a single zero byte, and an integer whose high bit is
+	// set needs a zero byte in front of it to not be negative.
+	if len(i) == 0 || i[0]&0x80 != 0 {
+		return len(i) + 1
+	}
+
+	return len(i)
+}
+
+// appendDERInteger appends the DER encoding of the unsigned integer i, which
+// has no leading zeros, to der.
+func appendDERInteger(der, i []byte) []byte {
+	n := derIntegerLen(i)
+	der = append(der, 0x02, byte(n))
+	if n > len(i) {
+		der = append(der, 0)
+	}
+
+	return append(der, i...)
+}
@@ -0,0 +1,45 @@
+//go:build !go1.15
+// +build !go1.15
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"math/big"
+)
+
+// signECDSADigest signs digest with priv, reading the nonce from random, and
+// returns the signature in the fixed-width form JWTs use, with R and S each
+// keySize bytes.
+//
+// ecdsa.SignASN1 and ecdsa.VerifyASN1 are only available from Go 1.15 on.
+// Before that, the signature has to go through big.Int.
+func signECDSADigest(random io.Reader, priv *ecdsa.PrivateKey, digest []byte, keySize int) ([]byte, error) {
+	sigR, sigS, err := ecdsa.Sign(random, priv, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := make([]byte, 2*keySize)
+
+	// R and S may be shorter than keySize if they have leading zeros. They
+	// must be left-padded with zeros to exactly keySize bytes each.
+	r := sigR.Bytes()
+	s := sigS.Bytes()
+
+	copy(sig[keySize-len(r):keySize], r)
+	copy(sig[2*keySize-len(s):], s)
+
+	return sig, nil
+}
+
+// verifyECDSADigest reports whether sig, in the fixed-width form JWTs use, is a
+// valid signature of digest by pub. sig must have an even length.
+func verifyECDSADigest(pub *ecdsa.PublicKey, digest, sig []byte) bool {
+	var sigR, sigS big.Int
+	sigR.SetBytes(sig[:len(sig)/2])
+	sigS.SetBytes(sig[len(sig)/2:])
+
+	return ecdsa.Verify(pub, digest, &sigR, &sigS)
+}
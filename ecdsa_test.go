@@ -0,0 +1,207 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECDSASignatureDER(t *testing.T) {
+	fixed := func(r, s string) []byte {
+		sig := make([]byte, 64)
+		copy(sig[32-len(r):32], r)
+		copy(sig[64-len(s):], s)
+		return sig
+	}
+
+	testCases := []struct {
+		name string
+		sig  []byte
+	}{
+		{"short", fixed("\x01", "\x7f")},
+		{"high bits", fixed("\x80", "\xff\xff")},
+		{"leading zeros", fixed(string(bytes.Repeat([]byte{0x42}, 31)), string(bytes.Repeat([]byte{0x01}, 30)))},
+		{"full width", fixed(string(bytes.Repeat([]byte{0xff}, 32)), string(bytes.Repeat([]byte{0x7f}, 32)))},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			// What ecdsaSignatureFromDER accepts must be exactly what encoding/asn1
+			// makes of R and S.
+			var want struct{ R, S *big.Int }
+			want.R = new(big.Int).SetBytes(tt.sig[:32])
+			want.S = new(big.Int).SetBytes(tt.sig[32:])
+
+			der, err := asn1.Marshal(want)
+			assert.NoError(t, err)
+
+			sig, ok := ecdsaSignatureFromDER(der, 32)
+			assert.True(t, ok)
+			assert.Equal(t, tt.sig, sig)
+		})
+	}
+
+	// P-521 signatures are long enough to need the long form of DER lengths.
+	var long struct{ R, S *big.Int }
+	long.R = new(big.Int).Lsh(big.NewInt(1), 520)
+	long.S = new(big.Int).Lsh(big.NewInt(1), 519)
+
+	der, err := asn1.Marshal(long)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x81), der[1])
+
+	sig, ok := ecdsaSignatureFromDER(der, 66)
+	assert.True(t, ok)
+	assert.Equal(t, long.R.Bytes(), sig[:66])
+	assert.Equal(t, append([]byte{0}, long.S.Bytes()...), sig[66:])
+
+	invalid := map[string][]byte{
+		"empty":              {},
+		"not a sequence":     {0x31, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01},
+		"trailing data":      {0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01, 0x00},
+		"extra element":      {0x30, 0x09, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01},
+		"missing s":          {0x30, 0x03, 0x02, 0x01, 0x01},
+		"truncated":          {0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x02, 0x01},
+		"zero":               {0x30, 0x06, 0x02, 0x01, 0x00, 0x02, 0x01, 0x01},
+		"negative":           {0x30, 0x06, 0x02, 0x01, 0x81, 0x02, 0x01, 0x01},
+		"non-minimal int":    {0x30, 0x07, 0x02, 0x02, 0x00, 0x01, 0x02, 0x01, 0x01},
+		"non-minimal length": {0x30, 0x81, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01},
+		"indefinite length":  {0x30, 0x80, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01, 0x00, 0x00},
+		"too long":           append([]byte{0x30, 0x26, 0x02, 0x21, 0x01}, append(make([]byte, 32), 0x02, 0x01, 0x01)...),
+	}
+
+	for name, der := range invalid {
+		_, ok := ecdsaSignatureFromDER(der, 32)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestECDSADigest(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("data"))
+
+	// About one in every 128 signatures has an R or S with a leading zero byte.
+	// Sign until we've seen some of those, checking every signature against
+	// the standard library's idea of its R and S.
+	var leadingZeros int
+	for i := 0; i < 5000 && leadingZeros < 2; i++ {
+		sig, err := signECDSADigest(rand.Reader, priv, digest[:], 32)
+		assert.NoError(t, err)
+		assert.Len(t, sig, 64)
+
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		assert.True(t, ecdsa.Verify(&priv.PublicKey, digest[:], r, s))
+		assert.True(t, verifyECDSADigest(&priv.PublicKey, digest[:], sig))
+
+		if sig[0] == 0 || sig[32] == 0 {
+			leadingZeros++
+		}
+	}
+
+	assert.Equal(t, 2, leadingZeros)
+
+	// A signature whose R or S is zero is never valid.
+	assert.False(t, verifyECDSADigest(&priv.PublicKey, digest[:], make([]byte, 64)))
+}
+
+func TestVerifyECDSARange(t *testing.T) {
+	testCases := []struct {
+		alg    string
+		curve  elliptic.Curve
+		sign   func(*ecdsa.PrivateKey) ([]byte, error)
+		verify func(*ecdsa.PublicKey, []byte) error
+	}{
+		{
+			algES256,
+			elliptic.P256(),
+			func(priv *ecdsa.PrivateKey) ([]byte, error) { return SignES256(priv, StandardClaims{}) },
+			func(pub *ecdsa.PublicKey, token []byte) error { return VerifyES256(pub, token, &StandardClaims{}) },
+		},
+		{
+			algES512,
+			elliptic.P521(),
+			func(priv *ecdsa.PrivateKey) ([]byte, error) { return SignES512(priv, StandardClaims{}) },
+			func(pub *ecdsa.PublicKey, token []byte) error { return VerifyES512(pub, token, &StandardClaims{}) },
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.alg, func(t *testing.T) {
+			priv, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+			assert.NoError(t, err)
+
+			token, err := tt.sign(priv)
+			assert.NoError(t, err)
+			assert.NoError(t, tt.verify(&priv.PublicKey, token))
+
+			i := bytes.LastIndexByte(token, '.')
+			sig, err := base64.RawURLEncoding.DecodeString(string(token[i+1:]))
+			assert.NoError(t, err)
+
+			keySize := ecdsaKeySize(tt.curve)
+			r, s := sig[:keySize], sig[keySize:]
+
+			scalar := func(x *big.Int) []byte {
+				b := make([]byte, keySize)
+				xb := x.Bytes()
+				copy(b[keySize-len(xb):], xb)
+				return b
+			}
+
+			zero := make([]byte, keySize)
+			order := scalar(tt.curve.Params().N)
+
+			invalid := map[string][]byte{
+				"r=0":      append(append([]byte{}, zero...), s...),
+				"s=0":      append(append([]byte{}, r...), zero...),
+				"r=s=0":    append(append([]byte{}, zero...), zero...),
+				"r=N":      append(append([]byte{}, order...), s...),
+				"s=N":      append(append([]byte{}, r...), order...),
+				"all ones": bytes.Repeat([]byte{0xff}, 2*keySize),
+			}
+
+			for name, sig := range invalid {
+				forged := append(append([]byte{}, token[:i+1]...), base64.RawURLEncoding.EncodeToString(sig)...)
+				assert.Equal(t, ErrBadSignature, tt.verify(&priv.PublicKey, forged), name)
+			}
+		})
+	}
+}
+
+func TestECDSAScalarInRange(t *testing.T) {
+	one := big.NewInt(1)
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			size := ecdsaKeySize(curve)
+			n := ecdsaOrder(curve)
+			assert.Equal(t, curve.Params().N, new(big.Int).SetBytes(n))
+			assert.Len(t, n, size)
+
+			// pad encodes x the way R and S appear in a signature.
+			pad := func(x *big.Int) []byte {
+				b := x.Bytes()
+				return append(make([]byte, size-len(b)), b...)
+			}
+
+			max := curve.Params().N
+			assert.False(t, ecdsaScalarInRange(make([]byte, size), n))
+			assert.True(t, ecdsaScalarInRange(pad(one), n))
+			assert.True(t, ecdsaScalarInRange(pad(new(big.Int).Sub(max, one)), n))
+			assert.False(t, ecdsaScalarInRange(pad(max), n))
+			assert.False(t, ecdsaScalarInRange(pad(new(big.Int).Add(max, one)), n))
+			assert.False(t, ecdsaScalarInRange(bytes.Repeat([]byte{0xff}, size), n))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import "crypto/ed25519"
+
+const algEdDSA = "EdDSA"
+
+// SignEdDSA takes an Ed25519 private key and a set of claims, and returns an
+// EdDSA-signed JWT containing those claims.
+//
+// VerifyEdDSA can verify tokens signed by SignEdDSA.
+//
+// When using SignEdDSA and VerifyEdDSA in production, use a keypair generated
+// by ed25519.GenerateKey or a tool like OpenSSL. Do not leak or give out the
+// private key to any systems or people that don't need it, because they will
+// be able to generate JWTs that will be indistinguishable from the ones you can
+// generate yourself.
+//
+// EdDSA is short for Edwards-curve Digital Signature Algorithm. This package
+// supports EdDSA as described in RFC8037, using the Ed25519 curve. Ed25519
+// signatures are deterministic, so unlike with ES256, signing the same claims
+// with the same key always produces the same JWT.
+//
+// EdDSA is a mechanism for message authentication. By signing a set of claims
+// with SignEdDSA, you have not encrypted it. It is trivial for anyone to read
+// the data stored in the return value of SignEdDSA. All SignEdDSA gives you is
+// a signature that proves that when you generated a JWT, you had a particular
+// Ed25519 private key on hand -- and it does this without giving away what the
+// private key is. VerifyEdDSA can verify the JWTs produced by SignEdDSA; to do
+// this, it needs to use the public key that corresponds to the private key you
+// used in SignEdDSA.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignEdDSA will return ErrInvalidKey if priv is not ed25519.PrivateKeySize
+// bytes long. Otherwise, it will return an error only if calling json.Marshal
+// on v returns an error, or if one of opts rejects the claims.
+func SignEdDSA(priv ed25519.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algEdDSA, ed25519.SignatureSize, v, opts, func(data []byte) ([]byte, error) {
+		if len(priv) != ed25519.PrivateKeySize {
+			return nil, ErrInvalidKey
+		}
+
+		return ed25519.Sign(priv, data), nil
+	})
+}
+
+// VerifyEdDSA verifies a JWT using an Ed25519 public key. If the JWT is
+// verified, VerifyEdDSA will serialize the claims inside the JWT into v.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// verification succeeds, VerifyEdDSA will deserialize the claims in the JWT
+// into v.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyEdDSA will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than EdDSA, and ErrBadSignature if it is not signed with the private
+// key that corresponds to the public key given. It will return ErrInvalidKey if
+// pub is not ed25519.PublicKeySize bytes long.
+func VerifyEdDSA(pub ed25519.PublicKey, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	header, claims, err := verify(algEdDSA, s, v, opts, verifyEdDSA(pub))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
+
+// verifyEdDSA returns a function suitable for passing to verify. The returned
+// function checks Ed25519 signatures made with the private key corresponding
+// to pub.
+func verifyEdDSA(pub ed25519.PublicKey) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		if len(pub) != ed25519.PublicKeySize {
+			return ErrInvalidKey
+		}
+
+		if !ed25519.Verify(pub, data, sig) {
+			return ErrBadSignature
+		}
+
+		return nil
+	}
+}
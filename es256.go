@@ -1,12 +1,12 @@
 package jwt
 
 import (
+	"context"
 	"crypto"
 	"crypto/ecdsa"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/json"
-	"math/big"
+	"crypto/elliptic"
+	"fmt"
+	"io"
 )
 
 const algES256 = "ES256"
@@ -36,28 +36,67 @@ const algES256 = "ES256"
 // encoding/json package of the standard library. The JSON representation of v
 // will be used as the claims part of the returned JWT.
 //
-// SignES256 will return an error only if calling json.Marshal on v returns an
-// error.
-func SignES256(priv *ecdsa.PrivateKey, v interface{}) ([]byte, error) {
-	return sign(algES256, 64, v, func(data []byte) ([]byte, error) {
-		h := crypto.SHA256.New()
-		h.Write(data)
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignES256 will return an error wrapping ErrInvalidKey if priv is not on the
+// P-256 curve.
+// Otherwise, it will return an error only if calling json.Marshal on v returns
+// an error, or if one of opts rejects the claims.
+func SignES256(priv *ecdsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return SignES256WithRand(nil, priv, v, opts...)
+}
 
-		sigR, sigS, err := ecdsa.Sign(rand.Reader, priv, h.Sum(nil))
-		if err != nil {
-			return nil, err
-		}
+// SignES256WithRand is like SignES256, except that the random nonce every ECDSA
+// signature needs is read from rand. If rand is nil, crypto/rand.Reader is
+// used, just as SignES256 does.
+//
+// This is for routing entropy through a particular source, such as an approved
+// DRBG. Since Go 1.26, crypto/ecdsa ignores rand and uses its own secure source
+// unless GODEBUG=cryptocustomrand=1 is set, so rand is no way to make
+// signatures reproducible there. Use jwttest.SignES256Deterministic for that.
+func SignES256WithRand(rand io.Reader, priv *ecdsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algES256, 2*ecdsaKeySize(elliptic.P256()), v, opts, signECDSA(rand, priv, algES256, elliptic.P256(), crypto.SHA256))
+}
 
-		sig := make([]byte, 64)
+// SignES256Signer is like SignES256, except that the signature is made by
+// signer. This lets you sign JWTs with keys that are kept in a hardware
+// security module or a cloud key management service, and which can't be
+// exported as an *ecdsa.PrivateKey.
+//
+// signer.Sign is passed a SHA-256 digest and crypto.SHA256 as its options, and
+// must return an ASN.1 DER-encoded ECDSA signature, as *ecdsa.PrivateKey and
+// most other implementations of crypto.Signer do. SignES256Signer converts it
+// to the form that JWTs use.
+//
+// SignES256Signer returns an error wrapping ErrInvalidKey if signer.Public() is
+// not an *ecdsa.PublicKey on the P-256 curve, or if signer.Sign returns a
+// signature that isn't well-formed. Otherwise, it returns any error that
+// signer.Sign returns, and otherwise only returns an error in the same cases as
+// SignES256.
+func SignES256Signer(signer crypto.Signer, v interface{}, opts ...SignOption) ([]byte, error) {
+	return SignES256Context(context.Background(), signer, v, opts...)
+}
 
-		r := sigR.Bytes()
-		s := sigS.Bytes()
+// SignES256Context is like SignES256Signer, except that it takes a context. If
+// signer is a ContextSigner, ctx is passed to its SignContext method, so that a
+// call to a remote key management service can be given a deadline or be
+// cancelled.
+//
+// If ctx is done before the token is signed, SignES256Context returns
+// ctx.Err(), and never a token. This is true even if signer is not a
+// ContextSigner, though in that case signer.Sign runs to completion first.
+func SignES256Context(ctx context.Context, signer crypto.Signer, v interface{}, opts ...SignOption) ([]byte, error) {
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: ES256 requires an ECDSA key, not %s", ErrInvalidKey, keyTypeName(signer.Public()))
+	}
 
-		copy(sig[32-len(r):], r)
-		copy(sig[64-len(s):], s)
+	if err := checkECDSACurve(algES256, pub.Curve, elliptic.P256()); err != nil {
+		return nil, err
+	}
 
-		return sig, nil
-	})
+	return sign(algES256, 2*ecdsaKeySize(elliptic.P256()), v, opts, signECDSASigner(ctx, signer, elliptic.P256(), crypto.SHA256))
 }
 
 // VerifyES256 verifies a JWT using a ECDSA public key. If the JWT is verified,
@@ -68,32 +107,48 @@ func SignES256(priv *ecdsa.PrivateKey, v interface{}) ([]byte, error) {
 // verification succeeds, VerifyES256 will deserialize the claims in the JWT
 // into v.
 //
-// VerifyES256 will return InvalidSignature if the JWT is malformed, uses any
-// algorithm other than RS256, or is not signed with the private key that
-// corresponds to the public key given.
-func VerifyES256(pub *ecdsa.PublicKey, s []byte, v interface{}) error {
-	claims, err := verify(algES256, s, func(data, sig []byte) error {
-		if len(sig) != 64 {
-			return ErrInvalidSignature
-		}
-
-		var sigR, sigS big.Int
-		sigR.SetBytes(sig[:32])
-		sigS.SetBytes(sig[32:])
-
-		h := sha256.New()
-		h.Write(data)
+// If v is a *json.RawMessage, VerifyES256 sets it to the claims of the JWT
+// exactly as they were signed, without decoding them. This is useful for
+// passing the claims along verbatim. The claims are in a newly allocated slice
+// that nothing else refers to.
+//
+// If v is nil, VerifyES256 verifies only the signature, and none of the
+// claims, not even "exp". See VerifyHS256.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyES256 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than ES256, and ErrBadSignature if it is not signed with the private
+// key that corresponds to the public key given. It will return an error
+// wrapping ErrInvalidKey if pub is not on the P-256 curve.
+func VerifyES256(pub *ecdsa.PublicKey, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
 
-		if !ecdsa.Verify(pub, h.Sum(nil), &sigR, &sigS) {
-			return ErrInvalidSignature
-		}
+	header, claims, err := verify(algES256, s, v, opts, verifyECDSA(pub, algES256, elliptic.P256(), crypto.SHA256))
+	if err != nil {
+		return err
+	}
 
-		return nil
-	})
+	return unmarshalClaims(header, claims, v, opts)
+}
 
+// SignES256String is like SignES256, except that it returns the token as a
+// string. The string is made without copying the token.
+func SignES256String(priv *ecdsa.PrivateKey, v interface{}, opts ...SignOption) (string, error) {
+	token, err := SignES256(priv, v, opts...)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return json.Unmarshal(claims, v)
+	return bytesString(token), nil
+}
+
+// VerifyES256String is like VerifyES256, except that it takes the token as a
+// string, such as one taken from an HTTP header. The token is not copied.
+func VerifyES256String(pub *ecdsa.PublicKey, s string, v interface{}, opts ...VerifyOption) error {
+	return VerifyES256(pub, stringBytes(s), v, opts...)
 }
@@ -0,0 +1,115 @@
+// Package es256k implements the ES256K JWT algorithm, which is ECDSA using the
+// secp256k1 curve and SHA-256.
+//
+// ES256K is described in RFC8812. It is mostly used by systems adjacent to
+// cryptocurrencies, which use secp256k1 for their own keys. It is left out of
+// github.com/ucarion/jwt, which supports only the most widely-supported
+// algorithms, and it lives in its own module so that applications that don't
+// need it don't depend on a secp256k1 implementation.
+//
+// Just like the functions in github.com/ucarion/jwt, Sign and Verify in this
+// package only ever use ES256K. Verify will reject any token whose "alg"
+// header is not exactly "ES256K".
+package es256k
+
+import (
+	"crypto/sha256"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/ucarion/jwt"
+)
+
+const alg = "ES256K"
+
+// sigLen is the length of an ES256K signature: two 32-byte integers, r and s.
+const sigLen = 64
+
+// Sign takes a secp256k1 private key and a set of claims, and returns an
+// ES256K-signed JWT containing those claims.
+//
+// Verify can verify tokens signed by Sign.
+//
+// Signatures are computed deterministically, as described in RFC6979, so
+// signing the same claims with the same key always produces the same JWT. The
+// signature is encoded as the 64-byte concatenation of R and S, as RFC8812
+// requires.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is signed. See
+// jwt.SignOption.
+//
+// Sign will return an error only if calling json.Marshal on v returns an
+// error, or if one of opts rejects the claims.
+func Sign(priv *secp256k1.PrivateKey, v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+	return jwt.SignCustomSize(alg, sigLen, v, func(data []byte) ([]byte, error) {
+		h := sha256.Sum256(data)
+		sig := ecdsa.Sign(priv, h[:])
+
+		r := sig.R()
+		s := sig.S()
+
+		out := make([]byte, sigLen)
+		r.PutBytesUnchecked(out[:32])
+		s.PutBytesUnchecked(out[32:])
+
+		return out, nil
+	}, opts...)
+}
+
+// Verify verifies a JWT using a secp256k1 public key. If the JWT is verified,
+// Verify will serialize the claims inside the JWT into v.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// verification succeeds, Verify will deserialize the claims in the JWT into v.
+//
+// opts can be used to further configure how the token is verified. See
+// jwt.VerifyOption.
+//
+// Verify will return an error wrapping jwt.ErrMalformedToken if the JWT is
+// malformed. It will return jwt.ErrWrongAlgorithm if the JWT uses any algorithm
+// other than ES256K, and jwt.ErrBadSignature if it is not signed with the
+// private key that corresponds to the public key given.
+func Verify(pub *secp256k1.PublicKey, s []byte, v interface{}, opts ...jwt.VerifyOption) error {
+	return jwt.VerifyCustom(alg, s, v, verifySignature(pub), opts...)
+}
+
+// Allow permits jwt.VerifyAny to accept ES256K tokens signed with the private
+// key corresponding to pub.
+func Allow(pub *secp256k1.PublicKey) jwt.AllowedAlgorithm {
+	return jwt.AllowCustom(alg, verifySignature(pub))
+}
+
+// verifySignature returns a function suitable for passing to jwt.VerifyCustom.
+// The returned function checks ES256K signatures made with the private key
+// corresponding to pub.
+func verifySignature(pub *secp256k1.PublicKey) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		if len(sig) != 64 {
+			return jwt.ErrInvalidSignature
+		}
+
+		// SetByteSlice reports whether its input was greater than or equal to
+		// the curve order. Such values are never valid, and must not be
+		// silently reduced. Neither is zero.
+		var sigR, sigS secp256k1.ModNScalar
+		if sigR.SetByteSlice(sig[:32]) || sigS.SetByteSlice(sig[32:]) {
+			return jwt.ErrInvalidSignature
+		}
+
+		if sigR.IsZero() || sigS.IsZero() {
+			return jwt.ErrInvalidSignature
+		}
+
+		h := sha256.Sum256(data)
+		if !ecdsa.NewSignature(&sigR, &sigS).Verify(h[:], pub) {
+			return jwt.ErrInvalidSignature
+		}
+
+		return nil
+	}
+}
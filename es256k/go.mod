@@ -0,0 +1,18 @@
+module github.com/ucarion/jwt/es256k
+
+go 1.17
+
+require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
+	github.com/stretchr/testify v1.5.1
+	github.com/ucarion/jwt v0.1.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
+
+// Use this checkout of the root module when developing in this repository.
+replace github.com/ucarion/jwt => ../
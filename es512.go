@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+)
+
+const algES512 = "ES512"
+
+// SignES512 takes a ECDSA private key on the P-521 curve and a set of claims,
+// and returns a ES512-signed JWT containing those claims.
+//
+// VerifyES512 can verify tokens signed by SignES512.
+//
+// When using SignES512 and VerifyES512 in production, use a ECDSA keypair
+// generated by a tool like OpenSSL. Do not leak or give out the private key to
+// any systems or people that don't need it, because they will be able to
+// generate JWTs that will be indistinguishable from the ones you can generate
+// yourself.
+//
+// ES512 is short for Elliptic Curve Digital Signature Algorithm with SHA-512.
+// It is a mechanism for message authentication. By signing a set of claims with
+// SignES512, you have not encrypted it. It is trivial for anyone to read the
+// data stored in the return value of SignES512. All SignES512 gives you is a
+// signature that proves that when you generated a JWT, you had a particular
+// ECDSA private key on hand -- and it does this without giving away what the
+// private key is. VerifyES512 can verify the JWTs produced by SignES512; to do
+// this, it needs to use the public key that corresponds to the private key you
+// used in SignES512.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignES512 will return an error wrapping ErrInvalidKey if priv is not on the
+// P-521 curve.
+// Otherwise, it will return an error only if calling json.Marshal on v returns
+// an error, or if one of opts rejects the claims.
+func SignES512(priv *ecdsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algES512, 2*ecdsaKeySize(elliptic.P521()), v, opts, signECDSA(nil, priv, algES512, elliptic.P521(), crypto.SHA512))
+}
+
+// VerifyES512 verifies a JWT using a ECDSA public key. If the JWT is verified,
+// VerifyES512 will serialize the claims inside the JWT into v.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// verification succeeds, VerifyES512 will deserialize the claims in the JWT
+// into v.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyES512 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than ES512, and ErrBadSignature if it is not signed with the private
+// key that corresponds to the public key given. It will return an error
+// wrapping ErrInvalidKey if pub is not on the P-521 curve.
+func VerifyES512(pub *ecdsa.PublicKey, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	header, claims, err := verify(algES512, s, v, opts, verifyECDSA(pub, algES512, elliptic.P521(), crypto.SHA512))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
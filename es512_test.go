@@ -0,0 +1,99 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestSignES512(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	// R and S are frequently shorter than 66 bytes, so sign a few times to make
+	// sure the left-padding is exercised.
+	for i := 0; i < 32; i++ {
+		token, err := jwt.SignES512(privateKey, claims)
+		assert.NoError(t, err)
+
+		parts := strings.Split(string(token), ".")
+		assert.Len(t, parts, 3)
+
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.NoError(t, err)
+		assert.Len(t, sig, 132)
+
+		var out jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyES512(&privateKey.PublicKey, token, &out))
+		assert.Equal(t, claims, out)
+	}
+}
+
+func TestSignES512WrongCurve(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = jwt.SignES512(privateKey, jwt.StandardClaims{})
+	assert.True(t, errors.Is(err, jwt.ErrInvalidKey))
+	assert.EqualError(t, err, "jwt: invalid key: ES512 requires an ECDSA key on P-521, not P-256")
+
+	privateKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = jwt.SignES256(privateKey, jwt.StandardClaims{})
+	assert.True(t, errors.Is(err, jwt.ErrInvalidKey))
+	assert.EqualError(t, err, "jwt: invalid key: ES256 requires an ECDSA key on P-256, not P-521")
+}
+
+func TestVerifyES512(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	token, err := jwt.SignES512(privateKey, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+
+	parts := strings.Split(string(token), ".")
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+
+	withSig := func(sig []byte) []byte {
+		return []byte(parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(sig))
+	}
+
+	var claims jwt.StandardClaims
+
+	// Wrong key.
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyES512(&otherKey.PublicKey, token, &claims))
+
+	// Key not on P-521.
+	err = jwt.VerifyES512(&p256Key.PublicKey, token, &claims)
+	assert.True(t, errors.Is(err, jwt.ErrInvalidKey))
+	assert.EqualError(t, err, "jwt: invalid key: ES512 requires an ECDSA key on P-521, not P-256")
+
+	// Signatures that aren't exactly 132 bytes, even if they'd otherwise decode
+	// to the same R and S.
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyES512(&privateKey.PublicKey, withSig(sig[:131]), &claims))
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyES512(&privateKey.PublicKey, withSig(append([]byte{0}, sig...)), &claims))
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyES512(&privateKey.PublicKey, withSig(append(sig, 0)), &claims))
+
+	// An ES512 token must not be accepted by VerifyES256, nor vice-versa.
+	es256, err := jwt.SignES256(p256Key, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyES512(&privateKey.PublicKey, es256, &claims))
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyES256(&p256Key.PublicKey, token, &claims))
+}
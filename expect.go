@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnexpectedIssuer is the error returned by the Verify functions in this
+// package when WithExpectedIssuer is used, and a JWT's "iss" is missing or is
+// not the expected issuer. The returned error wraps ErrUnexpectedIssuer, and
+// says what the "iss" was.
+var ErrUnexpectedIssuer = errors.New("jwt: unexpected issuer")
+
+// ErrUnexpectedAudience is the error returned by the Verify functions in this
+// package when WithExpectedAudience is used, and a JWT's "aud" is missing or
+// does not contain the expected audience. The returned error wraps
+// ErrUnexpectedAudience, and says what the "aud" was.
+var ErrUnexpectedAudience = errors.New("jwt: unexpected audience")
+
+// WithExpectedIssuer makes a Verify function reject JWTs whose "iss" claim is
+// not exactly iss. Such tokens are rejected with an error wrapping
+// ErrUnexpectedIssuer, as are tokens with no "iss".
+//
+// The check is done on the claims in the JWT, not on v, so it works no matter
+// what type of value you verify the JWT into.
+//
+// https://tools.ietf.org/html/rfc7519#section-4.1.1
+func WithExpectedIssuer(iss string) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.expectedIssuer = &iss
+	})
+}
+
+// WithExpectedAudience makes a Verify function reject JWTs whose "aud" claim
+// does not contain aud. "aud" may be a single string or an array of strings;
+// see Audience. Tokens whose "aud" doesn't contain aud are rejected with an
+// error wrapping ErrUnexpectedAudience, as are tokens with no "aud".
+//
+// Like WithExpectedIssuer, the check is done on the claims in the JWT, not on
+// v.
+//
+// https://tools.ietf.org/html/rfc7519#section-4.1.3
+func WithExpectedAudience(aud string) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.expectedAudience = &aud
+	})
+}
+
+// WithLeeway makes a Verify function check the "exp" and "nbf" claims of JWTs,
+// allowing for up to d of clock skew between the issuer and you. A token is
+// rejected with ErrExpiredToken if it expired more than d ago, and with
+// ErrNotYetValid if it becomes valid more than d from now. Tokens without an
+// "exp" or an "nbf" are not rejected for lacking them.
+//
+// Without WithLeeway or WithNow, the Verify functions don't check "exp" or
+// "nbf" at all, and it's up to you to call a method like
+// StandardClaims.Valid. To have them checked with no leeway, use WithLeeway(0).
+//
+// Like WithExpectedIssuer, the check is done on the claims in the JWT, not on
+// v.
+func WithLeeway(d time.Duration) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.checkTimes = true
+		c.leeway = d
+	})
+}
+
+// WithNow makes a Verify function check the "exp" and "nbf" claims of JWTs, in
+// the same way as WithLeeway, using now to get the current time instead of
+// time.Now. It is meant for tests.
+//
+// WithNow can also be passed to a Sign function, to set the time that
+// WithIssuedAtNow and WithSignPolicy use.
+func WithNow(now func() time.Time) NowOption {
+	return NowOption{now: now}
+}
+
+// checkExpectations checks the claims in the JSON claims against the
+// expectations set by WithExpectedIssuer, WithExpectedAudience, WithLeeway,
+// WithNow, WithClock, WithRequiredScopes, and WithAccessTokenProfile.
+func (c *verifyConfig) checkExpectations(claims []byte) error {
+	if c.expectedIssuer == nil && c.expectedAudience == nil && !c.checkTimes && len(c.requiredScopes) == 0 && len(c.requiredClaims) == 0 {
+		return nil
+	}
+
+	// Only the claims that are checked are decoded, so that an odd value of a
+	// claim nobody asked about doesn't get a token rejected.
+	var std map[string]json.RawMessage
+	if err := json.Unmarshal(claims, &std); err != nil {
+		return fmt.Errorf("jwt: cannot decode claims: %w", err)
+	}
+
+	// A missing claim is reported as missing, rather than as, for instance, an
+	// unexpected issuer.
+	if err := c.checkRequiredClaims(std); err != nil {
+		return err
+	}
+
+	if c.expectedIssuer != nil {
+		var iss *string
+		if err := json.Unmarshal(orNull(std["iss"]), &iss); err != nil {
+			return fmt.Errorf("jwt: cannot decode \"iss\" claim: %w", err)
+		}
+
+		if iss == nil {
+			return fmt.Errorf("%w: no \"iss\"", ErrUnexpectedIssuer)
+		}
+
+		if *iss != *c.expectedIssuer {
+			return fmt.Errorf("%w: %q", ErrUnexpectedIssuer, *iss)
+		}
+	}
+
+	if c.expectedAudience != nil {
+		var aud Audience
+		if err := json.Unmarshal(orNull(std["aud"]), &aud); err != nil {
+			return fmt.Errorf("jwt: cannot decode \"aud\" claim: %w", err)
+		}
+
+		if len(aud) == 0 {
+			return fmt.Errorf("%w: no \"aud\"", ErrUnexpectedAudience)
+		}
+
+		if !aud.Contains(*c.expectedAudience) {
+			return fmt.Errorf("%w: %q", ErrUnexpectedAudience, []string(aud))
+		}
+	}
+
+	if err := c.checkScopes(std); err != nil {
+		return err
+	}
+
+	if !c.checkTimes {
+		return nil
+	}
+
+	now := c.clock()
+
+	if exp, ok, err := optionalNumericDate("exp", std["exp"]); err != nil {
+		return err
+	} else if ok && now.Add(-c.leeway).After(exp) {
+		return ErrExpiredToken
+	}
+
+	if nbf, ok, err := optionalNumericDate("nbf", std["nbf"]); err != nil {
+		return err
+	} else if ok && now.Add(c.leeway).Before(nbf) {
+		return ErrNotYetValid
+	}
+
+	return nil
+}
+
+// optionalNumericDate parses raw, the value of the claim called name, as a
+// NumericDate. It returns false if the claim is missing or null.
+func optionalNumericDate(name string, raw json.RawMessage) (time.Time, bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, false, nil
+	}
+
+	t, err := parseNumericDate(string(raw))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("jwt: cannot decode %q claim: %w", name, err)
+	}
+
+	return t, true, nil
+}
+
+// orNull returns raw, or the JSON null if raw is empty because the claim it
+// came from is missing.
+func orNull(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return raw
+}
@@ -0,0 +1,136 @@
+package jwt_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestExpectations(t *testing.T) {
+	secret := []byte("my secret key")
+	now := time.Unix(1600000000, 0)
+	clock := func() time.Time { return now }
+
+	sign := func(claims string) []byte {
+		return forgeToken(`{"typ":"JWT","alg":"HS256"}`, claims, hmacSHA256(secret))
+	}
+
+	t.Run("issuer", func(t *testing.T) {
+		var claims jwt.StandardClaims
+		opt := jwt.WithExpectedIssuer("https://issuer.example.com")
+
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"iss":"https://issuer.example.com"}`), &claims, opt))
+
+		err := jwt.VerifyHS256(secret, sign(`{"iss":"https://evil.example.com"}`), &claims, opt)
+		assert.True(t, errors.Is(err, jwt.ErrUnexpectedIssuer))
+		assert.EqualError(t, err, `jwt: unexpected issuer: "https://evil.example.com"`)
+
+		err = jwt.VerifyHS256(secret, sign(`{}`), &claims, opt)
+		assert.EqualError(t, err, `jwt: unexpected issuer: no "iss"`)
+
+		err = jwt.VerifyHS256(secret, sign(`{"iss":null}`), &claims, opt)
+		assert.True(t, errors.Is(err, jwt.ErrUnexpectedIssuer))
+
+		err = jwt.VerifyHS256(secret, sign(`{"iss":1}`), &claims, opt)
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, jwt.ErrUnexpectedIssuer))
+	})
+
+	t.Run("audience", func(t *testing.T) {
+		var claims jwt.RegisteredClaims
+		opt := jwt.WithExpectedAudience("api://me")
+
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"aud":"api://me"}`), &claims, opt))
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"aud":["api://other","api://me"]}`), &claims, opt))
+
+		err := jwt.VerifyHS256(secret, sign(`{"aud":["api://other"]}`), &claims, opt)
+		assert.True(t, errors.Is(err, jwt.ErrUnexpectedAudience))
+		assert.EqualError(t, err, `jwt: unexpected audience: ["api://other"]`)
+
+		err = jwt.VerifyHS256(secret, sign(`{"aud":[]}`), &claims, opt)
+		assert.EqualError(t, err, `jwt: unexpected audience: no "aud"`)
+
+		// Only claims that are checked are decoded.
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"aud":"api://me","iss":1}`), &map[string]interface{}{}, opt))
+	})
+
+	t.Run("times", func(t *testing.T) {
+		var claims jwt.StandardClaims
+
+		expired := sign(`{"exp":1599999990}`)
+		notYetValid := sign(`{"nbf":1600000010}`)
+
+		// Without WithLeeway or WithNow, times are not checked.
+		assert.NoError(t, jwt.VerifyHS256(secret, expired, &claims))
+
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, expired, &claims, jwt.WithNow(clock)))
+		assert.Equal(t, jwt.ErrNotYetValid, jwt.VerifyHS256(secret, notYetValid, &claims, jwt.WithNow(clock)))
+
+		assert.NoError(t, jwt.VerifyHS256(secret, expired, &claims, jwt.WithNow(clock), jwt.WithLeeway(10*time.Second)))
+		assert.NoError(t, jwt.VerifyHS256(secret, notYetValid, &claims, jwt.WithNow(clock), jwt.WithLeeway(10*time.Second)))
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, expired, &claims, jwt.WithNow(clock), jwt.WithLeeway(9*time.Second)))
+
+		// Fractional times are compared exactly.
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, sign(`{"exp":1599999999.5}`), &map[string]interface{}{}, jwt.WithNow(clock)))
+
+		// Tokens without "exp" or "nbf" aren't rejected for it.
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{}`), &claims, jwt.WithLeeway(0)))
+
+		// WithLeeway alone uses the real clock.
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, expired, &claims, jwt.WithLeeway(0)))
+
+		err := jwt.VerifyHS256(secret, sign(`{"exp":"soon"}`), &map[string]interface{}{}, jwt.WithNow(clock))
+		assert.EqualError(t, err, `jwt: cannot decode "exp" claim: jwt: timestamp is not a number`)
+	})
+
+	t.Run("works with any claims type", func(t *testing.T) {
+		type customClaims struct {
+			jwt.StandardClaims
+			Scope string `json:"scope"`
+		}
+
+		token := sign(`{"iss":"https://issuer.example.com","exp":1599999990,"scope":"read"}`)
+		opts := []jwt.VerifyOption{jwt.WithExpectedIssuer("https://issuer.example.com"), jwt.WithNow(clock)}
+
+		var custom customClaims
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, token, &custom, opts...))
+
+		var m map[string]interface{}
+		assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, token, &m, opts...))
+
+		_, err := jwt.VerifyAny(token, &m, append(opts, jwt.AllowHS256(secret))...)
+		assert.Equal(t, jwt.ErrExpiredToken, err)
+	})
+
+	t.Run("signature is checked first", func(t *testing.T) {
+		var claims jwt.StandardClaims
+		token := forgeToken(`{"typ":"JWT","alg":"HS256"}`, `{"iss":"https://evil.example.com"}`, hmacSHA256([]byte("other secret")))
+		assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyHS256(secret, token, &claims, jwt.WithExpectedIssuer("https://issuer.example.com")))
+	})
+}
+
+func ExampleWithExpectedIssuer() {
+	secret := []byte("my secret key")
+	token, _ := jwt.SignHS256(secret, jwt.RegisteredClaims{
+		Issuer:         "https://issuer.example.com",
+		Audience:       jwt.Audience{"api://me"},
+		ExpirationTime: jwt.NumericDateFromTime(time.Unix(1600000000, 0)),
+	})
+
+	var claims jwt.RegisteredClaims
+	err := jwt.VerifyHS256(secret, token, &claims,
+		jwt.WithExpectedIssuer("https://issuer.example.com"),
+		jwt.WithExpectedAudience("api://me"),
+		jwt.WithLeeway(30*time.Second),
+		jwt.WithNow(func() time.Time { return time.Unix(1600000010, 0) }),
+	)
+
+	fmt.Println(err)
+	// Output:
+	//
+	// <nil>
+}
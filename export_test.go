@@ -0,0 +1,51 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"time"
+)
+
+// SetJWKSFetcherCooldown lets tests control how often a JWKSFetcher refreshes
+// its JWK Set because of an unknown "kid".
+func SetJWKSFetcherCooldown(f *JWKSFetcher, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cooldown = d
+}
+
+// SealA128CBCHS256, OpenA128CBCHS256, SealA256GCM, and OpenA256GCM let tests
+// check the content encryption of JWEs against the examples in RFC7516, none
+// of which use "dir".
+var (
+	SealA128CBCHS256 = a128CBCHS256.seal
+	OpenA128CBCHS256 = a128CBCHS256.open
+	SealA256GCM      = a256GCM.seal
+	OpenA256GCM      = a256GCM.open
+)
+
+// SigningInputSigner returns a function that signs the signing input of a JWS
+// the way the Sign function for alg does, or nil if alg's signatures are
+// randomized. The Sign functions always write their own header and claims, so
+// this is what lets tests check signatures against tokens from elsewhere.
+func SigningInputSigner(alg string, key interface{}) func(data []byte) ([]byte, error) {
+	switch key := key.(type) {
+	case []byte:
+		if alg == algHS256 {
+			return signHMAC(key, algHS256, crypto.SHA256, false)
+		}
+	case *rsa.PrivateKey:
+		if alg == algRS256 {
+			return signRSA(nil, key, algRS256, crypto.SHA256, false, false)
+		}
+	case ed25519.PrivateKey:
+		if alg == algEdDSA {
+			return func(data []byte) ([]byte, error) {
+				return ed25519.Sign(key, data), nil
+			}
+		}
+	}
+
+	return nil
+}
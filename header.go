@@ -0,0 +1,536 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Header is the header of a JWT.
+//
+// The Verify functions in this package only ever look at the "alg" member of a
+// JWT's header. Use WithHeader to see the rest of the header of a JWT once it
+// has been verified.
+//
+// https://tools.ietf.org/html/rfc7515#section-4
+type Header struct {
+	// Type is the "typ" header parameter.
+	//
+	// https://tools.ietf.org/html/rfc7515#section-4.1.9
+	Type string
+
+	// Algorithm is the "alg" header parameter.
+	//
+	// https://tools.ietf.org/html/rfc7515#section-4.1.1
+	Algorithm string
+
+	// KeyID is the "kid" header parameter. It identifies which key a JWT was
+	// signed with.
+	//
+	// https://tools.ietf.org/html/rfc7515#section-4.1.4
+	KeyID string
+
+	// Extra holds every header parameter other than "typ", "alg", and "kid",
+	// with each value as json.Unmarshal would decode it into an interface{}.
+	// Extra is nil if there are no such parameters.
+	Extra map[string]interface{}
+}
+
+// MarshalJSON implements json.Marshaler. "typ", "alg", and "kid" come first,
+// in that order, and are omitted if empty. They are followed by the members of
+// Extra, sorted by name. Members of Extra named "typ", "alg", or "kid" are
+// ignored.
+func (h Header) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	write := func(name string, v interface{}) error {
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+		return nil
+	}
+
+	for _, p := range []struct{ name, value string }{{"typ", h.Type}, {"alg", h.Algorithm}, {"kid", h.KeyID}} {
+		if p.value != "" {
+			if err := write(p.name, p.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(h.Extra))
+	for name := range h.Extra {
+		if !isRegisteredHeaderParam(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if err := write(name, h.Extra[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It returns an error if "typ",
+// "alg", or "kid" are present but are not strings.
+func (h *Header) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	*h = Header{}
+	for name, raw := range m {
+		var dst *string
+		switch name {
+		case "typ":
+			dst = &h.Type
+		case "alg":
+			dst = &h.Algorithm
+		case "kid":
+			dst = &h.KeyID
+		}
+
+		if dst != nil {
+			if bytes.Equal(raw, []byte("null")) || json.Unmarshal(raw, dst) != nil {
+				return errors.New("jwt: \"" + name + "\" header parameter must be a string")
+			}
+
+			continue
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+
+		if h.Extra == nil {
+			h.Extra = map[string]interface{}{}
+		}
+
+		h.Extra[name] = v
+	}
+
+	return nil
+}
+
+// isRegisteredHeaderParam returns whether name is one of the header
+// parameters that Header has a field for.
+func isRegisteredHeaderParam(name string) bool {
+	return name == "typ" || name == "alg" || name == "kid"
+}
+
+// WithKeyID makes a Sign function set the "kid" header parameter of the JWT it
+// produces to kid. This lets verifiers that trust several keys, such as those
+// in a JWK Set, pick which one to verify the JWT with.
+//
+// WithKeyID only adds "kid" to the header. The "typ" and "alg" header
+// parameters are the same as they are without WithKeyID. If kid is empty, the
+// header has no "kid".
+//
+// https://tools.ietf.org/html/rfc7515#section-4.1.4
+func WithKeyID(kid string) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.keyID = kid
+	})
+}
+
+// ErrReservedHeaderParam is the error returned by the Sign functions in this
+// package if WithHeaderParams is used to set "typ", "alg", "kid", or another
+// parameter that the Sign function sets itself, such as the "enc" of a JWE.
+// The returned error wraps ErrReservedHeaderParam, and names the parameter.
+var ErrReservedHeaderParam = errors.New("jwt: reserved header parameter")
+
+// WithHeaderParams makes a Sign function add params to the header of the JWT
+// it produces. Each value in params is encoded with json.Marshal.
+//
+// "typ" and "alg" are always controlled by this package, and "kid" is set with
+// WithKeyID. SignNested controls "cty", and EncryptJWE controls "enc". If
+// params contains any of these, compared case-insensitively, the Sign function
+// returns an error wrapping ErrReservedHeaderParam, and produces no JWT. This
+// way, the header can never claim the JWT was signed with some algorithm other
+// than the one that was really used.
+//
+// params are written after "typ", "alg", and "kid", sorted by name. If
+// WithHeaderParams is used more than once, the params are merged, with later
+// ones taking precedence.
+func WithHeaderParams(params map[string]interface{}) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		if c.headerParams == nil {
+			c.headerParams = map[string]interface{}{}
+		}
+
+		for name, value := range params {
+			c.headerParams[name] = value
+		}
+	})
+}
+
+// encodedHeaders holds the base64url-encoded header of a JWT signed with each
+// of the algorithms in this package, when the header has only "typ" and "alg".
+// The header is the same every time, so there's no need to marshal it again
+// for every token.
+var encodedHeaders = func() map[string][]byte {
+	headers := map[string][]byte{}
+	for _, alg := range []string{algHS256, algHS512, algRS256, algRS384, algPS256, algES256, algES512, algEdDSA} {
+		h, err := json.Marshal(header{Type: headerTypeJWT, Algorithm: alg})
+		if err != nil {
+			panic(err)
+		}
+
+		headers[alg] = []byte(base64.RawURLEncoding.EncodeToString(h))
+	}
+
+	return headers
+}()
+
+// knownHeader is a JWT header that verifySelect recognizes without decoding
+// it.
+type knownHeader struct {
+	alg  string
+	json []byte
+}
+
+// knownHeaders maps the base64url-encoded headers that this package, and most
+// other JWT libraries, produce to the JSON they decode to. These are headers
+// with only "alg", or only "typ" and "alg" in either order. Since nearly every
+// token has one of them, verifySelect can usually skip decoding the header and
+// checking it for duplicate members.
+var knownHeaders = func() map[string]knownHeader {
+	headers := map[string]knownHeader{}
+	for alg := range encodedHeaders {
+		for _, h := range []string{
+			`{"typ":"JWT","alg":"` + alg + `"}`,
+			`{"alg":"` + alg + `","typ":"JWT"}`,
+			`{"alg":"` + alg + `"}`,
+		} {
+			b := []byte(h)
+			headers[base64.RawURLEncoding.EncodeToString(b)] = knownHeader{alg: alg, json: b[:len(b):len(b)]}
+		}
+	}
+
+	return headers
+}()
+
+// encodeHeader is like marshalHeader, except that it returns the header
+// base64url-encoded, as it appears in the JWT. The returned slice may be
+// shared, and must not be modified.
+func (c *signConfig) encodeHeader(alg string) ([]byte, error) {
+	if c.keyID == "" && len(c.headerParams) == 0 && c.nestedPayload == nil {
+		if h, ok := encodedHeaders[alg]; ok {
+			return h, nil
+		}
+	}
+
+	h, err := c.marshalHeader(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, base64.RawURLEncoding.EncodedLen(len(h)))
+	base64.RawURLEncoding.Encode(encoded, h)
+
+	return encoded, nil
+}
+
+// marshalHeader returns the JSON header of a JWT signed with alg, with the
+// header parameters that c asks for.
+func (c *signConfig) marshalHeader(alg string) ([]byte, error) {
+	var fixed map[string]interface{}
+	if c.nestedPayload != nil {
+		fixed = map[string]interface{}{"cty": headerTypeJWT}
+	}
+
+	return c.marshalHeaderParams(headerTypeJWT, alg, fixed)
+}
+
+// marshalHeaderParams is like marshalHeader, except that the "typ" is typ, or
+// left out if typ is empty, and fixed are added to the header as well. Like
+// "typ", "alg", and "kid", the names in fixed are controlled by this package,
+// and can't be set with WithHeaderParams.
+func (c *signConfig) marshalHeaderParams(typ, alg string, fixed map[string]interface{}) ([]byte, error) {
+	if len(c.headerParams) == 0 && len(fixed) == 0 && typ != "" {
+		return json.Marshal(header{Type: typ, Algorithm: alg, KeyID: c.keyID})
+	}
+
+	reserved := map[string]bool{foldKey("typ"): true, foldKey("alg"): true, foldKey("kid"): true}
+	for name := range fixed {
+		reserved[foldKey(name)] = true
+	}
+
+	// Names are compared case-insensitively, for the same reason
+	// checkDuplicateKeys does.
+	params := make(map[string]interface{}, len(c.headerParams)+len(fixed))
+	for name, value := range c.headerParams {
+		if reserved[foldKey(name)] {
+			return nil, fmt.Errorf("%w: %q", ErrReservedHeaderParam, name)
+		}
+
+		params[name] = value
+	}
+
+	for name, value := range fixed {
+		params[name] = value
+	}
+
+	return json.Marshal(Header{Type: typ, Algorithm: alg, KeyID: c.keyID, Extra: params})
+}
+
+// WithHeader makes a Verify function store the header of the JWT it verifies
+// in h. h is only written to once the JWT's signature has been verified, so
+// its contents can be trusted as much as the claims can. h is written to even
+// if the claims are then rejected, so that the header can be logged alongside
+// the reason the claims were rejected.
+//
+// If the header can't be unmarshalled into a Header, for instance because its
+// "kid" is not a string, the Verify function returns an error wrapping
+// ErrMalformedToken, and so ErrInvalidSignature.
+func WithHeader(h *Header) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.header = h
+	})
+}
+
+// ErrUnexpectedType is the error returned by the Verify functions in this
+// package when WithExpectedType is used, and the "typ" header parameter of a
+// JWT isn't one of the expected types.
+var ErrUnexpectedType = errors.New("jwt: unexpected token type")
+
+// WithExpectedType makes a Verify function require that the "typ" header
+// parameter of the JWT it verifies is one of types. If it isn't, the Verify
+// function returns ErrUnexpectedType.
+//
+// For instance, resource servers that accept access tokens as described in
+// RFC9068 can use WithExpectedType("at+jwt") to keep other kinds of JWTs from
+// being used as access tokens.
+//
+// Types are compared case-insensitively, and a leading "application/" is
+// ignored, as RFC7515 recommends. So WithExpectedType("at+jwt") accepts a "typ"
+// of "at+JWT" or "application/at+jwt". To accept JWTs that have no "typ" at
+// all, include "" in types.
+//
+// Applications that handle more than one kind of JWT, such as access tokens and
+// security event tokens, should use WithExpectedType everywhere they verify
+// one, so that no kind can stand in for another. Where only plain JWTs are
+// expected, WithExpectedType("JWT", "") accepts a "typ" of "JWT", "jwt", or
+// "application/jwt", or none at all, and rejects "secevent+jwt", "at+jwt",
+// "JWE", and anything else.
+//
+// Without WithExpectedType, the Verify functions in this package accept any
+// "typ", or none at all.
+//
+// https://tools.ietf.org/html/rfc7515#section-4.1.9
+func WithExpectedType(types ...string) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.expectedTypes = append(c.expectedTypes, types...)
+
+		// Distinguish WithExpectedType() from not using WithExpectedType at all.
+		if c.expectedTypes == nil {
+			c.expectedTypes = []string{}
+		}
+	})
+}
+
+// checkType returns ErrUnexpectedType if the "typ" in the JSON header h isn't
+// one of c.expectedTypes.
+func (c *verifyConfig) checkType(h []byte) error {
+	if c.expectedTypes == nil {
+		return nil
+	}
+
+	var header header
+	if err := json.Unmarshal(h, &header); err != nil {
+		return malformedToken("header", err)
+	}
+
+	for _, t := range c.expectedTypes {
+		if strings.EqualFold(trimMediaType(t), trimMediaType(header.Type)) {
+			return nil
+		}
+	}
+
+	return ErrUnexpectedType
+}
+
+// trimMediaType removes the "application/" prefix from a media type, if it's
+// present.
+func trimMediaType(t string) string {
+	if len(t) >= len("application/") && strings.EqualFold(t[:len("application/")], "application/") {
+		return t[len("application/"):]
+	}
+
+	return t
+}
+
+// ErrUnsupportedCritical is the error returned by the Verify functions in this
+// package when the "crit" header parameter of a JWT is malformed, or lists a
+// header parameter that the caller has not said it understands. The returned
+// error wraps ErrUnsupportedCritical, and says what is wrong.
+//
+// https://tools.ietf.org/html/rfc7515#section-4.1.11
+var ErrUnsupportedCritical = errors.New("jwt: unsupported critical header parameter")
+
+// WithCriticalHeaders tells a Verify function that the caller understands and
+// processes the header parameters in names, and so that it's okay for them to
+// be listed in the "crit" header parameter of a JWT.
+//
+// RFC7515 requires that a JWT be rejected if its "crit" header parameter lists
+// any extension that the verifier doesn't understand. This package doesn't
+// understand any extensions on its own, so without WithCriticalHeaders, any
+// JWT with a "crit" header parameter is rejected with an error wrapping
+// ErrUnsupportedCritical.
+//
+// Use WithHeader to read the header parameters in names once the JWT is
+// verified. The Verify functions in this package don't do anything with them
+// beyond checking that they are present.
+//
+// The one extension this package does understand is "b64", from RFC7797, and
+// only VerifyDetachedHS256 accepts it. Listing "b64" in names doesn't make the
+// other Verify functions accept it.
+//
+// https://tools.ietf.org/html/rfc7515#section-4.1.11
+func WithCriticalHeaders(names ...string) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.critical = append(c.critical, names...)
+	})
+}
+
+// standardHeaderParams are the header parameters defined by RFC7515 and
+// RFC7516. RFC7515 forbids "crit" from listing any of these.
+var standardHeaderParams = []string{
+	"alg", "jku", "jwk", "kid", "x5u", "x5c", "x5t", "x5t#S256", "typ", "cty",
+	"crit", "enc", "zip",
+}
+
+// checkCritical returns an error wrapping ErrUnsupportedCritical if the JSON
+// header h has a "crit" header parameter that is malformed, or that lists a
+// name that isn't in c.critical.
+func (c *verifyConfig) checkCritical(h []byte) error {
+	// Almost no JWTs have a "crit", so avoid decoding the header again unless
+	// it might. The name of a member could be written with escape sequences,
+	// so this shortcut is only taken for headers without any backslashes.
+	if !bytes.Contains(h, []byte("crit")) && bytes.IndexByte(h, '\\') == -1 {
+		return nil
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(h, &header); err != nil {
+		return malformedToken("header", err)
+	}
+
+	raw, ok := header["crit"]
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(raw, &names); err != nil || len(names) == 0 {
+		return fmt.Errorf("%w: \"crit\" must be a non-empty array of strings", ErrUnsupportedCritical)
+	}
+
+	for _, name := range names {
+		for _, standard := range standardHeaderParams {
+			if name == standard {
+				return fmt.Errorf("%w: \"crit\" must not list %q", ErrUnsupportedCritical, name)
+			}
+		}
+
+		if _, ok := header[name]; !ok {
+			return fmt.Errorf("%w: %q is listed in \"crit\", but is missing", ErrUnsupportedCritical, name)
+		}
+
+		// "b64" changes what the signature is computed over. Only
+		// VerifyDetachedHS256 knows how to check such signatures, so no option
+		// can make the other Verify functions accept it.
+		if name == "b64" {
+			if !c.detached {
+				return fmt.Errorf("%w: %q is only supported by VerifyDetachedHS256", ErrUnsupportedCritical, name)
+			}
+
+			continue
+		}
+
+		understood := false
+		for _, c := range c.critical {
+			if name == c {
+				understood = true
+				break
+			}
+		}
+
+		if !understood {
+			return fmt.Errorf("%w: %q", ErrUnsupportedCritical, name)
+		}
+	}
+
+	return nil
+}
+
+// ErrUntrustedKeyHeader is the error returned by the Verify functions in this
+// package when a JWT has a "jwk" or "jku" header parameter, unless
+// WithUntrustedKeyHeaders is used.
+//
+// "jwk" embeds a key in the JWT, and "jku" points to a URL to fetch keys from.
+// Trusting either would let whoever made the JWT choose the key it's verified
+// with. This package never does so, but a JWT that has these parameters
+// usually means that its issuer expects them to be honored, which is a sign of
+// misconfiguration, or of an attack.
+var ErrUntrustedKeyHeader = errors.New("jwt: token carries its own key in a \"jwk\" or \"jku\" header parameter")
+
+// WithUntrustedKeyHeaders makes a Verify function accept JWTs with "jwk" or
+// "jku" header parameters, instead of rejecting them with
+// ErrUntrustedKeyHeader. These parameters are still never used to verify the
+// JWT.
+func WithUntrustedKeyHeaders() VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.allowKeyHeaders = true
+	})
+}
+
+// checkKeyHeaders returns ErrUntrustedKeyHeader if the JSON header h has a
+// "jwk" or "jku" header parameter, unless c allows them.
+func (c *verifyConfig) checkKeyHeaders(h []byte) error {
+	if c.allowKeyHeaders {
+		return nil
+	}
+
+	// See checkCritical for why this shortcut is safe.
+	if !bytes.Contains(h, []byte("jwk")) && !bytes.Contains(h, []byte("jku")) && bytes.IndexByte(h, '\\') == -1 {
+		return nil
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(h, &header); err != nil {
+		return malformedToken("header", err)
+	}
+
+	if _, ok := header["jwk"]; ok {
+		return ErrUntrustedKeyHeader
+	}
+
+	if _, ok := header["jku"]; ok {
+		return ErrUntrustedKeyHeader
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build go1.18
+// +build go1.18
+
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+// FuzzDecodeHeader checks that recognizing a header in knownHeaders comes to
+// the same decision, and the same header, as decoding it the slow way.
+func FuzzDecodeHeader(f *testing.F) {
+	for _, known := range knownHeaders {
+		f.Add(known.json)
+	}
+
+	f.Add([]byte(`{"alg": "HS256", "typ": "JWT"}`))
+	f.Add([]byte(`{"typ":"JWT","alg":"HS256","kid":"k1"}`))
+	f.Add([]byte(`{"typ":"JWT","alg":"HS256","alg":"none"}`))
+	f.Add([]byte(`{"typ":"JWT","alg":"hs256"}`))
+	f.Add([]byte(`{"typ":"JWT","alg":"HS256"} `))
+	f.Add([]byte(`{"typ":"JWT","alg":"HS256"}`))
+	f.Add([]byte(`{"typ":"jwt","alg":"HS256"}`))
+	f.Add([]byte(`{"alg":"HS256","kid":1}`))
+
+	f.Fuzz(func(t *testing.T, header []byte) {
+		encoded := []byte(base64.RawURLEncoding.EncodeToString(header))
+
+		alg, decoded, _, err := decodeHeader(nil, encoded)
+		slowAlg, slowDecoded, _, slowErr := decodeUnknownHeader(nil, encoded)
+		ok, slowOK := err == nil, slowErr == nil
+
+		if ok != slowOK || alg != slowAlg || !bytes.Equal(decoded, slowDecoded) {
+			t.Fatalf("%s: fast path gave (%q, %s, %v), slow path gave (%q, %s, %v)", header, alg, decoded, ok, slowAlg, slowDecoded, slowOK)
+		}
+
+		if _, known := knownHeaders[string(encoded)]; known && !bytes.Equal(decoded, header) {
+			t.Fatalf("%s: known header decoded to %s", header, decoded)
+		}
+	})
+}
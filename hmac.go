@@ -0,0 +1,236 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWeakSecret is the error returned when WithStrictSecrets is used, and a
+// secret for HS256 or HS512 is shorter than RFC7518, Section 3.2 requires: 32
+// bytes for HS256, and 64 bytes for HS512. The returned error wraps
+// ErrWeakSecret, and says how long the secret is.
+var ErrWeakSecret = errors.New("jwt: secret is too short")
+
+// StrictSecretsOption is the option returned by WithStrictSecrets. It is both
+// a SignOption and a VerifyOption.
+type StrictSecretsOption struct{}
+
+func (StrictSecretsOption) applySign(c *signConfig) {
+	c.strictSecrets = true
+}
+
+func (StrictSecretsOption) applyVerify(c *verifyConfig) {
+	c.strictSecrets = true
+}
+
+// WithStrictSecrets makes SignHS256, VerifyHS256, and the other HS256 and
+// HS512 functions return an error wrapping ErrWeakSecret if they are given a
+// secret that is shorter than the output of the hash function: 32 bytes for
+// HS256, and 64 bytes for HS512. This is the minimum that RFC7518, Section 3.2
+// requires.
+//
+// Short secrets are accepted by default, so that existing users of this package
+// aren't broken. New code should use WithStrictSecrets, on both the signing and
+// verifying sides, so that a server can't be configured with a weak secret by
+// mistake. Passed to VerifyAny, VerifyWithKeySet, or VerifyNested, it applies
+// to the secrets given by AllowHS256 and AllowHS512.
+//
+// https://tools.ietf.org/html/rfc7518#section-3.2
+func WithStrictSecrets() StrictSecretsOption {
+	return StrictSecretsOption{}
+}
+
+// checkSecretLength returns an error wrapping ErrWeakSecret if secret is
+// shorter than the output of hash, unless strict is false.
+func checkSecretLength(alg string, secret []byte, hash crypto.Hash, strict bool) error {
+	if !strict {
+		return nil
+	}
+
+	if len(secret) < hash.Size() {
+		return fmt.Errorf("%w: %s requires a secret of at least %d bytes, not %d", ErrWeakSecret, alg, hash.Size(), len(secret))
+	}
+
+	return nil
+}
+
+// signHMAC returns a function suitable for passing to sign. The returned
+// function computes the HMAC of data using secret and hash.
+//
+// If strict is true, the returned function returns an error wrapping
+// ErrWeakSecret if secret is too short to be used with alg.
+func signHMAC(secret []byte, alg string, hash crypto.Hash, strict bool) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		if err := checkSecretLength(alg, secret, hash, strict); err != nil {
+			return nil, err
+		}
+
+		return cachedHMACPool(secret, alg, hash).sum(data), nil
+	}
+}
+
+// verifyHMAC returns a function suitable for passing to verify. It is the
+// counterpart of signHMAC. The comparison is done in constant time.
+func verifyHMAC(secret []byte, alg string, hash crypto.Hash, strict bool) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		if err := checkSecretLength(alg, secret, hash, strict); err != nil {
+			return err
+		}
+
+		if cachedHMACPool(secret, alg, hash).compare(data, sig) != 1 {
+			return ErrBadSignature
+		}
+
+		return nil
+	}
+}
+
+// verifyHMACAny is like verifyHMAC, except that it accepts a signature made
+// with any of secrets, and stores the index of the first matching secret in
+// index.
+//
+// Every secret is checked, and the index is chosen without branching on which
+// secrets matched, so that timing doesn't reveal which secret was used.
+func verifyHMACAny(secrets [][]byte, alg string, hash crypto.Hash, strict bool, index *int) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		for _, secret := range secrets {
+			if err := checkSecretLength(alg, secret, hash, strict); err != nil {
+				return err
+			}
+		}
+
+		found, match := 0, 0
+		for i, secret := range secrets {
+			eq := cachedHMACPool(secret, alg, hash).compare(data, sig)
+			match = subtle.ConstantTimeSelect(eq&^found, i, match)
+			found |= eq
+		}
+
+		if found == 0 {
+			return ErrBadSignature
+		}
+
+		*index = match
+		return nil
+	}
+}
+
+// hmacPool computes HMACs with a fixed secret. hmac.New hashes the secret into
+// the inner and outer pads every time it's called; hmacPool does that once per
+// pooled hash.Hash, and afterwards only resets the hashes it hands out.
+//
+// hmacPool is safe for concurrent use.
+type hmacPool struct {
+	secret []byte
+	alg    string
+	hash   crypto.Hash
+	pool   sync.Pool
+}
+
+// hmacState is what a hmacPool pools: a hash.Hash, and somewhere to put its
+// sum so that comparing it against a signature doesn't allocate.
+type hmacState struct {
+	h   hash.Hash
+	sum [sha512.Size]byte
+}
+
+// newHMACPool returns a hmacPool for alg, which uses hash. It keeps a copy of
+// secret, so that later changes to secret don't affect it.
+func newHMACPool(secret []byte, alg string, hash crypto.Hash) *hmacPool {
+	p := &hmacPool{secret: append([]byte(nil), secret...), alg: alg, hash: hash}
+	p.pool.New = func() interface{} {
+		return &hmacState{h: hmac.New(p.hash.New, p.secret)}
+	}
+
+	return p
+}
+
+// sum returns the HMAC of data.
+func (p *hmacPool) sum(data []byte) []byte {
+	st := p.pool.Get().(*hmacState)
+	st.h.Write(data)
+	mac := st.h.Sum(nil)
+
+	st.h.Reset()
+	p.pool.Put(st)
+	return mac
+}
+
+// compare returns 1 if sig is the HMAC of data, and 0 otherwise, in the manner
+// of subtle.ConstantTimeCompare.
+func (p *hmacPool) compare(data, sig []byte) int {
+	st := p.pool.Get().(*hmacState)
+	st.h.Write(data)
+	eq := subtle.ConstantTimeCompare(st.h.Sum(st.sum[:0]), sig)
+
+	st.h.Reset()
+	p.pool.Put(st)
+	return eq
+}
+
+// sign is like signHMAC, but uses the pool's secret and hashes.
+func (p *hmacPool) sign(strict bool) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		if err := checkSecretLength(p.alg, p.secret, p.hash, strict); err != nil {
+			return nil, err
+		}
+
+		return p.sum(data), nil
+	}
+}
+
+// verify is like verifyHMAC, but uses the pool's secret and hashes.
+func (p *hmacPool) verify(strict bool) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		if err := checkSecretLength(p.alg, p.secret, p.hash, strict); err != nil {
+			return err
+		}
+
+		if p.compare(data, sig) != 1 {
+			return ErrBadSignature
+		}
+
+		return nil
+	}
+}
+
+// hmacPoolCacheSize is how many secrets cachedHMACPool remembers. Most programs
+// use one or two secrets, or a few more while rotating them.
+const hmacPoolCacheSize = 8
+
+// hmacPools holds the hmacPools that cachedHMACPool has handed out most
+// recently. Each slot holds a *hmacPool, or nothing.
+var hmacPools struct {
+	slots [hmacPoolCacheSize]atomic.Value
+	next  uint32
+}
+
+// cachedHMACPool returns a hmacPool for secret and alg, so that the
+// package-level HS256 and HS512 functions don't recompute the HMAC key schedule
+// on every call with the same secret.
+//
+// Looking up a cached pool doesn't lock or allocate. When a secret isn't
+// cached, a new pool replaces one of the cached ones, round-robin; a program
+// that cycles through more than hmacPoolCacheSize secrets gets little benefit,
+// and should use HS256Signer and HS256Verifier instead.
+func cachedHMACPool(secret []byte, alg string, hash crypto.Hash) *hmacPool {
+	for i := range hmacPools.slots {
+		p, ok := hmacPools.slots[i].Load().(*hmacPool)
+		if ok && p.alg == alg && p.hash == hash && hmac.Equal(p.secret, secret) {
+			return p
+		}
+	}
+
+	p := newHMACPool(secret, alg, hash)
+	i := atomic.AddUint32(&hmacPools.next, 1) % hmacPoolCacheSize
+	hmacPools.slots[i].Store(p)
+
+	return p
+}
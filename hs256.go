@@ -1,9 +1,9 @@
 package jwt
 
 import (
-	"crypto/hmac"
+	"crypto"
 	"crypto/sha256"
-	"encoding/json"
+	"fmt"
 )
 
 const algHS256 = "HS256"
@@ -31,15 +31,15 @@ const algHS256 = "HS256"
 // encoding/json package of the standard library. The JSON representation of v
 // will be used as the claims part of the returned JWT.
 //
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
 // SignHS256 will return an error only if calling json.Marshal on v returns an
-// error.
-func SignHS256(secret []byte, v interface{}) ([]byte, error) {
-	return sign(algHS256, sha256.Size, v, func(data []byte) ([]byte, error) {
-		h := hmac.New(sha256.New, secret)
-		h.Write(data)
-
-		return h.Sum(nil), nil
-	})
+// error, or if one of opts rejects the claims. If WithStrictSecrets is among
+// opts, SignHS256 also returns an error wrapping ErrWeakSecret if secret is
+// shorter than 32 bytes.
+func SignHS256(secret []byte, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algHS256, sha256.Size, v, opts, signHMAC(secret, algHS256, crypto.SHA256, newSignConfig(opts).strictSecrets))
 }
 
 // VerifyHS256 verifies a JWT using a secret. If the JWT is verified,
@@ -50,23 +50,200 @@ func SignHS256(secret []byte, v interface{}) ([]byte, error) {
 // verification succeeds, VerifyHS256 will deserialize the claims in the JWT
 // into v.
 //
-// VerifyHS256 will return InvalidSignature if the JWT is malformed, uses any
-// algorithm other than HS256, or is not signed with the given secret.
-func VerifyHS256(secret, s []byte, v interface{}) error {
-	claims, err := verify(algHS256, s, func(data, sig []byte) error {
-		h := hmac.New(sha256.New, secret)
-		h.Write(data)
+// If v is a *json.RawMessage, VerifyHS256 sets it to the claims of the JWT
+// exactly as they were signed, without decoding them. This is useful for
+// passing the claims along verbatim. The claims are in a newly allocated slice
+// that nothing else refers to.
+//
+// If v is nil, VerifyHS256 only verifies the signature. The claims are not
+// decoded from base64 or from JSON, and so no claim is validated in any way:
+// an expired token, or one meant for someone else, is accepted so long as its
+// signature is valid. This is for code that passes tokens along without
+// looking inside them. Because the signature covers the claims, a token whose
+// claims were tampered with is still rejected. Options that check claims, such
+// as WithExpectedIssuer or WithReplayStore, can't be used with a nil v; see
+// ErrInvalidClaimsTarget.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyHS256 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than HS256, and ErrBadSignature if it is not signed with the given
+// secret. All three of these errors match ErrInvalidSignature, so code that
+// only needs to know whether the JWT is authentic can check for that alone. If
+// WithStrictSecrets is among opts, VerifyHS256 returns an error wrapping
+// ErrWeakSecret if secret is shorter than 32 bytes.
+func VerifyHS256(secret, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
 
-		if !hmac.Equal(h.Sum(nil), sig) {
-			return ErrInvalidSignature
-		}
+	header, claims, err := verify(algHS256, s, v, opts, verifyHMAC(secret, algHS256, crypto.SHA256, newVerifyConfig(opts).strictSecrets))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
+
+// VerifyHS256Any is like VerifyHS256, except that it accepts a JWT signed with
+// any one of secrets. It returns the index in secrets of the secret that the
+// JWT was signed with.
+//
+// VerifyHS256Any is meant for rotating secrets. While you move from an old
+// secret to a new one, pass both, and use the returned index to find out when
+// tokens signed with the old secret have stopped arriving.
+//
+// The JWT is parsed only once, and it is checked against every secret, even
+// after one matches. So how long VerifyHS256Any takes doesn't reveal which of
+// secrets the JWT was signed with.
+//
+// If secrets is empty, VerifyHS256Any returns an error wrapping ErrInvalidKey.
+// Otherwise, it returns the errors VerifyHS256 would, and returns
+// ErrBadSignature only if VerifyHS256 would for every one of secrets. If
+// VerifyHS256Any returns an error, the returned index is -1.
+func VerifyHS256Any(secrets [][]byte, s []byte, v interface{}, opts ...VerifyOption) (int, error) {
+	if err := checkClaimsTarget(v); err != nil {
+		return -1, err
+	}
 
-		return nil
-	})
+	if len(secrets) == 0 {
+		return -1, fmt.Errorf("%w: no secrets", ErrInvalidKey)
+	}
 
+	var index int
+	header, claims, err := verify(algHS256, s, v, opts, verifyHMACAny(secrets, algHS256, crypto.SHA256, newVerifyConfig(opts).strictSecrets, &index))
+	if err != nil {
+		return -1, err
+	}
+
+	if err := unmarshalClaims(header, claims, v, opts); err != nil {
+		return -1, err
+	}
+
+	return index, nil
+}
+
+// HS256Signer signs JWTs with HS256, using a secret fixed when the
+// HS256Signer is constructed. It produces the same tokens as SignHS256, but
+// is faster when many tokens are signed with the same secret, because it does
+// the work of preparing the secret for HMAC only once, rather than on every
+// call.
+//
+// An HS256Signer is a Signer, and is safe for concurrent use. Construct one
+// using NewHS256Signer.
+type HS256Signer struct {
+	pool *hmacPool
+	opts []SignOption
+}
+
+// NewHS256Signer returns an HS256Signer that signs with secret. It keeps a
+// copy of secret, so changing secret afterwards has no effect.
+//
+// opts are applied to every token the HS256Signer signs, before any opts
+// passed to Sign. This is the way to make sure every token an issuer produces
+// satisfies a SignPolicy, no matter which code ends up calling Sign:
+//
+//	signer := jwt.NewHS256Signer(secret, jwt.WithSignPolicy(jwt.SignPolicy{
+//		RequireExpirationTime: true,
+//		MaxTTL:                time.Hour,
+//	}))
+func NewHS256Signer(secret []byte, opts ...SignOption) *HS256Signer {
+	return &HS256Signer{
+		pool: newHMACPool(secret, algHS256, crypto.SHA256),
+		opts: opts[:len(opts):len(opts)],
+	}
+}
+
+// Sign is like SignHS256, using the secret h was constructed with.
+func (h *HS256Signer) Sign(v interface{}, opts ...SignOption) ([]byte, error) {
+	if len(h.opts) != 0 {
+		opts = append(h.opts, opts...)
+	}
+
+	return sign(algHS256, sha256.Size, v, opts, h.pool.sign(newSignConfig(opts).strictSecrets))
+}
+
+// HS256Verifier verifies JWTs with HS256, using a secret fixed when the
+// HS256Verifier is constructed. It accepts the same tokens as VerifyHS256, but
+// is faster when many tokens are verified with the same secret. See
+// HS256Signer.
+//
+// An HS256Verifier is a Verifier, and is safe for concurrent use. Construct
+// one using NewHS256Verifier.
+type HS256Verifier struct {
+	pool   *hmacPool
+	config verifyConfig
+	verify func(data, sig []byte) error
+}
+
+// NewHS256Verifier returns an HS256Verifier that verifies with secret. It
+// keeps a copy of secret, so changing secret afterwards has no effect.
+//
+// opts are used every time the HS256Verifier verifies a token, in the same
+// way as they would be if they were passed to VerifyHS256.
+func NewHS256Verifier(secret []byte, opts ...VerifyOption) *HS256Verifier {
+	h := &HS256Verifier{
+		pool:   newHMACPool(secret, algHS256, crypto.SHA256),
+		config: newVerifyConfig(opts),
+	}
+
+	h.verify = h.pool.verify(h.config.strictSecrets)
+	return h
+}
+
+// Verify is like VerifyHS256, using the secret and options h was constructed
+// with. If the JWT is verified, Verify will serialize the claims inside the JWT
+// into v.
+func (h *HS256Verifier) Verify(s []byte, v interface{}) error {
+	return h.VerifyBuf(s, nil, v)
+}
+
+// VerifyBuf is like Verify, except that it decodes the JWT into buf instead of
+// allocating memory to decode it into. If buf is shorter than s, VerifyBuf
+// allocates memory as Verify does.
+//
+// VerifyBuf is for programs that verify so many tokens that the garbage from
+// doing so matters. For a token whose header has only "typ" and "alg", as
+// almost all tokens do, and with options that only check claims, VerifyBuf
+// allocates nothing except what encoding/json allocates while decoding the
+// claims into v. Reusing one buf for many tokens, such as by keeping one per
+// goroutine or in a sync.Pool, is the point of VerifyBuf.
+//
+// VerifyBuf overwrites the contents of buf. The decoded claims are in buf while
+// VerifyBuf decodes them into v, but encoding/json copies any part of them it
+// keeps, including into a json.RawMessage, so nothing refers to buf once
+// VerifyBuf returns, and buf can be reused right away. The one exception is a
+// type in v whose UnmarshalJSON method keeps the slice it is passed, which
+// json.Unmarshaler forbids. Don't use the same buf in two calls to VerifyBuf
+// at once.
+func (h *HS256Verifier) VerifyBuf(s, buf []byte, v interface{}) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	header, claims, err := verifyBuf(algHS256, s, buf, v, h.config.lenientBase64, h.verify)
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(claims, v)
+	return h.config.unmarshalClaims(header, claims, v, buf != nil)
+}
+
+// SignHS256String is like SignHS256, except that it returns the token as a
+// string. The string is made without copying the token.
+func SignHS256String(secret []byte, v interface{}, opts ...SignOption) (string, error) {
+	token, err := SignHS256(secret, v, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return bytesString(token), nil
+}
+
+// VerifyHS256String is like VerifyHS256, except that it takes the token as a
+// string, such as one taken from an HTTP header. The token is not copied.
+func VerifyHS256String(secret []byte, s string, v interface{}, opts ...VerifyOption) error {
+	return VerifyHS256(secret, stringBytes(s), v, opts...)
 }
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/sha512"
+)
+
+const algHS512 = "HS512"
+
+// SignHS512 takes a secret and a set of claims, and returns a HS512-signed JWT
+// containing those claims.
+//
+// VerifyHS512 can verify tokens signed by SignHS512.
+//
+// When using SignHS512 and VerifyHS512 in production, use a long,
+// randomly-generated secret. Do not leak or give out the secret to any systems
+// or people that don't need it, because they will be able to generate JWTs that
+// will be indistinguishable from the ones you can generate yourself.
+//
+// HS512 is short for HMAC SHA-512. It is a mechanism for message
+// authentication. By signing a set of claims with SignHS512, you have not
+// encrypted it. It is trivial for anyone to read the data stored in the return
+// value of SignHS512. All SignHS512 gives you is a signature that proves that
+// when you generated a JWT, you had a particular secret on hand -- and it does
+// this without giving away what the secret is. VerifyHS512 can verify the JWTs
+// produced by SignHS512; to do this, it needs to use the same secret you gave
+// to SignHS512.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignHS512 will return an error only if calling json.Marshal on v returns an
+// error, or if one of opts rejects the claims. If WithStrictSecrets is among
+// opts, SignHS512 also returns an error wrapping ErrWeakSecret if secret is
+// shorter than 64 bytes.
+func SignHS512(secret []byte, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algHS512, sha512.Size, v, opts, signHMAC(secret, algHS512, crypto.SHA512, newSignConfig(opts).strictSecrets))
+}
+
+// VerifyHS512 verifies a JWT using a secret. If the JWT is verified,
+// VerifyHS512 will serialize the claims inside the JWT into v.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// verification succeeds, VerifyHS512 will deserialize the claims in the JWT
+// into v.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyHS512 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than HS512, and ErrBadSignature if it is not signed with the given
+// secret. If WithStrictSecrets is among opts, VerifyHS512 returns an error
+// wrapping ErrWeakSecret if secret is shorter than 64 bytes.
+func VerifyHS512(secret, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	header, claims, err := verify(algHS512, s, v, opts, verifyHMAC(secret, algHS512, crypto.SHA512, newVerifyConfig(opts).strictSecrets))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
@@ -0,0 +1,7 @@
+// Package interop checks that this package and github.com/golang-jwt/jwt/v5
+// agree on what a JWT is: each must accept the tokens the other signs, with
+// the same claims, and each must reject them once they've been tampered with.
+//
+// There is nothing to import here, only tests. This package lives in its own
+// module so that github.com/ucarion/jwt doesn't depend on golang-jwt.
+package interop
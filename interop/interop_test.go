@@ -0,0 +1,315 @@
+package interop_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	jwt5 "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// algorithm is one of the algorithms both packages support, along with a key
+// to use it with.
+type algorithm struct {
+	method jwt5.SigningMethod
+
+	// signKey and verifyKey are what golang-jwt signs and verifies with.
+	signKey, verifyKey interface{}
+
+	// sign and verify use this package.
+	sign   func(v interface{}) ([]byte, error)
+	verify func(s []byte, v interface{}) error
+}
+
+func algorithms(t *testing.T) []algorithm {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	assert.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	return []algorithm{
+		{
+			method:    jwt5.SigningMethodHS256,
+			signKey:   secret,
+			verifyKey: secret,
+			sign: func(v interface{}) ([]byte, error) {
+				return jwt.SignHS256(secret, v)
+			},
+			verify: func(s []byte, v interface{}) error {
+				return jwt.VerifyHS256(secret, s, v)
+			},
+		},
+		{
+			method:    jwt5.SigningMethodRS256,
+			signKey:   rsaKey,
+			verifyKey: &rsaKey.PublicKey,
+			sign: func(v interface{}) ([]byte, error) {
+				return jwt.SignRS256(rsaKey, v)
+			},
+			verify: func(s []byte, v interface{}) error {
+				return jwt.VerifyRS256(&rsaKey.PublicKey, s, v)
+			},
+		},
+		{
+			method:    jwt5.SigningMethodES256,
+			signKey:   ecKey,
+			verifyKey: &ecKey.PublicKey,
+			sign: func(v interface{}) ([]byte, error) {
+				return jwt.SignES256(ecKey, v)
+			},
+			verify: func(s []byte, v interface{}) error {
+				return jwt.VerifyES256(&ecKey.PublicKey, s, v)
+			},
+		},
+	}
+}
+
+// parse verifies s with golang-jwt, accepting only a's algorithm.
+func (a algorithm) parse(s []byte) (*jwt5.Token, error) {
+	parser := jwt5.NewParser(jwt5.WithValidMethods([]string{a.method.Alg()}), jwt5.WithJSONNumber())
+	return parser.Parse(string(s), func(*jwt5.Token) (interface{}, error) {
+		return a.verifyKey, nil
+	})
+}
+
+// userClaims is a claims struct of the sort applications define for use with
+// golang-jwt.
+type userClaims struct {
+	jwt5.RegisteredClaims
+	Name  string   `json:"name"`
+	Admin bool     `json:"admin"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// claimsShapes are the claims each algorithm is tested with.
+func claimsShapes() map[string]jwt5.Claims {
+	now := time.Now().Truncate(time.Second)
+
+	return map[string]jwt5.Claims{
+		"struct": &userClaims{
+			RegisteredClaims: jwt5.RegisteredClaims{
+				Issuer:    "https://issuer.example.com",
+				Subject:   "jdoe@example.com",
+				Audience:  jwt5.ClaimStrings{"api", "admin"},
+				ExpiresAt: jwt5.NewNumericDate(now.Add(time.Hour)),
+				IssuedAt:  jwt5.NewNumericDate(now),
+				ID:        "abc123",
+			},
+			Name:  "John Doe",
+			Admin: true,
+			Roles: []string{"reader", "writer"},
+		},
+		"map": jwt5.MapClaims{
+			"sub":   "jdoe@example.com",
+			"aud":   "api",
+			"scope": "read write",
+		},
+		"nested": jwt5.MapClaims{
+			"sub": "jdoe@example.com",
+			"user": map[string]interface{}{
+				"name":   map[string]interface{}{"given": "John", "family": "Doe"},
+				"groups": []interface{}{"a", map[string]interface{}{"b": []interface{}{"c", nil, true}}},
+			},
+			"empty": map[string]interface{}{},
+			"list":  []interface{}{},
+		},
+		"unicode": jwt5.MapClaims{
+			"name":    "Zoë Ñandú",
+			"cjk":     "李小龍",
+			"emoji":   "🔑🙂",
+			"escapes": "<a href=\"x\">&amp;</a> \\\t",
+			"ключ":    "значение",
+		},
+		"large numbers": jwt5.MapClaims{
+			"big":      json.Number("12345678901234567890"),
+			"maxInt64": json.Number("9223372036854775807"),
+			"minInt64": json.Number("-9223372036854775808"),
+			"unsafe":   json.Number("9007199254740993"),
+			"float":    json.Number("0.1"),
+			"exponent": json.Number("1e300"),
+		},
+	}
+}
+
+// decodeJSON decodes data the same way whichever package produced it, keeping
+// numbers exactly as they were written so that large ones can be compared.
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	assert.NoError(t, dec.Decode(&v))
+	return v
+}
+
+// marshalJSON encodes v as JSON, and then decodes it with decodeJSON.
+func marshalJSON(t *testing.T, v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return decodeJSON(t, data)
+}
+
+// tamper returns the ways s can be changed after it's signed that both
+// packages must reject: other claims with the original signature, and the
+// original claims with a bit of the signature flipped.
+func tamper(t *testing.T, s []byte) map[string][]byte {
+	parts := strings.Split(string(s), ".")
+	assert.Len(t, parts, 3)
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+
+	flipped := append([]byte(nil), sig...)
+	flipped[len(flipped)/2] ^= 1
+
+	return map[string][]byte{
+		"claims":    []byte(parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2]),
+		"signature": []byte(parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(flipped)),
+	}
+}
+
+func TestSignedHereVerifiedThere(t *testing.T) {
+	for _, a := range algorithms(t) {
+		for name, claims := range claimsShapes() {
+			t.Run(a.method.Alg()+"/"+name, func(t *testing.T) {
+				s, err := a.sign(claims)
+				assert.NoError(t, err)
+
+				token, err := a.parse(s)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, "JWT", token.Header["typ"])
+				assert.Equal(t, marshalJSON(t, claims), marshalJSON(t, token.Claims))
+
+				for how, tampered := range tamper(t, s) {
+					_, err := a.parse(tampered)
+					assert.True(t, errors.Is(err, jwt5.ErrTokenSignatureInvalid), "%s: %v", how, err)
+
+					var raw json.RawMessage
+					err = a.verify(tampered, &raw)
+					assert.Equal(t, jwt.ErrBadSignature, err, how)
+				}
+			})
+		}
+	}
+}
+
+func TestSignedThereVerifiedHere(t *testing.T) {
+	for _, a := range algorithms(t) {
+		for name, claims := range claimsShapes() {
+			t.Run(a.method.Alg()+"/"+name, func(t *testing.T) {
+				s, err := jwt5.NewWithClaims(a.method, claims).SignedString(a.signKey)
+				assert.NoError(t, err)
+
+				var raw json.RawMessage
+				if !assert.NoError(t, a.verify([]byte(s), &raw)) {
+					return
+				}
+
+				assert.Equal(t, marshalJSON(t, claims), decodeJSON(t, raw))
+
+				// golang-jwt checks its own work too, so a failure here would be
+				// in golang-jwt, not this package.
+				_, err = a.parse([]byte(s))
+				assert.NoError(t, err)
+
+				for how, tampered := range tamper(t, []byte(s)) {
+					assert.Equal(t, jwt.ErrBadSignature, a.verify(tampered, &raw), how)
+
+					_, err := a.parse(tampered)
+					assert.True(t, errors.Is(err, jwt5.ErrTokenSignatureInvalid), "%s: %v", how, err)
+				}
+			})
+		}
+	}
+}
+
+func TestStructClaimsRoundTrip(t *testing.T) {
+	// Claims signed by golang-jwt decode into the same struct here, and the
+	// other way around.
+	want := claimsShapes()["struct"].(*userClaims)
+
+	for _, a := range algorithms(t) {
+		s, err := jwt5.NewWithClaims(a.method, want).SignedString(a.signKey)
+		assert.NoError(t, err)
+
+		var got userClaims
+		assert.NoError(t, a.verify([]byte(s), &got))
+		assert.Equal(t, *want, got)
+
+		signed, err := a.sign(want)
+		assert.NoError(t, err)
+
+		var parsed userClaims
+		_, err = jwt5.ParseWithClaims(string(signed), &parsed, func(*jwt5.Token) (interface{}, error) {
+			return a.verifyKey, nil
+		}, jwt5.WithValidMethods([]string{a.method.Alg()}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, *want, parsed)
+	}
+}
+
+func TestES256ShortIntegers(t *testing.T) {
+	// ES256 signatures are R and S, each left-padded to 32 bytes. About one
+	// signature in 128 has an R or S that would be shorter without the
+	// padding, so sign until both packages have had to pad, and check the
+	// other accepts the result.
+	var es256 algorithm
+	for _, a := range algorithms(t) {
+		if a.method == jwt5.SigningMethodES256 {
+			es256 = a
+		}
+	}
+
+	isPadded := func(s []byte) bool {
+		sig, err := base64.RawURLEncoding.DecodeString(string(s[bytes.LastIndexByte(s, '.')+1:]))
+		assert.NoError(t, err)
+		assert.Len(t, sig, 64)
+
+		return sig[0] == 0 || sig[32] == 0
+	}
+
+	claims := jwt5.MapClaims{"sub": "jdoe@example.com"}
+
+	var here, there int
+	for i := 0; i < 10000 && (here == 0 || there == 0); i++ {
+		s, err := es256.sign(claims)
+		assert.NoError(t, err)
+
+		if isPadded(s) {
+			here++
+
+			_, err := es256.parse(s)
+			assert.NoError(t, err)
+		}
+
+		signed, err := jwt5.NewWithClaims(es256.method, claims).SignedString(es256.signKey)
+		assert.NoError(t, err)
+
+		if isPadded([]byte(signed)) {
+			there++
+			assert.NoError(t, es256.verify([]byte(signed), nil))
+		}
+	}
+
+	assert.NotZero(t, here)
+	assert.NotZero(t, there)
+}
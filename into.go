@@ -0,0 +1,40 @@
+package jwt
+
+import "fmt"
+
+// Into returns a claims target that decodes the claims of a JWT into each of
+// targets. Pass it as the v parameter of a Verify function, when the claims
+// you need are split across types that you can't combine by embedding, such
+// as StandardClaims and a type from another package:
+//
+//	var std jwt.StandardClaims
+//	var custom otherpkg.Claims
+//	err := jwt.VerifyRS256(pub, token, jwt.Into(&std, &custom))
+//
+// The JWT is verified once, and its claims are decoded into each target in
+// turn. Each of targets must be a non-nil pointer, as v would have to be. If a
+// target implements Validator, its Validate method is called.
+//
+// If decoding into a target fails, the Verify function returns an error that
+// says which target it was, and wraps the error from decoding. The targets
+// after it are left alone.
+func Into(targets ...interface{}) interface{} {
+	return &claimsTargets{targets: targets}
+}
+
+// claimsTargets is the claims target that Into returns.
+type claimsTargets struct {
+	targets []interface{}
+}
+
+// each calls fn on each target, and wraps the first error it returns with the
+// index and type of the target.
+func (c *claimsTargets) each(fn func(v interface{}) error) error {
+	for i, v := range c.targets {
+		if err := fn(v); err != nil {
+			return fmt.Errorf("jwt: claims target %d (%T): %w", i, v, err)
+		}
+	}
+
+	return nil
+}
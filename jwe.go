@@ -0,0 +1,396 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	algDir          = "dir"
+	encA128CBCHS256 = "A128CBC-HS256"
+	encA256GCM      = "A256GCM"
+)
+
+// ErrDecryptionFailed is the error returned by DecryptJWE and DecryptJWEA256GCM
+// if a JWE can't be decrypted with the given key: either it was encrypted with
+// a different key, or it was changed after it was encrypted. It plays the same
+// part for JWEs that ErrBadSignature does for signed JWTs, and like
+// ErrBadSignature, it matches ErrInvalidSignature with errors.Is.
+var ErrDecryptionFailed error = invalidSignatureError("jwt: decryption failed")
+
+// EncryptJWE takes a key and a set of claims, and returns an encrypted JWT
+// containing those claims. Unlike the JWTs that SignHS256 and the other Sign
+// functions produce, which anyone can read, only those who have key can see
+// what claims an encrypted JWT contains.
+//
+// DecryptJWE can decrypt tokens encrypted by EncryptJWE.
+//
+// The returned JWT is a JWE, in the compact serialization of RFC7516: five
+// base64url-encoded parts, separated by periods. Its header has an "alg" of
+// "dir", meaning that key is used to encrypt the claims directly, and an "enc"
+// of "A128CBC-HS256", meaning that they are encrypted with AES-128 in CBC mode
+// and authenticated with HMAC SHA-256. key must be exactly 32 bytes long, and
+// should be randomly generated; EncryptJWE returns an error wrapping
+// ErrInvalidKey otherwise. Do not use the same key for EncryptJWE and for
+// SignHS256.
+//
+// The encryption also authenticates the claims, so there's no need to sign
+// them as well: DecryptJWE rejects any token that wasn't encrypted with key.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is produced, as with
+// SignHS256. See SignOption.
+//
+// https://tools.ietf.org/html/rfc7516
+func EncryptJWE(key []byte, v interface{}, opts ...SignOption) ([]byte, error) {
+	return encryptJWE(a128CBCHS256, key, v, opts)
+}
+
+// DecryptJWE decrypts a JWT encrypted using EncryptJWE. If the JWT is
+// decrypted, DecryptJWE will serialize the claims inside the JWT into v.
+//
+// Only a JWE whose header has an "alg" of "dir" and an "enc" of
+// "A128CBC-HS256" can be decrypted. The token has no say in which algorithms
+// are used to decrypt it.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// decryption succeeds, DecryptJWE will deserialize the claims in the JWT into
+// v. As with VerifyHS256, v may be nil.
+//
+// opts can be used to further configure how the token is checked. See
+// VerifyOption.
+//
+// DecryptJWE will return an error wrapping ErrMalformedToken if the JWT is not
+// a well-formed JWE with five parts. Signed JWTs, which have three, are always
+// rejected as malformed. It will return ErrWrongAlgorithm if the JWT uses any
+// "alg" or "enc" other than the ones above, or is compressed, and
+// ErrDecryptionFailed if it wasn't encrypted with key. DecryptJWE returns an
+// error wrapping ErrInvalidKey if key is not 32 bytes long.
+func DecryptJWE(key, s []byte, v interface{}, opts ...VerifyOption) error {
+	return decryptJWE(a128CBCHS256, key, s, v, opts)
+}
+
+// EncryptJWEA256GCM is like EncryptJWE, except that the claims are encrypted
+// with AES-256 in GCM mode, and the "enc" header parameter is "A256GCM". key
+// must be exactly 32 bytes long.
+//
+// DecryptJWEA256GCM can decrypt tokens encrypted by EncryptJWEA256GCM. Because
+// the token can't choose how it is decrypted, DecryptJWE can't.
+func EncryptJWEA256GCM(key []byte, v interface{}, opts ...SignOption) ([]byte, error) {
+	return encryptJWE(a256GCM, key, v, opts)
+}
+
+// DecryptJWEA256GCM is like DecryptJWE, except that it decrypts JWTs encrypted
+// using EncryptJWEA256GCM, whose "enc" header parameter is "A256GCM".
+func DecryptJWEA256GCM(key, s []byte, v interface{}, opts ...VerifyOption) error {
+	return decryptJWE(a256GCM, key, s, v, opts)
+}
+
+// contentEncryption is one of the algorithms that can be used as the "enc" of
+// a JWE. The algorithms are all AEADs, whose additional authenticated data is
+// the JWE's encoded header.
+type contentEncryption struct {
+	name    string
+	keySize int
+	ivSize  int
+	tagSize int
+	seal    func(key, iv, aad, plaintext []byte) (ciphertext, tag []byte, err error)
+	open    func(key, iv, aad, ciphertext, tag []byte) ([]byte, error)
+}
+
+// a128CBCHS256 is AES_128_CBC_HMAC_SHA_256, as defined by RFC7518.
+//
+// https://tools.ietf.org/html/rfc7518#section-5.2.3
+var a128CBCHS256 = contentEncryption{
+	name:    encA128CBCHS256,
+	keySize: 32,
+	ivSize:  aes.BlockSize,
+	tagSize: 16,
+	seal:    sealCBCHMAC,
+	open:    openCBCHMAC,
+}
+
+// a256GCM is AES GCM with a 256-bit key, as defined by RFC7518.
+//
+// https://tools.ietf.org/html/rfc7518#section-5.3
+var a256GCM = contentEncryption{
+	name:    encA256GCM,
+	keySize: 32,
+	ivSize:  12,
+	tagSize: 16,
+	seal:    sealGCM,
+	open:    openGCM,
+}
+
+// checkKey returns an error wrapping ErrInvalidKey if key is the wrong size
+// for e.
+func (e contentEncryption) checkKey(key []byte) error {
+	if len(key) != e.keySize {
+		return fmt.Errorf("%w: %s requires a %d-byte key, not %d bytes", ErrInvalidKey, e.name, e.keySize, len(key))
+	}
+
+	return nil
+}
+
+// encryptJWE is what EncryptJWE and EncryptJWEA256GCM have in common.
+func encryptJWE(e contentEncryption, key []byte, v interface{}, opts []SignOption) ([]byte, error) {
+	if err := e.checkKey(key); err != nil {
+		return nil, err
+	}
+
+	config := newSignConfig(opts)
+
+	h, err := config.marshalHeaderParams(headerTypeJWT, algDir, map[string]interface{}{"enc": e.name})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := config.payload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, e.ivSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, fmt.Errorf("jwt: generating initialization vector: %w", err)
+	}
+
+	// The header, as it appears in the token, is authenticated along with the
+	// claims, so that it can't be changed without DecryptJWE noticing.
+	encodedHeader := base64.RawURLEncoding.EncodeToString(h)
+	ciphertext, tag, err := e.seal(key, iv, []byte(encodedHeader), claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// With "dir", there's no encrypted key, so the second part is empty.
+	var buf bytes.Buffer
+	buf.WriteString(encodedHeader)
+	buf.WriteString("..")
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(iv))
+	buf.WriteByte('.')
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(ciphertext))
+	buf.WriteByte('.')
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(tag))
+
+	return buf.Bytes(), nil
+}
+
+// jweHeader is the part of a JWE header that decryptJWE checks.
+type jweHeader struct {
+	Algorithm   string           `json:"alg"`
+	Encryption  string           `json:"enc"`
+	Compression *json.RawMessage `json:"zip"`
+}
+
+// decryptJWE is what DecryptJWE and DecryptJWEA256GCM have in common.
+func decryptJWE(e contentEncryption, key, s []byte, v interface{}, opts []VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	if err := e.checkKey(key); err != nil {
+		return err
+	}
+
+	parts, ok := splitJWE(s)
+	if !ok {
+		return ErrMalformedToken
+	}
+
+	encodedHeader := parts[0]
+	header, _, err := decodeHeaderPart(nil, encodedHeader)
+	if err != nil {
+		return malformedToken("header", err)
+	}
+
+	var h jweHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return malformedToken("header", err)
+	}
+
+	// As with the "alg" of a signed JWT, the token doesn't get to pick. Since
+	// "dir" is the only "alg" accepted, there is no way to ask for no
+	// encryption at all.
+	if h.Algorithm != algDir || h.Encryption != e.name || h.Compression != nil {
+		return ErrWrongAlgorithm
+	}
+
+	if len(parts[1]) != 0 {
+		return malformedToken("encrypted key", errors.New("must be empty when \"alg\" is \"dir\""))
+	}
+
+	iv, err := decodeNewPart(parts[2])
+	if err != nil {
+		return malformedToken("initialization vector", err)
+	}
+
+	if len(iv) != e.ivSize {
+		return malformedToken("initialization vector", fmt.Errorf("must be %d bytes, not %d", e.ivSize, len(iv)))
+	}
+
+	ciphertext, err := decodeNewPart(parts[3])
+	if err != nil {
+		return malformedToken("ciphertext", err)
+	}
+
+	tag, err := decodeNewPart(parts[4])
+	if err != nil {
+		return malformedToken("authentication tag", err)
+	}
+
+	if len(tag) != e.tagSize {
+		return ErrDecryptionFailed
+	}
+
+	claims, err := e.open(key, iv, encodedHeader, ciphertext, tag)
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+
+	c := newVerifyConfig(opts)
+	return c.unmarshalClaims(header, claims, v, false)
+}
+
+// splitJWE splits the compact serialization of a JWE into its five parts:
+// the header, the encrypted key, the initialization vector, the ciphertext,
+// and the authentication tag. Like Split, it ignores whitespace around s, and
+// requires each part to be made up only of the base64url alphabet.
+//
+// Only the encrypted key may be empty; whether it has to be depends on the
+// "alg" in the header.
+func splitJWE(s []byte) ([][]byte, bool) {
+	parts := bytes.Split(trimToken(s), []byte{'.'})
+	if len(parts) != 5 {
+		return nil, false
+	}
+
+	for i, part := range parts {
+		if (len(part) == 0 && i != 1) || !isBase64URL(part) {
+			return nil, false
+		}
+	}
+
+	return parts, true
+}
+
+// sealCBCHMAC encrypts plaintext with AES_128_CBC_HMAC_SHA_256. The first half
+// of key is used for HMAC, and the second half for AES.
+func sealCBCHMAC(key, iv, aad, plaintext []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// PKCS #7 padding always adds at least one byte, and at most a block.
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	ciphertext := make([]byte, len(plaintext)+n)
+	copy(ciphertext, plaintext)
+	for i := len(plaintext); i < len(ciphertext); i++ {
+		ciphertext[i] = byte(n)
+	}
+
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	return ciphertext, cbcHMACTag(key[:16], iv, aad, ciphertext), nil
+}
+
+// openCBCHMAC reverses sealCBCHMAC. The tag is checked before anything is
+// decrypted, so a token that wasn't encrypted with key is never decrypted.
+func openCBCHMAC(key, iv, aad, ciphertext, tag []byte) ([]byte, error) {
+	if !hmac.Equal(tag, cbcHMACTag(key[:16], iv, aad, ciphertext)) {
+		return nil, ErrDecryptionFailed
+	}
+
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrDecryptionFailed
+	}
+
+	block, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	n := int(plaintext[len(plaintext)-1])
+	if n == 0 || n > aes.BlockSize {
+		return nil, ErrDecryptionFailed
+	}
+
+	for _, b := range plaintext[len(plaintext)-n:] {
+		if subtle.ConstantTimeByteEq(b, byte(n)) != 1 {
+			return nil, ErrDecryptionFailed
+		}
+	}
+
+	return plaintext[:len(plaintext)-n], nil
+}
+
+// cbcHMACTag returns the authentication tag of AES_128_CBC_HMAC_SHA_256: the
+// first half of the HMAC of the additional authenticated data, the IV, the
+// ciphertext, and the length in bits of the additional authenticated data.
+func cbcHMACTag(macKey, iv, aad, ciphertext []byte) []byte {
+	var al [8]byte
+	binary.BigEndian.PutUint64(al[:], uint64(len(aad))*8)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(aad)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	mac.Write(al[:])
+
+	return mac.Sum(nil)[:16]
+}
+
+// sealGCM encrypts plaintext with AES GCM, and returns the ciphertext and tag
+// separately, as they appear in a JWE.
+func sealGCM(key, iv, aad, plaintext []byte) ([]byte, []byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := aead.Seal(nil, iv, plaintext, aad)
+	return out[:len(plaintext)], out[len(plaintext):], nil
+}
+
+// openGCM reverses sealGCM.
+func openGCM(key, iv, aad, ciphertext, tag []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(sealed, ciphertext...)
+	sealed = append(sealed, tag...)
+
+	return aead.Open(nil, iv, sealed, aad)
+}
+
+// newGCM returns AES GCM, with the standard 12-byte nonce and 16-byte tag.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,253 @@
+package jwt_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// forgeJWE returns a JWE with the given header and claims, encrypted with key
+// using A128CBC-HS256 and a fixed IV, whatever the header says.
+func forgeJWE(header, claims string, key []byte) []byte {
+	h := base64.RawURLEncoding.EncodeToString([]byte(header))
+	iv := bytes.Repeat([]byte{1}, 16)
+
+	ciphertext, tag, err := jwt.SealA128CBCHS256(key, iv, []byte(h), []byte(claims))
+	if err != nil {
+		panic(err)
+	}
+
+	return []byte(h + ".." + base64.RawURLEncoding.EncodeToString(iv) + "." + base64.RawURLEncoding.EncodeToString(ciphertext) + "." + base64.RawURLEncoding.EncodeToString(tag))
+}
+
+func TestJWEContentEncryptionRFC7516(t *testing.T) {
+	// The CEKs, IVs, and outputs of the examples in RFC7516, Appendix A. The
+	// examples encrypt their CEKs with RSA or AES Key Wrap, but the content
+	// encryption is the same as with "dir".
+	testCases := []struct {
+		name       string
+		seal       func(key, iv, aad, plaintext []byte) ([]byte, []byte, error)
+		open       func(key, iv, aad, ciphertext, tag []byte) ([]byte, error)
+		cek        []byte
+		iv         string
+		header     string
+		plaintext  string
+		ciphertext string
+		tag        string
+	}{
+		{
+			name:       "A.1 RSAES-OAEP and AES GCM",
+			seal:       jwt.SealA256GCM,
+			open:       jwt.OpenA256GCM,
+			cek:        []byte{177, 161, 244, 128, 84, 143, 225, 115, 63, 180, 3, 255, 107, 154, 212, 246, 138, 7, 110, 91, 112, 46, 34, 105, 47, 130, 203, 46, 122, 234, 64, 252},
+			iv:         "48V1_ALb6US04U3b",
+			header:     "eyJhbGciOiJSU0EtT0FFUCIsImVuYyI6IkEyNTZHQ00ifQ",
+			plaintext:  "The true sign of intelligence is not knowledge but imagination.",
+			ciphertext: "5eym8TW_c8SuK0ltJ3rpYIzOeDQz7TALvtu6UG9oMo4vpzs9tX_EFShS8iB7j6jiSdiwkIr3ajwQzaBtQD_A",
+			tag:        "XFBoMYUZodetZdvTiFvSkQ",
+		},
+		{
+			name:       "A.2 RSAES-PKCS1-v1_5 and AES_128_CBC_HMAC_SHA_256",
+			seal:       jwt.SealA128CBCHS256,
+			open:       jwt.OpenA128CBCHS256,
+			cek:        []byte{4, 211, 31, 197, 84, 157, 252, 254, 11, 100, 157, 250, 63, 170, 106, 206, 107, 124, 212, 45, 111, 107, 9, 219, 200, 177, 0, 240, 143, 156, 44, 207},
+			iv:         "AxY8DCtDaGlsbGljb3RoZQ",
+			header:     "eyJhbGciOiJSU0ExXzUiLCJlbmMiOiJBMTI4Q0JDLUhTMjU2In0",
+			plaintext:  "Live long and prosper.",
+			ciphertext: "KDlTtXchhZTGufMYmOYGS4HffxPSUrfmqCHXaI9wOGY",
+			tag:        "9hH0vgRfYgPnAHOd8stkvw",
+		},
+		{
+			name:       "A.3 AES Key Wrap and AES_128_CBC_HMAC_SHA_256",
+			seal:       jwt.SealA128CBCHS256,
+			open:       jwt.OpenA128CBCHS256,
+			cek:        []byte{4, 211, 31, 197, 84, 157, 252, 254, 11, 100, 157, 250, 63, 170, 106, 206, 107, 124, 212, 45, 111, 107, 9, 219, 200, 177, 0, 240, 143, 156, 44, 207},
+			iv:         "AxY8DCtDaGlsbGljb3RoZQ",
+			header:     "eyJhbGciOiJBMTI4S1ciLCJlbmMiOiJBMTI4Q0JDLUhTMjU2In0",
+			plaintext:  "Live long and prosper.",
+			ciphertext: "KDlTtXchhZTGufMYmOYGS4HffxPSUrfmqCHXaI9wOGY",
+			tag:        "U0m_YmjN04DJvceFICbCVQ",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			iv, err := base64.RawURLEncoding.DecodeString(tt.iv)
+			assert.NoError(t, err)
+
+			ciphertext, tag, err := tt.seal(tt.cek, iv, []byte(tt.header), []byte(tt.plaintext))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ciphertext, base64.RawURLEncoding.EncodeToString(ciphertext))
+			assert.Equal(t, tt.tag, base64.RawURLEncoding.EncodeToString(tag))
+
+			plaintext, err := tt.open(tt.cek, iv, []byte(tt.header), ciphertext, tag)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.plaintext, string(plaintext))
+
+			// The header is authenticated along with the ciphertext.
+			_, err = tt.open(tt.cek, iv, []byte(tt.header+"x"), ciphertext, tag)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEncryptJWE(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	testCases := []struct {
+		name    string
+		encrypt func(key []byte, v interface{}, opts ...jwt.SignOption) ([]byte, error)
+		decrypt func(key, s []byte, v interface{}, opts ...jwt.VerifyOption) error
+		header  string
+	}{
+		{"A128CBC-HS256", jwt.EncryptJWE, jwt.DecryptJWE, `{"typ":"JWT","alg":"dir","enc":"A128CBC-HS256"}`},
+		{"A256GCM", jwt.EncryptJWEA256GCM, jwt.DecryptJWEA256GCM, `{"typ":"JWT","alg":"dir","enc":"A256GCM"}`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.encrypt(key, jwt.StandardClaims{Subject: "jdoe@example.com"})
+			assert.NoError(t, err)
+
+			parts := strings.Split(string(token), ".")
+			assert.Len(t, parts, 5)
+			assert.Equal(t, "", parts[1])
+
+			header, err := base64.RawURLEncoding.DecodeString(parts[0])
+			assert.NoError(t, err)
+			assert.Equal(t, tt.header, string(header))
+
+			// The claims can't be read without the key.
+			assert.NotContains(t, string(token), base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"jdoe@example.com"}`)))
+			_, err = jwt.PeekHeader(token)
+			assert.True(t, errors.Is(err, jwt.ErrMalformedToken))
+
+			var claims jwt.StandardClaims
+			assert.NoError(t, tt.decrypt(key, token, &claims))
+			assert.Equal(t, "jdoe@example.com", claims.Subject)
+
+			assert.NoError(t, tt.decrypt(key, []byte(" "+string(token)+"\n"), nil))
+
+			// Every token gets a new IV.
+			again, err := tt.encrypt(key, jwt.StandardClaims{Subject: "jdoe@example.com"})
+			assert.NoError(t, err)
+			assert.NotEqual(t, string(token), string(again))
+
+			// The wrong key can't decrypt it.
+			other := []byte("fedcba9876543210fedcba9876543210")
+			err = tt.decrypt(other, token, &claims)
+			assert.Equal(t, jwt.ErrDecryptionFailed, err)
+			assert.True(t, errors.Is(err, jwt.ErrInvalidSignature))
+
+			// Changing any part makes it undecryptable.
+			for i := range parts {
+				if i == 1 {
+					continue
+				}
+
+				changed := append([]string(nil), parts...)
+				b, err := base64.RawURLEncoding.DecodeString(changed[i])
+				assert.NoError(t, err)
+				b[0] ^= 1
+				changed[i] = base64.RawURLEncoding.EncodeToString(b)
+
+				err = tt.decrypt(key, []byte(strings.Join(changed, ".")), &claims)
+				assert.True(t, errors.Is(err, jwt.ErrInvalidSignature), i)
+			}
+
+			// A truncated tag is rejected, not compared against a truncated MAC.
+			tag, err := base64.RawURLEncoding.DecodeString(parts[4])
+			assert.NoError(t, err)
+			truncated := strings.Join(parts[:4], ".") + "." + base64.RawURLEncoding.EncodeToString(tag[:8])
+			assert.Equal(t, jwt.ErrDecryptionFailed, tt.decrypt(key, []byte(truncated), &claims))
+
+			// Options apply as they do to the Sign and Verify functions.
+			token, err = tt.encrypt(key, map[string]string{"iss": "me"}, jwt.WithKeyID("k"), jwt.WithIssuedAtNow())
+			assert.NoError(t, err)
+
+			var h jwt.Header
+			var out map[string]interface{}
+			assert.NoError(t, tt.decrypt(key, token, &out, jwt.WithExpectedIssuer("me"), jwt.WithHeader(&h)))
+			assert.Equal(t, "k", h.KeyID)
+			assert.Equal(t, "dir", h.Algorithm)
+			assert.Equal(t, map[string]interface{}{"enc": tt.name}, h.Extra)
+			assert.Contains(t, out, "iat")
+
+			err = tt.decrypt(key, token, &out, jwt.WithExpectedIssuer("you"))
+			assert.True(t, errors.Is(err, jwt.ErrUnexpectedIssuer))
+
+			_, err = tt.encrypt(key, nil, jwt.WithHeaderParams(map[string]interface{}{"ENC": "none"}))
+			assert.True(t, errors.Is(err, jwt.ErrReservedHeaderParam))
+
+			// Keys must be 32 bytes long.
+			_, err = tt.encrypt(key[:16], claims)
+			assert.True(t, errors.Is(err, jwt.ErrInvalidKey))
+
+			err = tt.decrypt(key[:16], token, &claims)
+			assert.True(t, errors.Is(err, jwt.ErrInvalidKey))
+		})
+	}
+}
+
+func TestDecryptJWEAlgorithms(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	cbc, err := jwt.EncryptJWE(key, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+
+	gcm, err := jwt.EncryptJWEA256GCM(key, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+
+	var claims jwt.StandardClaims
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.DecryptJWE(key, gcm, &claims))
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.DecryptJWEA256GCM(key, cbc, &claims))
+
+	// The token can't ask for anything other than "dir" and the expected "enc",
+	// even if it's otherwise encrypted correctly.
+	for _, header := range []string{
+		`{"alg":"none"}`,
+		`{"alg":"none","enc":"A128CBC-HS256"}`,
+		`{"alg":"dir"}`,
+		`{"alg":"dir","enc":"none"}`,
+		`{"alg":"dir","enc":"a128cbc-hs256"}`,
+		`{"alg":"A128KW","enc":"A128CBC-HS256"}`,
+		`{"alg":"HS256","enc":"A128CBC-HS256"}`,
+		`{"alg":"dir","enc":"A128CBC-HS256","zip":"DEF"}`,
+	} {
+		err := jwt.DecryptJWE(key, forgeJWE(header, `{"sub":"jdoe@example.com"}`, key), &claims)
+		assert.Equal(t, jwt.ErrWrongAlgorithm, err, header)
+	}
+
+	assert.NoError(t, jwt.DecryptJWE(key, forgeJWE(`{"enc":"A128CBC-HS256","alg":"dir"}`, `{"sub":"jdoe@example.com"}`, key), &claims))
+	assert.Equal(t, "jdoe@example.com", claims.Subject)
+
+	// Signed JWTs, even ones made with the same key, are not JWEs, and JWEs are
+	// not signed JWTs.
+	signed, err := jwt.SignHS256(key, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ErrMalformedToken, jwt.DecryptJWE(key, signed, &claims))
+	assert.Equal(t, jwt.ErrMalformedToken, jwt.VerifyHS256(key, cbc, &claims))
+
+	parts := strings.Split(string(cbc), ".")
+	for _, token := range []string{
+		strings.Join(parts[:4], "."),
+		string(cbc) + ".",
+		strings.Join([]string{parts[0], "AAAA", parts[2], parts[3], parts[4]}, "."),
+		strings.Join([]string{parts[0], "", parts[2][:20], parts[3], parts[4]}, "."),
+		strings.Join([]string{parts[0], "", parts[2], "", parts[4]}, "."),
+		strings.Join([]string{parts[0], "", parts[2], parts[3] + "=", parts[4]}, "."),
+		strings.Join([]string{"bm90IGpzb24", "", parts[2], parts[3], parts[4]}, "."),
+	} {
+		err := jwt.DecryptJWE(key, []byte(token), &claims)
+		assert.True(t, errors.Is(err, jwt.ErrMalformedToken), token)
+	}
+
+	// The claims must still be JSON once decrypted.
+	err = jwt.DecryptJWE(key, forgeJWE(`{"alg":"dir","enc":"A128CBC-HS256"}`, `not json`, key), &claims)
+	assert.True(t, errors.Is(err, jwt.ErrMalformedToken))
+}
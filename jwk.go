@@ -0,0 +1,413 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrInvalidJWK is the error returned by ParseJWK when it is given a JWK that
+// is malformed. The returned error wraps ErrInvalidJWK, and says what is wrong.
+var ErrInvalidJWK = errors.New("jwt: invalid JWK")
+
+// ErrUnsupportedKeyType is the error returned by ParseJWK when it is given a
+// JWK whose "kty", or whose "crv", this package does not support. The returned
+// error wraps ErrUnsupportedKeyType, and names the unsupported value.
+var ErrUnsupportedKeyType = errors.New("jwt: unsupported JWK key type")
+
+// ParseJWK parses a JSON Web Key, and returns the key it represents. The type
+// of the returned key depends on the JWK's "kty" and "crv", and on whether the
+// JWK has private key parameters:
+//
+//	kty  crv             public key         private key
+//	---  ---             ----------         -----------
+//	RSA                  *rsa.PublicKey     *rsa.PrivateKey
+//	EC   P-256, P-384,   *ecdsa.PublicKey   *ecdsa.PrivateKey
+//	     or P-521
+//	OKP  Ed25519         ed25519.PublicKey  ed25519.PrivateKey
+//	oct                  []byte             []byte
+//
+// These are the types that the Sign and Verify functions in this package take.
+// For instance, the key from an "oct" JWK can be passed to VerifyHS256, and the
+// key from an RSA public JWK can be passed to VerifyRS256.
+//
+// ParseJWK checks that the key is well-formed. In particular, EC coordinates
+// must be exactly as long as the curve requires and must be a point on the
+// curve, and private keys must match their public keys. If they aren't,
+// ParseJWK returns an error wrapping ErrInvalidJWK. If the "kty" or "crv" is
+// not one of those listed above, ParseJWK returns an error wrapping
+// ErrUnsupportedKeyType.
+//
+// ParseJWK does not look at "alg", "use", "key_ops", or "kid". For RSA private
+// keys, "dp", "dq", and "qi" are recomputed from the other parameters rather
+// than being read.
+//
+// https://tools.ietf.org/html/rfc7517
+func ParseJWK(data []byte) (interface{}, error) {
+	var j jwk
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+
+	return j.key()
+}
+
+// MarshalJWK returns the JSON Web Key representation of the public key key.
+// key may be an *rsa.PublicKey, an *ecdsa.PublicKey on P-256, P-384, or P-521,
+// or an ed25519.PublicKey. Only the public key is ever marshalled, so key may
+// also be the corresponding private key type, in which case MarshalJWK returns
+// its public key.
+//
+// EC keys include "alg" if this package supports an algorithm for their
+// curve, and Ed25519 keys include "alg" of "EdDSA". RSA keys do not include
+// "alg", because the same key may be used with RS256, RS384, or PS256.
+//
+// MarshalJWK returns an error wrapping ErrUnsupportedKeyType for any other
+// type of key. ParseJWK can parse the JWKs that MarshalJWK returns.
+//
+// https://tools.ietf.org/html/rfc7517
+func MarshalJWK(key interface{}) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		key = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key = &k.PublicKey
+	case ed25519.PrivateKey:
+		key = k.Public()
+	case []byte:
+		return nil, fmt.Errorf("%w: symmetric keys are secret; use MarshalPrivateJWK", ErrUnsupportedKeyType)
+	}
+
+	j, err := newJWK(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(j)
+}
+
+// MarshalPrivateJWK is like MarshalJWK, except that it marshals private keys,
+// including all of their private parameters. key may be an *rsa.PrivateKey,
+// an *ecdsa.PrivateKey, an ed25519.PrivateKey, or a []byte secret for use with
+// HS256 or HS512.
+//
+// The returned JWK contains secret key material. Do not publish it. To publish
+// a public key, use MarshalJWK.
+//
+// MarshalPrivateJWK returns an error wrapping ErrUnsupportedKeyType for any
+// other type of key, including public keys.
+func MarshalPrivateJWK(key interface{}) ([]byte, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey, []byte:
+	default:
+		return nil, fmt.Errorf("%w: %T is not a private key", ErrUnsupportedKeyType, key)
+	}
+
+	j, err := newJWK(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(j)
+}
+
+// newJWK returns the JWK representation of key, which may be of any of the
+// types that ParseJWK returns.
+func newJWK(key interface{}) (*jwk, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return &jwk{Kty: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}, nil
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, fmt.Errorf("%w: RSA keys with more than two primes", ErrUnsupportedKeyType)
+		}
+
+		// These are the same values as Precompute computes, but calling
+		// Precompute would modify k.
+		p, q := k.Primes[0], k.Primes[1]
+		one := big.NewInt(1)
+		dp := new(big.Int).Mod(k.D, new(big.Int).Sub(p, one))
+		dq := new(big.Int).Mod(k.D, new(big.Int).Sub(q, one))
+		qi := new(big.Int).ModInverse(q, p)
+
+		j, _ := newJWK(&k.PublicKey)
+		j.D = b64(k.D.Bytes())
+		j.P = b64(p.Bytes())
+		j.Q = b64(q.Bytes())
+		j.DP = b64(dp.Bytes())
+		j.DQ = b64(dq.Bytes())
+		j.QI = b64(qi.Bytes())
+		return j, nil
+	case *ecdsa.PublicKey:
+		var crv, alg string
+		switch k.Curve {
+		case elliptic.P256():
+			crv, alg = "P-256", algES256
+		case elliptic.P384():
+			crv = "P-384"
+		case elliptic.P521():
+			crv, alg = "P-521", algES512
+		default:
+			return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKeyType, k.Curve.Params().Name)
+		}
+
+		size := ecdsaKeySize(k.Curve)
+		return &jwk{Kty: "EC", Alg: alg, Crv: crv, X: b64(fixedBytes(k.X, size)), Y: b64(fixedBytes(k.Y, size))}, nil
+	case *ecdsa.PrivateKey:
+		j, err := newJWK(&k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		j.D = b64(fixedBytes(k.D, ecdsaKeySize(k.Curve)))
+		return j, nil
+	case ed25519.PublicKey:
+		return &jwk{Kty: "OKP", Alg: algEdDSA, Crv: "Ed25519", X: b64(k)}, nil
+	case ed25519.PrivateKey:
+		j, _ := newJWK(k.Public())
+		j.D = b64(k.Seed())
+		return j, nil
+	case []byte:
+		return &jwk{Kty: "oct", K: b64(k)}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, key)
+}
+
+// b64 returns the unpadded base64url encoding of b.
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// fixedBytes returns the big-endian representation of i, left-padded with
+// zeros to size bytes.
+func fixedBytes(i *big.Int, size int) []byte {
+	b := i.Bytes()
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// jwk is the JSON representation of a JSON Web Key. Only the members this
+// package uses are present.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+
+	// RSA
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+
+	// EC and OKP
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
+
+	// RSA, EC, and OKP
+	D string `json:"d,omitempty"`
+
+	// oct
+	K string `json:"k,omitempty"`
+}
+
+// key returns the key j represents. See ParseJWK.
+func (j *jwk) key() (interface{}, error) {
+	switch j.Kty {
+	case "RSA":
+		return j.rsaKey()
+	case "EC":
+		return j.ecKey()
+	case "OKP":
+		return j.okpKey()
+	case "oct":
+		k, err := j.decode("k", j.K)
+		if err != nil {
+			return nil, err
+		}
+
+		return k, nil
+	case "":
+		return nil, fmt.Errorf("%w: missing \"kty\"", ErrInvalidJWK)
+	}
+
+	return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKeyType, j.Kty)
+}
+
+func (j *jwk) rsaKey() (interface{}, error) {
+	n, err := j.decodeInt("n", j.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := j.decodeInt("e", j.E)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 || e.Bit(0) == 0 {
+		return nil, fmt.Errorf("%w: \"e\" is not a valid RSA exponent", ErrInvalidJWK)
+	}
+
+	pub := rsa.PublicKey{N: n, E: int(e.Int64())}
+	if j.D == "" {
+		return &pub, nil
+	}
+
+	d, err := j.decodeInt("d", j.D)
+	if err != nil {
+		return nil, err
+	}
+
+	// Go's RSA implementation needs the primes, so JWKs with "d" but without
+	// "p" and "q" are rejected.
+	p, err := j.decodeInt("p", j.P)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := j.decodeInt("q", j.Q)
+	if err != nil {
+		return nil, err
+	}
+
+	priv := &rsa.PrivateKey{PublicKey: pub, D: d, Primes: []*big.Int{p, q}}
+	if err := priv.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+
+	priv.Precompute()
+	return priv, nil
+}
+
+func (j *jwk) ecKey() (interface{}, error) {
+	var curve elliptic.Curve
+	switch j.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	case "":
+		return nil, fmt.Errorf("%w: missing \"crv\"", ErrInvalidJWK)
+	default:
+		return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKeyType, j.Crv)
+	}
+
+	size := ecdsaKeySize(curve)
+	x, err := j.decodeFixed("x", j.X, size)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := j.decodeFixed("y", j.Y, size)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("%w: point is not on the curve", ErrInvalidJWK)
+	}
+
+	if j.D == "" {
+		return &pub, nil
+	}
+
+	d, err := j.decodeFixed("d", j.D, size)
+	if err != nil {
+		return nil, err
+	}
+
+	priv := &ecdsa.PrivateKey{PublicKey: pub, D: new(big.Int).SetBytes(d)}
+	if priv.D.Sign() == 0 || priv.D.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("%w: \"d\" is out of range", ErrInvalidJWK)
+	}
+
+	if x, y := curve.ScalarBaseMult(d); x.Cmp(pub.X) != 0 || y.Cmp(pub.Y) != 0 {
+		return nil, fmt.Errorf("%w: private key does not match public key", ErrInvalidJWK)
+	}
+
+	return priv, nil
+}
+
+func (j *jwk) okpKey() (interface{}, error) {
+	switch j.Crv {
+	case "Ed25519":
+	case "":
+		return nil, fmt.Errorf("%w: missing \"crv\"", ErrInvalidJWK)
+	default:
+		return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKeyType, j.Crv)
+	}
+
+	x, err := j.decodeFixed("x", j.X, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.D == "" {
+		return ed25519.PublicKey(x), nil
+	}
+
+	d, err := j.decodeFixed("d", j.D, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	priv := ed25519.NewKeyFromSeed(d)
+	if subtle.ConstantTimeCompare(priv.Public().(ed25519.PublicKey), x) != 1 {
+		return nil, fmt.Errorf("%w: private key does not match public key", ErrInvalidJWK)
+	}
+
+	return priv, nil
+}
+
+// decode decodes the base64url-encoded member name, whose value is s.
+func (j *jwk) decode(name, s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%w: missing %q", ErrInvalidJWK, name)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not valid base64url", ErrInvalidJWK, name)
+	}
+
+	return b, nil
+}
+
+// decodeFixed is like decode, but requires the decoded value to be exactly
+// size bytes long.
+func (j *jwk) decodeFixed(name, s string, size int) ([]byte, error) {
+	b, err := j.decode(name, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) != size {
+		return nil, fmt.Errorf("%w: %q must be %d bytes, not %d", ErrInvalidJWK, name, size, len(b))
+	}
+
+	return b, nil
+}
+
+// decodeInt is like decode, but decodes a big-endian unsigned integer.
+func (j *jwk) decodeInt(name, s string) (*big.Int, error) {
+	b, err := j.decode(name, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
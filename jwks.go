@@ -0,0 +1,121 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateKeyID is the error returned when unmarshalling a JWKSet in which
+// two keys have the same "kid". The returned error wraps ErrDuplicateKeyID,
+// and names the duplicated key ID.
+var ErrDuplicateKeyID = errors.New("jwt: duplicate JWK key ID")
+
+// JWK is a key in a JWKSet.
+type JWK struct {
+	// KeyID is the key's "kid". It is empty if the key has no "kid".
+	KeyID string
+
+	// Algorithm is the key's "alg". It is empty if the key has no "alg".
+	//
+	// Algorithm is whatever the JWK Set says it is. If you use it to choose a
+	// Verify function, make sure it is an algorithm you expect.
+	Algorithm string
+
+	// Key is the key itself. It has one of the types that ParseJWK returns.
+	Key interface{}
+}
+
+// JWKSet is a JSON Web Key Set, the document that many identity providers
+// publish their public keys in. Use json.Unmarshal to parse a JWKSet:
+//
+//	{"keys":[{"kty":"RSA","kid":"...","n":"...","e":"AQAB"}, ...]}
+//
+// Each key is parsed the same way as ParseJWK would parse it. Keys whose "kty"
+// or "crv" this package does not support are skipped, so that a provider
+// adding a new kind of key does not break verification with the keys it
+// already has. Any other invalid key is an error wrapping ErrInvalidJWK, and
+// two keys with the same "kid" is an error wrapping ErrDuplicateKeyID.
+//
+// PeekKeyID and Key together let you pick which key to verify a token with. See
+// the example for JWKSet.
+//
+// https://tools.ietf.org/html/rfc7517#section-5
+type JWKSet struct {
+	keys []JWK
+}
+
+// Key returns the key in s whose "kid" is kid, and the "alg" it declares, if
+// any. If s has no key with that ID, ok is false.
+//
+// Keys without a "kid" are never returned by Key. Use Keys to get them.
+func (s *JWKSet) Key(kid string) (key interface{}, alg string, ok bool) {
+	if kid == "" {
+		return nil, "", false
+	}
+
+	for _, k := range s.keys {
+		if k.KeyID == kid {
+			return k.Key, k.Algorithm, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// Keys returns all of the keys in s, in the order they appeared in the JWK
+// Set. Keys that were skipped because their type isn't supported are not
+// included.
+func (s *JWKSet) Keys() []JWK {
+	keys := make([]JWK, len(s.keys))
+	copy(keys, s.keys)
+	return keys
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *JWKSet) UnmarshalJSON(data []byte) error {
+	var set struct {
+		Keys *[]json.RawMessage `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+
+	if set.Keys == nil {
+		return fmt.Errorf("%w: missing \"keys\"", ErrInvalidJWK)
+	}
+
+	var keys []JWK
+	kids := map[string]struct{}{}
+	for _, raw := range *set.Keys {
+		var j jwk
+		if err := json.Unmarshal(raw, &j); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+		}
+
+		// Check for duplicates even among keys we skip; a "kid" that refers to
+		// two different keys is ambiguous no matter which of them we support.
+		if j.Kid != "" {
+			if _, ok := kids[j.Kid]; ok {
+				return fmt.Errorf("%w: %q", ErrDuplicateKeyID, j.Kid)
+			}
+
+			kids[j.Kid] = struct{}{}
+		}
+
+		key, err := j.key()
+		if errors.Is(err, ErrUnsupportedKeyType) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, JWK{KeyID: j.Kid, Algorithm: j.Alg, Key: key})
+	}
+
+	s.keys = keys
+	return nil
+}
@@ -0,0 +1,270 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKeyID is the error returned when verifying a JWT by its key ID, if
+// the JWT's "kid" does not name any known key. For a JWKSFetcher, this means
+// the key isn't in the JWK Set even after refreshing it. The returned error
+// wraps ErrUnknownKeyID, and names the unknown key ID.
+var ErrUnknownKeyID = errors.New("jwt: unknown key ID")
+
+// jwksMissCooldown is how long a JWKSFetcher waits after fetching its JWK Set
+// before a token with an unknown "kid" can make it fetch the set again.
+const jwksMissCooldown = 30 * time.Second
+
+// jwksMaxSize is the largest JWK Set document a JWKSFetcher will read.
+const jwksMaxSize = 1 << 20
+
+// JWKSFetcher verifies JWTs against a JWK Set published at a URL, such as the
+// "jwks_uri" of an OpenID Connect provider. It fetches the JWK Set the first
+// time it needs it, and caches it from then on.
+//
+// A JWKSFetcher keeps its cached JWK Set up to date in two ways. It refreshes
+// the set on a fixed interval in the background, and it refreshes the set when
+// it sees a token whose "kid" is not in the cache, which is what happens just
+// after a provider rotates in a new key. To keep a flood of tokens with made-up
+// key IDs from turning into a flood of requests, a refresh caused by an unknown
+// "kid" happens at most once every 30 seconds, and concurrent refreshes share
+// a single request.
+//
+// If a refresh fails, the JWKSFetcher keeps using the JWK Set it already has.
+// Only if it has never fetched the JWK Set successfully do the Verify methods
+// return the error from fetching it.
+//
+// A JWKSFetcher is safe for concurrent use. Construct one using
+// NewJWKSFetcher.
+type JWKSFetcher struct {
+	ctx      context.Context
+	url      string
+	client   *http.Client
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	set      *JWKSet
+	err      error     // the error from the last fetch, if it failed
+	fetched  time.Time // when the last fetch finished, successfully or not
+	inflight chan struct{}
+}
+
+// NewJWKSFetcher returns a JWKSFetcher for the JWK Set at url.
+//
+// The JWK Set is fetched using client. If client is nil, http.DefaultClient is
+// used instead. Because requests to url are made while verifying tokens,
+// client should have a timeout.
+//
+// If refresh is positive, the JWKSFetcher refreshes the JWK Set every refresh
+// in the background, until ctx is done. All requests to url are made using
+// ctx, so once ctx is done the JWKSFetcher can only use the JWK Set it already
+// has.
+func NewJWKSFetcher(ctx context.Context, url string, client *http.Client, refresh time.Duration) *JWKSFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	f := &JWKSFetcher{ctx: ctx, url: url, client: client, cooldown: jwksMissCooldown}
+	if refresh > 0 {
+		go f.refreshEvery(refresh)
+	}
+
+	return f
+}
+
+// VerifyRS256 verifies a JWT using the RSA public key in the JWK Set whose
+// "kid" is the same as the token's. If the JWT is verified, VerifyRS256 will
+// serialize the claims inside the JWT into v. See VerifyRS256.
+//
+// VerifyRS256 returns ErrMissingKeyID if the token has no "kid", and an error
+// wrapping ErrUnknownKeyID if the token's "kid" isn't in the JWK Set. It
+// returns an error wrapping ErrInvalidKey if the key with that "kid" isn't an
+// RSA key, or declares an "alg" other than RS256.
+func (f *JWKSFetcher) VerifyRS256(s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	key, err := f.verificationKey(algRS256, s)
+	if err != nil {
+		return err
+	}
+
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: key is not an RSA public key", ErrInvalidKey)
+	}
+
+	return VerifyRS256(pub, s, v, opts...)
+}
+
+// VerifyES256 verifies a JWT using the ECDSA public key in the JWK Set whose
+// "kid" is the same as the token's. If the JWT is verified, VerifyES256 will
+// serialize the claims inside the JWT into v. See VerifyES256.
+//
+// VerifyES256 returns ErrMissingKeyID if the token has no "kid", and an error
+// wrapping ErrUnknownKeyID if the token's "kid" isn't in the JWK Set. It
+// returns an error wrapping ErrInvalidKey if the key with that "kid" isn't an
+// ECDSA key, or declares an "alg" other than ES256.
+func (f *JWKSFetcher) VerifyES256(s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	key, err := f.verificationKey(algES256, s)
+	if err != nil {
+		return err
+	}
+
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: key is not an ECDSA public key", ErrInvalidKey)
+	}
+
+	return VerifyES256(pub, s, v, opts...)
+}
+
+// verificationKey returns the key in the JWK Set that the token s names, so
+// long as that key doesn't declare an algorithm other than alg.
+func (f *JWKSFetcher) verificationKey(alg string, s []byte) (interface{}, error) {
+	kid, err := PeekKeyID(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if kid == "" {
+		return nil, ErrMissingKeyID
+	}
+
+	key, keyAlg, err := f.key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyAlg != "" && keyAlg != alg {
+		return nil, fmt.Errorf("%w: key is for %s, not %s", ErrInvalidKey, keyAlg, alg)
+	}
+
+	return key, nil
+}
+
+// key returns the key with the given ID, refreshing the JWK Set if it doesn't
+// have one.
+func (f *JWKSFetcher) key(kid string) (interface{}, string, error) {
+	f.mu.Lock()
+	set := f.set
+	f.mu.Unlock()
+
+	if set != nil {
+		if key, alg, ok := set.Key(kid); ok {
+			return key, alg, nil
+		}
+	}
+
+	f.refresh(false)
+
+	f.mu.Lock()
+	set, err := f.set, f.err
+	f.mu.Unlock()
+
+	if set == nil {
+		return nil, "", err
+	}
+
+	if key, alg, ok := set.Key(kid); ok {
+		return key, alg, nil
+	}
+
+	return nil, "", fmt.Errorf("%w: %q", ErrUnknownKeyID, kid)
+}
+
+// refreshEvery refreshes the JWK Set every d, until f.ctx is done.
+func (f *JWKSFetcher) refreshEvery(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-t.C:
+			f.refresh(true)
+		}
+	}
+}
+
+// refresh fetches the JWK Set. If a fetch is already in progress, refresh
+// waits for it instead of starting another. Unless force is true, refresh does
+// nothing if the last fetch finished less than f.cooldown ago.
+func (f *JWKSFetcher) refresh(force bool) {
+	f.mu.Lock()
+	if done := f.inflight; done != nil {
+		f.mu.Unlock()
+		<-done
+		return
+	}
+
+	if !force && !f.fetched.IsZero() && time.Since(f.fetched) < f.cooldown {
+		f.mu.Unlock()
+		return
+	}
+
+	done := make(chan struct{})
+	f.inflight = done
+	f.mu.Unlock()
+
+	set, err := f.fetch()
+
+	f.mu.Lock()
+	if err == nil {
+		f.set = set
+	}
+
+	f.err = err
+	f.fetched = time.Now()
+	f.inflight = nil
+	f.mu.Unlock()
+	close(done)
+}
+
+// fetch makes a request for the JWK Set, and parses the response.
+func (f *JWKSFetcher) fetch() (*JWKSet, error) {
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetching JWK Set: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetching JWK Set: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: fetching JWK Set: unexpected status %s", res.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, jwksMaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetching JWK Set: %w", err)
+	}
+
+	var set JWKSet
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
@@ -0,0 +1,239 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// jwksServer serves whatever JWK Set document it was most recently given, and
+// counts how many requests it has received.
+type jwksServer struct {
+	mu       sync.Mutex
+	body     string
+	status   int
+	requests int32
+	block    chan struct{}
+}
+
+func (s *jwksServer) set(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.body = status, body
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&s.requests, 1)
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.WriteHeader(s.status)
+	fmt.Fprint(w, s.body)
+}
+
+func jwkSetOf(t *testing.T, keys map[string]interface{}) string {
+	var out []byte
+	for kid, key := range keys {
+		data, err := jwt.MarshalJWK(key)
+		assert.NoError(t, err)
+
+		if len(out) > 0 {
+			out = append(out, ',')
+		}
+
+		out = append(out, fmt.Sprintf(`{"kid":%q,%s`, kid, data[1:])...)
+	}
+
+	return `{"keys":[` + string(out) + `]}`
+}
+
+func TestJWKSFetcher(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	newEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+	rsaToken, err := jwt.SignRS256(rsaKey, claims, jwt.WithKeyID("rsa"))
+	assert.NoError(t, err)
+
+	ecToken, err := jwt.SignES256(ecKey, claims, jwt.WithKeyID("ec"))
+	assert.NoError(t, err)
+
+	newToken, err := jwt.SignES256(newEC, claims, jwt.WithKeyID("new"))
+	assert.NoError(t, err)
+
+	setup := func() (*jwksServer, *jwt.JWKSFetcher, func()) {
+		s := &jwksServer{}
+		s.set(http.StatusOK, jwkSetOf(t, map[string]interface{}{"rsa": rsaKey, "ec": ecKey}))
+
+		server := httptest.NewServer(s)
+		return s, jwt.NewJWKSFetcher(context.Background(), server.URL, server.Client(), 0), server.Close
+	}
+
+	t.Run("verify and cache", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+
+		var out jwt.StandardClaims
+		assert.NoError(t, f.VerifyRS256(rsaToken, &out))
+		assert.Equal(t, claims, out)
+
+		out = jwt.StandardClaims{}
+		assert.NoError(t, f.VerifyES256(ecToken, &out))
+		assert.Equal(t, claims, out)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&s.requests))
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, f, done := setup()
+		defer done()
+
+		var out jwt.StandardClaims
+		assert.True(t, errors.Is(f.VerifyES256(rsaToken, &out), jwt.ErrInvalidKey))
+		assert.True(t, errors.Is(f.VerifyRS256(ecToken, &out), jwt.ErrInvalidKey))
+
+		noKid, err := jwt.SignES256(ecKey, claims)
+		assert.NoError(t, err)
+		assert.Equal(t, jwt.ErrMissingKeyID, f.VerifyES256(noKid, &out))
+
+		assert.True(t, errors.Is(f.VerifyES256([]byte("a.b.c"), &out), jwt.ErrMalformedToken))
+	})
+
+	t.Run("declared alg must match", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+		s.set(http.StatusOK, strings.Replace(jwkSetOf(t, map[string]interface{}{"ec": ecKey}), `"alg":"ES256"`, `"alg":"ES512"`, 1))
+
+		var out jwt.StandardClaims
+		assert.True(t, errors.Is(f.VerifyES256(ecToken, &out), jwt.ErrInvalidKey))
+	})
+
+	t.Run("unknown kid refreshes", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+		jwt.SetJWKSFetcherCooldown(f, 0)
+
+		var out jwt.StandardClaims
+		assert.True(t, errors.Is(f.VerifyES256(newToken, &out), jwt.ErrUnknownKeyID))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&s.requests))
+
+		s.set(http.StatusOK, jwkSetOf(t, map[string]interface{}{"ec": ecKey, "new": newEC}))
+		assert.NoError(t, f.VerifyES256(newToken, &out))
+		assert.NoError(t, f.VerifyES256(ecToken, &out))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&s.requests))
+	})
+
+	t.Run("unknown kid refreshes at most once per cooldown", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+
+		var out jwt.StandardClaims
+		for i := 0; i < 10; i++ {
+			assert.True(t, errors.Is(f.VerifyES256(newToken, &out), jwt.ErrUnknownKeyID))
+		}
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&s.requests))
+	})
+
+	t.Run("concurrent misses share a request", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+		s.block = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				var out jwt.StandardClaims
+				assert.NoError(t, f.VerifyES256(ecToken, &out))
+			}()
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		close(s.block)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&s.requests))
+	})
+
+	t.Run("errors serve stale keys", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+		jwt.SetJWKSFetcherCooldown(f, 0)
+
+		var out jwt.StandardClaims
+		assert.NoError(t, f.VerifyES256(ecToken, &out))
+
+		s.set(http.StatusInternalServerError, "")
+		assert.True(t, errors.Is(f.VerifyES256(newToken, &out), jwt.ErrUnknownKeyID))
+		assert.NoError(t, f.VerifyES256(ecToken, &out))
+
+		s.set(http.StatusOK, "not json")
+		assert.True(t, errors.Is(f.VerifyES256(newToken, &out), jwt.ErrUnknownKeyID))
+		assert.NoError(t, f.VerifyES256(ecToken, &out))
+	})
+
+	t.Run("errors without keys", func(t *testing.T) {
+		s, f, done := setup()
+		defer done()
+		s.set(http.StatusInternalServerError, "")
+
+		var out jwt.StandardClaims
+		err := f.VerifyES256(ecToken, &out)
+		assert.EqualError(t, err, "jwt: fetching JWK Set: unexpected status 500 Internal Server Error")
+	})
+
+	t.Run("background refresh", func(t *testing.T) {
+		s := &jwksServer{}
+		s.set(http.StatusOK, jwkSetOf(t, map[string]interface{}{"ec": ecKey}))
+
+		server := httptest.NewServer(s)
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		f := jwt.NewJWKSFetcher(ctx, server.URL, server.Client(), 10*time.Millisecond)
+
+		var out jwt.StandardClaims
+		assert.NoError(t, f.VerifyES256(ecToken, &out))
+
+		// A refresh caused by the unknown kid is suppressed by the cooldown, so
+		// only the background refresh can pick up the new key.
+		s.set(http.StatusOK, jwkSetOf(t, map[string]interface{}{"new": newEC}))
+		assert.Eventually(t, func() bool {
+			return f.VerifyES256(newToken, &out) == nil
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		requests := atomic.LoadInt32(&s.requests)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, requests, atomic.LoadInt32(&s.requests))
+	})
+}
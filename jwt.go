@@ -7,52 +7,102 @@
 // letting JWTs drive what algorithm is used for verification.
 //
 // When you use this package, you must specify exactly what algorithm you want
-// to use, and only the three most widely-supported algorithms are permitted:
-// HS256, RS256, and ES256. An attacker cannot trick you into accidentally
-// reading a JWT without verifying it, and an attacker cannot trick you into
-// using a different algorithm than you wanted.
+// to use, and only a handful of widely-supported algorithms are permitted. An
+// attacker cannot trick you into accidentally reading a JWT without verifying
+// it, and an attacker cannot trick you into using a different algorithm than
+// you wanted.
 //
-// If you want to use a symmetric-key signature, see SignHS256 and VerifyHS256.
+// If you want to use a symmetric-key signature, see SignHS256 and VerifyHS256,
+// or SignHS512 and VerifyHS512.
 //
-// If you want to use RSA public-key signatures, see SignRS256 and VerifyRS256.
+// If you want to use RSA public-key signatures, see SignRS256 and VerifyRS256,
+// SignRS384 and VerifyRS384, or SignPS256 and VerifyPS256.
 //
 // If you want to use ECDSA public-key signatures, see SignES256 and
-// VerifyES256.
+// VerifyES256, or SignES512 and VerifyES512.
+//
+// If you want to use Ed25519 public-key signatures, see SignEdDSA and
+// VerifyEdDSA.
+//
+// If you want the claims to be encrypted, rather than signed, see EncryptJWE
+// and DecryptJWE.
+//
+// If you want to sign a payload that is sent separately from the token, such as
+// a request body, see SignDetachedHS256 and VerifyDetachedHS256.
+//
+// Any type that works with encoding/json can be used as the claims of a JWT.
+// RegisteredClaims holds the claims registered by RFC7519, and can be embedded
+// in your own claims types.
 package jwt
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
-// ErrInvalidSignature is the error returned by VerifyHS256, VerifyRS256, and
-// VerifyES256 if there is anything wrong with the cryptographic signature on a
-// JWT.
+// ErrInvalidSignature is the error returned by the Verify functions in this
+// package, such as VerifyHS256, if a JWT can't be shown to be authentic.
 //
-// This package intentionally provides no details beyond this error; for most
-// applications, there is no reason to attempt to distinguish the various
-// reasons a cryptographic signature on a JWT may be invalid.
+// The Verify functions return one of three more specific errors, all of which
+// match ErrInvalidSignature with errors.Is:
 //
-// Some of the underlying reasons this error might be returned include:
+// * ErrWrongAlgorithm, if the header of the JWT says that it was signed with an
+// algorithm other than the one the Verify function checks for, or with no
+// algorithm at all.
 //
-// * The JWT was ill-formed. For instance, it may have been missing a header or
-// a signature section.
+// * ErrBadSignature, if the JWT was signed with the wrong key or secret, or
+// some of the data in the token was corrupted after it was signed.
 //
-// * The header section of the JWT indicated that the JWT was signed with the
-// wrong algorithm.
+// * An error wrapping ErrMalformedToken, if the JWT isn't even well-formed,
+// such as one that isn't valid base64 or whose header isn't a JSON object.
 //
-// * The token was signed with the wrong secret.
-//
-// * Some of the data in the token was corrupted.
-//
-// Unless you know precisely what you're doing, you should usually treat any JWT
-// that results in ErrInvalidSignature as an invalid request. You should usually
-// not, in production systems, attempt to automatically dig into precisely what
-// aspect of a JWT was invalid.
+// Check for ErrInvalidSignature with errors.Is, not ==. Unless you know
+// precisely what you're doing, you should treat any JWT that results in
+// ErrInvalidSignature as an invalid request. The specific errors are there so
+// that you can tell them apart in logs and metrics; they all mean the JWT must
+// not be trusted, and none of them should be handled more leniently than the
+// others.
 var ErrInvalidSignature = errors.New("jwt: invalid signature")
 
+// ErrWrongAlgorithm is the error returned by the Verify functions in this
+// package if a JWT's "alg" header isn't the algorithm they verify. It matches
+// ErrInvalidSignature with errors.Is.
+var ErrWrongAlgorithm error = invalidSignatureError("jwt: wrong algorithm")
+
+// ErrBadSignature is the error returned by the Verify functions in this
+// package if the signature on a JWT doesn't match its contents and the key it
+// was checked with. It matches ErrInvalidSignature with errors.Is.
+var ErrBadSignature error = invalidSignatureError("jwt: bad signature")
+
+// invalidSignatureError is the type of the errors that are more specific kinds
+// of ErrInvalidSignature.
+type invalidSignatureError string
+
+func (e invalidSignatureError) Error() string {
+	return string(e)
+}
+
+func (invalidSignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
+
+// ErrInvalidKey is the error returned when the key passed to a Sign or Verify
+// function can't be used with that function's algorithm. For instance,
+// SignES512 returns ErrInvalidKey if it is given a key that is not on the P-521
+// curve.
+//
+// Unlike ErrInvalidSignature, ErrInvalidKey indicates a problem with how the
+// application is configured, not with the JWT being verified.
+var ErrInvalidKey = errors.New("jwt: invalid key")
+
 // StandardClaims is the set of claims registered by RFC7519.
 //
+// StandardClaims remains for compatibility. New code should prefer
+// RegisteredClaims, whose timestamps are NumericDate values rather than
+// int64 values. With int64 timestamps, it is easy to mistakenly store
+// milliseconds or nanoseconds instead of seconds; see VerifyExpirationTime.
+//
 // It is entirely possible and valid to use JWT but not use StandardClaims.
 // StandardClaims is just a convenience struct to hold some of the most
 // commonly-used claims in practice.
@@ -68,6 +118,12 @@ var ErrInvalidSignature = errors.New("jwt: invalid signature")
 // In order to keep the JSON representation of this struct as terse as possible,
 // all fields of this struct are omitted if left to their zero values.
 //
+// RFC7519 permits timestamps to have fractional parts, such as
+// "exp":1300819380.123. When the Verify functions in this package decode such
+// a token into StandardClaims, "exp" and "iat" are rounded down to a whole
+// second, and "nbf" is rounded up. Use RegisteredClaims if you need the exact
+// values.
+//
 // StandardClaims is just a convenience struct. Do not assume that the claims in
 // StandardClaims carry any special meaning in the JWT spec. For more details on
 // the standard JWT claims, see:
@@ -86,6 +142,10 @@ type StandardClaims struct {
 
 	// Audience identifies who is meant to process the JWT.
 	//
+	// RFC7519 permits "aud" to also be an array of strings, but Audience can
+	// only hold a single string. Tokens that use the array form can't be
+	// unmarshalled into StandardClaims. Use RegisteredClaims for such tokens.
+	//
 	// https://tools.ietf.org/html/rfc7519#section-4.1.3
 	Audience string `json:"aud,omitempty"`
 
@@ -117,16 +177,30 @@ type StandardClaims struct {
 	ID string `json:"jti,omitempty"`
 }
 
-// ErrExpiredToken is the error returned from VerifyExpirationTime and
-// VerifyNotBefore when a JWT is not currently valid.
-//
-// When returned from VerifyExpirationTime, ErrExpiredToken error indicates that
-// the JWT is expired.
-//
-// When returned from VerifyNotBefore, ErrExpiredToken error indicates that the
-// JWT is not yet valid.
+// ErrExpiredToken is the error returned from VerifyExpirationTime when a JWT
+// is expired.
 var ErrExpiredToken = errors.New("jwt: expired token")
 
+// ErrNotYetValid is the error returned from VerifyNotBefore when a JWT is not
+// yet valid.
+//
+// VerifyNotBefore used to return ErrExpiredToken in this case. So that
+// existing checks keep working, errors.Is(ErrNotYetValid, ErrExpiredToken) is
+// true. Checks that compare against ErrExpiredToken with == will no longer
+// match. This compatibility will be removed in a future release; new code
+// should check for ErrNotYetValid.
+var ErrNotYetValid error = notYetValidError{}
+
+type notYetValidError struct{}
+
+func (notYetValidError) Error() string {
+	return "jwt: token not yet valid"
+}
+
+func (notYetValidError) Is(target error) bool {
+	return target == ErrExpiredToken
+}
+
 // VerifyExpirationTime checks ExpirationTime ("exp") to see if a JWT has
 // expired, and returns ErrExpiredToken if the token is expired.
 //
@@ -144,6 +218,12 @@ var ErrExpiredToken = errors.New("jwt: expired token")
 // make sure you populate the ExpirationTime ("exp") field in StandardClaims by
 // calling the Unix function on a time.Time instance. If you use UnixNano
 // instead of Unix, VerifyExpirationTime will return invalid results.
+//
+// VerifyExpirationTime does not special-case a missing "exp". If
+// ExpirationTime is left to its zero value, VerifyExpirationTime treats the
+// token as having expired at the Unix epoch, and returns ErrExpiredToken. If
+// tokens without an "exp" are meant to never expire, use
+// VerifyExpirationTimeIfSet instead.
 func (s *StandardClaims) VerifyExpirationTime(now time.Time) error {
 	if now.After(time.Unix(s.ExpirationTime, 0)) {
 		return ErrExpiredToken
@@ -152,8 +232,22 @@ func (s *StandardClaims) VerifyExpirationTime(now time.Time) error {
 	return nil
 }
 
+// VerifyExpirationTimeIfSet is like VerifyExpirationTime, except that a token
+// whose ExpirationTime is left to its zero value is treated as intentionally
+// having no expiry, and so never expires.
+//
+// To reject tokens that have no "exp", combine VerifyExpirationTimeIfSet with
+// RequireClaims.
+func (s *StandardClaims) VerifyExpirationTimeIfSet(now time.Time) error {
+	if s.ExpirationTime == 0 {
+		return nil
+	}
+
+	return s.VerifyExpirationTime(now)
+}
+
 // VerifyNotBefore checks NotBefore ("nbf") to see if a JWT is not yet valid,
-// and returns ErrExpiredToken if the token is not yet valid.
+// and returns ErrNotYetValid if the token is not yet valid.
 //
 // In production, you should usually pass time.Now() as the now argument to this
 // function. But in your tests you may want to use a hard-coded time instead.
@@ -168,9 +262,94 @@ func (s *StandardClaims) VerifyExpirationTime(now time.Time) error {
 // sure you populate the NotBefore ("nbf") field in StandardClaims by calling
 // the Unix function on a time.Time instance. If you use UnixNano instead of
 // Unix, VerifyNotBefore will return invalid results.
+//
+// If NotBefore is left to its zero value, VerifyNotBefore treats the token as
+// becoming valid at the Unix epoch, which is to say that the check always
+// passes. VerifyNotBeforeIfSet makes this explicit.
 func (s *StandardClaims) VerifyNotBefore(now time.Time) error {
 	if now.Before(time.Unix(s.NotBefore, 0)) {
-		return ErrExpiredToken
+		return ErrNotYetValid
+	}
+
+	return nil
+}
+
+// VerifyNotBeforeIfSet is like VerifyNotBefore, except that a token whose
+// NotBefore is left to its zero value is treated as intentionally having no
+// "nbf", and so is valid from the moment it's issued.
+//
+// To reject tokens that have no "nbf", combine VerifyNotBeforeIfSet with
+// RequireClaims.
+func (s *StandardClaims) VerifyNotBeforeIfSet(now time.Time) error {
+	if s.NotBefore == 0 {
+		return nil
+	}
+
+	return s.VerifyNotBefore(now)
+}
+
+// Valid checks both ExpirationTime ("exp") and NotBefore ("nbf"), and returns
+// the first error it finds. It returns ErrExpiredToken if the token is
+// expired, and ErrNotYetValid if the token is not yet valid.
+//
+// Valid is equivalent to calling VerifyExpirationTimeIfSet and then
+// VerifyNotBeforeIfSet. It skips the check for any of these fields that is
+// left to its zero value. A token with no "exp"
+// claim never expires, and a token with no "nbf" claim is valid from the
+// moment it's issued. If you require tokens to carry an expiration time, you
+// must check for that separately.
+//
+// In production, you should usually pass time.Now() as the now argument to this
+// function. But in your tests you may want to use a hard-coded time instead.
+func (s *StandardClaims) Valid(now time.Time) error {
+	if err := s.VerifyExpirationTimeIfSet(now); err != nil {
+		return err
+	}
+
+	return s.VerifyNotBeforeIfSet(now)
+}
+
+// ErrMissingClaim is the error returned from RequireClaims, and from the
+// Verify functions when WithAccessTokenProfile is used, when a claim that is
+// required is not present. The returned error wraps ErrMissingClaim, and
+// names the missing claim; use errors.Is to check for it.
+var ErrMissingClaim = errors.New("jwt: missing required claim")
+
+// RequireClaims checks that each of ExpirationTime ("exp"), NotBefore
+// ("nbf"), and IssuedAt ("iat") is present, if the corresponding argument is
+// true. It returns an error wrapping ErrMissingClaim for the first one that is
+// missing.
+//
+// In StandardClaims, a claim is considered missing if it is left to its zero
+// value. Because of this, a token whose "exp" is literally 0 is treated the
+// same as a token that has no "exp" at all.
+//
+// RequireClaims only checks that claims are present. It does not check whether
+// the token is expired or not yet valid; for that, use Valid. Keeping the two
+// apart lets you tell "this token is missing a claim" apart from "this token
+// has expired":
+//
+//  if err := claims.RequireClaims(true, false, false); err != nil {
+//    // the token has no "exp"
+//  }
+//
+//  if err := claims.Valid(time.Now()); err != nil {
+//    // the token is expired, or not yet valid
+//  }
+func (s *StandardClaims) RequireClaims(exp, nbf, iat bool) error {
+	return requireClaims(exp && s.ExpirationTime == 0, nbf && s.NotBefore == 0, iat && s.IssuedAt == 0)
+}
+
+// requireClaims returns an error wrapping ErrMissingClaim for the first of
+// "exp", "nbf", and "iat" whose corresponding argument is true.
+func requireClaims(missingExp, missingNbf, missingIat bool) error {
+	switch {
+	case missingExp:
+		return fmt.Errorf("%w: \"exp\"", ErrMissingClaim)
+	case missingNbf:
+		return fmt.Errorf("%w: \"nbf\"", ErrMissingClaim)
+	case missingIat:
+		return fmt.Errorf("%w: \"iat\"", ErrMissingClaim)
 	}
 
 	return nil
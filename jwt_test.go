@@ -1,8 +1,20 @@
 package jwt_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,7 +22,8 @@ import (
 	"github.com/ucarion/jwt"
 )
 
-// zeroReader is used to seed crypto/rand.Reader in some examples.
+// zeroReader is a source of "random" bits that are always zero, for tests that
+// need WithRandomID to generate the same "jti" every time.
 type zeroReader struct{}
 
 func (zeroReader) Read(buf []byte) (int, error) {
@@ -45,10 +58,155 @@ func TestVerifyExpirationTime(t *testing.T) {
 
 func TestVerifyNotBefore(t *testing.T) {
 	claims := jwt.StandardClaims{NotBefore: 1}
-	assert.Equal(t, jwt.ErrExpiredToken, claims.VerifyNotBefore(time.Unix(0, 0)))
+	assert.Equal(t, jwt.ErrNotYetValid, claims.VerifyNotBefore(time.Unix(0, 0)))
 	assert.NoError(t, claims.VerifyNotBefore(time.Unix(2, 0)))
 }
 
+func TestVerifyExpirationTimeIfSet(t *testing.T) {
+	claims := jwt.StandardClaims{ExpirationTime: 1}
+	assert.NoError(t, claims.VerifyExpirationTimeIfSet(time.Unix(0, 0)))
+	assert.Equal(t, jwt.ErrExpiredToken, claims.VerifyExpirationTimeIfSet(time.Unix(2, 0)))
+
+	var zero jwt.StandardClaims
+	assert.NoError(t, zero.VerifyExpirationTimeIfSet(time.Unix(0, 0)))
+	assert.NoError(t, zero.VerifyExpirationTimeIfSet(time.Unix(1300819380, 0)))
+}
+
+func TestVerifyNotBeforeIfSet(t *testing.T) {
+	claims := jwt.StandardClaims{NotBefore: 1}
+	assert.Equal(t, jwt.ErrNotYetValid, claims.VerifyNotBeforeIfSet(time.Unix(0, 0)))
+	assert.NoError(t, claims.VerifyNotBeforeIfSet(time.Unix(2, 0)))
+
+	var zero jwt.StandardClaims
+	assert.NoError(t, zero.VerifyNotBeforeIfSet(time.Unix(-1, 0)))
+	assert.NoError(t, zero.VerifyNotBeforeIfSet(time.Unix(1300819380, 0)))
+}
+
+func TestErrNotYetValid(t *testing.T) {
+	// For compatibility, ErrNotYetValid is also an ErrExpiredToken, but not the
+	// other way around.
+	assert.True(t, errors.Is(jwt.ErrNotYetValid, jwt.ErrExpiredToken))
+	assert.False(t, errors.Is(jwt.ErrExpiredToken, jwt.ErrNotYetValid))
+	assert.NotEqual(t, jwt.ErrNotYetValid, jwt.ErrExpiredToken)
+
+	claims := jwt.StandardClaims{NotBefore: 1}
+	assert.True(t, errors.Is(claims.VerifyNotBefore(time.Unix(0, 0)), jwt.ErrExpiredToken))
+	assert.True(t, errors.Is(fmt.Errorf("wrapped: %w", jwt.ErrNotYetValid), jwt.ErrNotYetValid))
+}
+
+func TestStandardClaimsValid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		claims jwt.StandardClaims
+		now    int64
+		err    error
+	}{
+		{"no claims", jwt.StandardClaims{}, 100, nil},
+		{"before exp", jwt.StandardClaims{ExpirationTime: 10}, 5, nil},
+		{"at exp", jwt.StandardClaims{ExpirationTime: 10}, 10, nil},
+		{"after exp", jwt.StandardClaims{ExpirationTime: 10}, 11, jwt.ErrExpiredToken},
+		{"before nbf", jwt.StandardClaims{NotBefore: 10}, 9, jwt.ErrNotYetValid},
+		{"at nbf", jwt.StandardClaims{NotBefore: 10}, 10, nil},
+		{"within nbf and exp", jwt.StandardClaims{NotBefore: 10, ExpirationTime: 20}, 15, nil},
+		{"before nbf and exp", jwt.StandardClaims{NotBefore: 10, ExpirationTime: 20}, 5, jwt.ErrNotYetValid},
+		{"after nbf and exp", jwt.StandardClaims{NotBefore: 10, ExpirationTime: 20}, 25, jwt.ErrExpiredToken},
+		{"exp before nbf", jwt.StandardClaims{NotBefore: 20, ExpirationTime: 10}, 15, jwt.ErrExpiredToken},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.err, tt.claims.Valid(time.Unix(tt.now, 0)))
+		})
+	}
+}
+
+func TestStandardClaimsRequireClaims(t *testing.T) {
+	all := jwt.StandardClaims{ExpirationTime: 1, NotBefore: 1, IssuedAt: 1}
+	assert.NoError(t, all.RequireClaims(true, true, true))
+
+	none := jwt.StandardClaims{}
+	assert.NoError(t, none.RequireClaims(false, false, false))
+
+	err := none.RequireClaims(true, true, true)
+	assert.True(t, errors.Is(err, jwt.ErrMissingClaim))
+	assert.Equal(t, `jwt: missing required claim: "exp"`, err.Error())
+
+	err = none.RequireClaims(false, true, true)
+	assert.Equal(t, `jwt: missing required claim: "nbf"`, err.Error())
+
+	err = none.RequireClaims(false, false, true)
+	assert.Equal(t, `jwt: missing required claim: "iat"`, err.Error())
+
+	// Missing claims and failed claims are distinguishable.
+	expired := jwt.StandardClaims{ExpirationTime: 1}
+	assert.NoError(t, expired.RequireClaims(true, false, false))
+	assert.Equal(t, jwt.ErrExpiredToken, expired.Valid(time.Unix(2, 0)))
+	assert.False(t, errors.Is(expired.Valid(time.Unix(2, 0)), jwt.ErrMissingClaim))
+}
+
+func TestStandardClaimsZeroValues(t *testing.T) {
+	// These tests pin down what each method does when "exp" and "nbf" are
+	// missing, which in StandardClaims means they are zero.
+	claims := jwt.StandardClaims{}
+	now := time.Unix(1300819380, 0)
+
+	// VerifyExpirationTime treats a missing "exp" as having expired at the Unix
+	// epoch.
+	assert.Equal(t, jwt.ErrExpiredToken, claims.VerifyExpirationTime(now))
+
+	// VerifyNotBefore treats a missing "nbf" as becoming valid at the Unix
+	// epoch, which is to say it is valid.
+	assert.NoError(t, claims.VerifyNotBefore(now))
+
+	// The IfSet variants skip their checks.
+	assert.NoError(t, claims.VerifyExpirationTimeIfSet(now))
+	assert.NoError(t, claims.VerifyNotBeforeIfSet(now))
+
+	// Valid skips both checks.
+	assert.NoError(t, claims.Valid(now))
+
+	// RequireClaims considers both missing.
+	assert.True(t, errors.Is(claims.RequireClaims(true, false, false), jwt.ErrMissingClaim))
+	assert.True(t, errors.Is(claims.RequireClaims(false, true, false), jwt.ErrMissingClaim))
+}
+
+func ExampleStandardClaims_RequireClaims() {
+	secret := []byte("my secret key")
+	token, _ := jwt.SignHS256(secret, jwt.StandardClaims{Subject: "jdoe@example.com"})
+
+	var claims jwt.StandardClaims
+	if err := jwt.VerifyHS256(secret, token, &claims); err != nil {
+		panic(err)
+	}
+
+	// This token has no "exp", so it would never expire. Refuse such tokens.
+	fmt.Println(claims.RequireClaims(true, false, false))
+	// Output:
+	//
+	// jwt: missing required claim: "exp"
+}
+
+func ExampleStandardClaims_Valid() {
+	secret := []byte("my secret key")
+	token, _ := jwt.SignHS256(secret, jwt.StandardClaims{
+		Subject:        "jdoe@example.com",
+		ExpirationTime: time.Date(2015, 5, 19, 0, 0, 0, 0, time.UTC).Unix(),
+	})
+
+	var claims jwt.StandardClaims
+	if err := jwt.VerifyHS256(secret, token, &claims); err != nil {
+		panic(err)
+	}
+
+	// Remember to check the claims after the signature.
+	fmt.Println(claims.Valid(time.Date(2015, 5, 18, 0, 0, 0, 0, time.UTC)))
+	fmt.Println(claims.Valid(time.Date(2015, 5, 20, 0, 0, 0, 0, time.UTC)))
+	// Output:
+	//
+	// <nil>
+	// jwt: expired token
+}
+
 func ExampleStandardClaims_VerifyExpirationTime() {
 	exp, _ := time.Parse(time.RFC3339, "2015-05-19T16:45:40-07:00")
 	claims := jwt.StandardClaims{ExpirationTime: exp.Unix()}
@@ -107,7 +265,7 @@ func ExampleStandardClaims_VerifyNotBefore() {
 	fmt.Println(claims.VerifyNotBefore(nowAfterNbf))
 	// Output:
 	//
-	// jwt: expired token
+	// jwt: token not yet valid
 	// <nil>
 }
 
@@ -137,6 +295,276 @@ func ExampleStandardClaims_VerifyNotBefore_unixNano() {
 	fmt.Println(claims.VerifyNotBefore(nowAfterNbf))
 	// Output:
 	//
-	// jwt: expired token
-	// jwt: expired token
+	// jwt: token not yet valid
+	// jwt: token not yet valid
+}
+
+// TestNoOutput checks that nothing in this module, or in the modules nested
+// in it, writes to stdout or stderr. A library has no business printing, and
+// a stray debugging statement in a Verify function would print on every
+// request an application handles.
+//
+// Commands are checked too. Their main function is the one place allowed to
+// name stdout and stderr, so that it can hand them to code that writes only
+// to the io.Writers it's given, and that tests can give other io.Writers.
+func TestNoOutput(t *testing.T) {
+	var paths []string
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != "." && (info.Name() == "testdata" || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Contains(t, paths, filepath.Join("cmd", "jwt", "main.go"))
+	assert.Contains(t, paths, filepath.Join("es256k", "es256k.go"))
+
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		assert.NoError(t, err)
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if f.Name.Name == "main" && n.Recv == nil && n.Name.Name == "main" {
+					return false
+				}
+			case *ast.Ident:
+				if n.Name == "print" || n.Name == "println" {
+					t.Errorf("%s: uses %s", fset.Position(n.Pos()), n.Name)
+				}
+			case *ast.SelectorExpr:
+				pkg, ok := n.X.(*ast.Ident)
+				if !ok {
+					break
+				}
+
+				switch {
+				case pkg.Name == "fmt" && strings.HasPrefix(n.Sel.Name, "Print"),
+					pkg.Name == "os" && (n.Sel.Name == "Stdout" || n.Sel.Name == "Stderr"),
+					pkg.Name == "log":
+					t.Errorf("%s: uses %s.%s", fset.Position(n.Pos()), pkg.Name, n.Sel.Name)
+				}
+			}
+
+			return true
+		})
+	}
+}
+
+func TestInvalidSignatureErrors(t *testing.T) {
+	secret := []byte("my secret key")
+	otherSecret := []byte("other secret")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherP256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+	otherP521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	_, otherEdKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	var keys jwt.KeySet
+	assert.NoError(t, keys.Add("k", jwt.AllowHS256(secret)))
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+	mustSign := func(token []byte, err error) []byte {
+		assert.NoError(t, err)
+		return token
+	}
+
+	hs256 := mustSign(jwt.SignHS256(secret, claims))
+
+	testCases := []struct {
+		name      string
+		verify    func(s []byte) error
+		badSig    []byte
+		wrongAlg  []byte
+		malformed []byte
+	}{
+		{
+			name:     "VerifyHS256",
+			verify:   func(s []byte) error { return jwt.VerifyHS256(secret, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignHS256(otherSecret, claims)),
+			wrongAlg: mustSign(jwt.SignHS512(secret, claims)),
+		},
+		{
+			name:     "VerifyHS256 without claims",
+			verify:   func(s []byte) error { return jwt.VerifyHS256(secret, s, nil) },
+			badSig:   mustSign(jwt.SignHS256(otherSecret, claims)),
+			wrongAlg: mustSign(jwt.SignHS512(secret, claims)),
+		},
+		{
+			name: "VerifyHS256Any",
+			verify: func(s []byte) error {
+				_, err := jwt.VerifyHS256Any([][]byte{secret, secret}, s, &jwt.StandardClaims{})
+				return err
+			},
+			badSig:   mustSign(jwt.SignHS256(otherSecret, claims)),
+			wrongAlg: mustSign(jwt.SignHS512(secret, claims)),
+		},
+		{
+			name:     "HS256Verifier",
+			verify:   func(s []byte) error { return jwt.NewHS256Verifier(secret).Verify(s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignHS256(otherSecret, claims)),
+			wrongAlg: mustSign(jwt.SignHS512(secret, claims)),
+		},
+		{
+			name: "HS256Verifier.VerifyBuf",
+			verify: func(s []byte) error {
+				return jwt.NewHS256Verifier(secret).VerifyBuf(s, make([]byte, len(s)), &jwt.StandardClaims{})
+			},
+			badSig:   mustSign(jwt.SignHS256(otherSecret, claims)),
+			wrongAlg: mustSign(jwt.SignHS512(secret, claims)),
+		},
+		{
+			name:     "VerifyHS512",
+			verify:   func(s []byte) error { return jwt.VerifyHS512(secret, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignHS512(otherSecret, claims)),
+			wrongAlg: hs256,
+		},
+		{
+			name:     "VerifyRS256",
+			verify:   func(s []byte) error { return jwt.VerifyRS256(&rsaKey.PublicKey, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignRS256(otherRSAKey, claims)),
+			wrongAlg: hs256,
+		},
+		{
+			name:     "VerifyRS384",
+			verify:   func(s []byte) error { return jwt.VerifyRS384(&rsaKey.PublicKey, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignRS384(otherRSAKey, claims)),
+			wrongAlg: mustSign(jwt.SignRS256(rsaKey, claims)),
+		},
+		{
+			name:     "VerifyPS256",
+			verify:   func(s []byte) error { return jwt.VerifyPS256(&rsaKey.PublicKey, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignPS256(otherRSAKey, claims)),
+			wrongAlg: mustSign(jwt.SignRS256(rsaKey, claims)),
+		},
+		{
+			name:     "VerifyES256",
+			verify:   func(s []byte) error { return jwt.VerifyES256(&p256Key.PublicKey, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignES256(otherP256Key, claims)),
+			wrongAlg: hs256,
+		},
+		{
+			name:     "VerifyES512",
+			verify:   func(s []byte) error { return jwt.VerifyES512(&p521Key.PublicKey, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignES512(otherP521Key, claims)),
+			wrongAlg: mustSign(jwt.SignES256(p256Key, claims)),
+		},
+		{
+			name:     "VerifyEdDSA",
+			verify:   func(s []byte) error { return jwt.VerifyEdDSA(edPub, s, &jwt.StandardClaims{}) },
+			badSig:   mustSign(jwt.SignEdDSA(otherEdKey, claims)),
+			wrongAlg: hs256,
+		},
+		{
+			name: "VerifyAny",
+			verify: func(s []byte) error {
+				_, err := jwt.VerifyAny(s, &jwt.StandardClaims{}, jwt.AllowHS256(secret), jwt.AllowES256(&p256Key.PublicKey))
+				return err
+			},
+			badSig:   mustSign(jwt.SignES256(otherP256Key, claims)),
+			wrongAlg: mustSign(jwt.SignEdDSA(edKey, claims)),
+		},
+		{
+			name: "VerifyCustom",
+			verify: func(s []byte) error {
+				return jwt.VerifyCustom("X-TEST", s, &jwt.StandardClaims{}, func(data, sig []byte) error {
+					return jwt.ErrInvalidSignature
+				})
+			},
+			badSig:   mustSign(jwt.SignCustom("X-TEST", claims, func(data []byte) ([]byte, error) { return []byte("sig"), nil })),
+			wrongAlg: hs256,
+		},
+		{
+			name:   "VerifyWithKeySet",
+			verify: func(s []byte) error { return jwt.VerifyWithKeySet(&keys, s, &jwt.StandardClaims{}) },
+			badSig: mustSign(jwt.SignHS256(otherSecret, claims, jwt.WithKeyID("k"))),
+		},
+		{
+			name: "Token.VerifyHS256",
+			verify: func(s []byte) error {
+				tok, err := jwt.Parse(s)
+				if err != nil {
+					return err
+				}
+
+				return tok.VerifyHS256(secret)
+			},
+			badSig:   mustSign(jwt.SignHS256(otherSecret, claims)),
+			wrongAlg: mustSign(jwt.SignHS512(secret, claims)),
+		},
+	}
+
+	// The header of this token is base64url, but not JSON.
+	malformed := []byte("bm90IGpzb24.e30.AAAA")
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds := map[error][]byte{
+				jwt.ErrBadSignature:   tt.badSig,
+				jwt.ErrWrongAlgorithm: tt.wrongAlg,
+				jwt.ErrMalformedToken: malformed,
+			}
+
+			for kind, token := range kinds {
+				if token == nil {
+					continue
+				}
+
+				err := tt.verify(token)
+				assert.True(t, errors.Is(err, jwt.ErrInvalidSignature), "%v: %v", kind, err)
+
+				for other := range kinds {
+					assert.Equal(t, other == kind, errors.Is(err, other), "%v: %v", kind, err)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidSignatureErrorsAreDistinct(t *testing.T) {
+	specific := []error{jwt.ErrBadSignature, jwt.ErrWrongAlgorithm, jwt.ErrMalformedToken}
+
+	for _, err := range specific {
+		assert.True(t, errors.Is(err, jwt.ErrInvalidSignature), err)
+		assert.False(t, errors.Is(jwt.ErrInvalidSignature, err), err)
+
+		for _, other := range specific {
+			assert.Equal(t, err == other, errors.Is(err, other), "%v, %v", err, other)
+		}
+	}
+
+	wrapped := fmt.Errorf("verifying: %w", jwt.ErrWrongAlgorithm)
+	assert.True(t, errors.Is(wrapped, jwt.ErrInvalidSignature))
+	assert.True(t, errors.Is(wrapped, jwt.ErrWrongAlgorithm))
 }
@@ -0,0 +1,23 @@
+module github.com/ucarion/jwt/jwtgrpc
+
+go 1.21
+
+require (
+	github.com/stretchr/testify v1.5.1
+	github.com/ucarion/jwt v0.1.0
+	google.golang.org/grpc v1.64.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
+
+// Use this checkout of the root module when developing in this repository.
+replace github.com/ucarion/jwt => ../
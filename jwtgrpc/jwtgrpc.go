@@ -0,0 +1,203 @@
+// Package jwtgrpc verifies JWTs sent to gRPC servers.
+//
+// UnaryServerInterceptor and StreamServerInterceptor return interceptors that
+// read a bearer token from the "authorization" metadata of each call, verify
+// it, and add its claims to the call's context, where handlers can get them
+// using jwt.ClaimsFromContext. The token itself is added too, and can be gotten
+// using jwt.RawTokenFromContext. They are the gRPC counterparts of
+// jwt.Middleware.
+//
+// This package lives in its own module so that users of
+// github.com/ucarion/jwt who don't use gRPC don't depend on it.
+package jwtgrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ucarion/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures the interceptors returned by UnaryServerInterceptor and
+// StreamServerInterceptor.
+type Option interface {
+	apply(*config)
+}
+
+// config is the result of applying a set of Option.
+type config struct {
+	verify     jwt.VerifyFunc
+	verifyOpts []jwt.VerifyOption
+	claims     func() interface{}
+	exempt     map[string]struct{}
+}
+
+// optionFunc adapts a function into an Option.
+type optionFunc func(*config)
+
+func (f optionFunc) apply(c *config) {
+	f(c)
+}
+
+// WithVerifyOptions makes an interceptor pass opts to its jwt.VerifyFunc. This
+// is how to have it check the issuer and audience of tokens, using
+// jwt.WithExpectedIssuer and jwt.WithExpectedAudience, or how much clock skew
+// to allow, using jwt.WithLeeway.
+//
+// The interceptors always check "exp" and "nbf", as if jwt.WithLeeway(0) came
+// before opts.
+func WithVerifyOptions(opts ...jwt.VerifyOption) Option {
+	return optionFunc(func(c *config) {
+		c.verifyOpts = append(c.verifyOpts, opts...)
+	})
+}
+
+// WithClaims makes an interceptor verify each token into a new value returned
+// by factory, instead of into a new *jwt.StandardClaims. factory must return a
+// non-nil pointer. The pointer is what jwt.ClaimsFromContext returns.
+func WithClaims(factory func() interface{}) Option {
+	return optionFunc(func(c *config) {
+		c.claims = factory
+	})
+}
+
+// WithExemptMethods makes an interceptor let calls to any of methods through
+// without a token. Each of methods is a full method name, such as
+// "/grpc.health.v1.Health/Check". Calls to exempt methods have no claims in
+// their context, even if they carry a token.
+func WithExemptMethods(methods ...string) Option {
+	return optionFunc(func(c *config) {
+		for _, m := range methods {
+			c.exempt[m] = struct{}{}
+		}
+	})
+}
+
+// UnaryServerInterceptor returns an interceptor that verifies the bearer token
+// in the "authorization" metadata of each unary call using verify, before
+// calling the handler. If the token is verified, its claims are added to the
+// context passed to the handler. By default, the claims are a
+// *jwt.StandardClaims; use WithClaims to change that. "exp" and "nbf" are
+// always checked.
+//
+// If a call has no token, or its token is rejected, the handler is not
+// called. The call fails with codes.PermissionDenied if the token lacks a scope
+// required by jwt.WithRequiredScopes, and with codes.Unauthenticated
+// otherwise. The status doesn't say anything more about why the token was
+// rejected.
+//
+// UnaryServerInterceptor panics if verify is nil.
+func UnaryServerInterceptor(verify jwt.VerifyFunc, opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(verify, opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := c.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is like UnaryServerInterceptor, but for streaming
+// calls. The claims are added to the context returned by the Context method of
+// the stream passed to the handler.
+//
+// StreamServerInterceptor panics if verify is nil.
+func StreamServerInterceptor(verify jwt.VerifyFunc, opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(verify, opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := c.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream is a grpc.ServerStream with a different context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// newConfig applies opts, in order, to the default config. It panics if verify
+// is nil, so that a misconfigured interceptor fails when it's constructed,
+// rather than on every call.
+func newConfig(verify jwt.VerifyFunc, opts []Option) *config {
+	if verify == nil {
+		panic("jwtgrpc: interceptor requires a non-nil jwt.VerifyFunc")
+	}
+
+	c := &config{
+		verify:     verify,
+		verifyOpts: []jwt.VerifyOption{jwt.WithLeeway(0)},
+		claims:     func() interface{} { return &jwt.StandardClaims{} },
+		exempt:     map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	return c
+}
+
+// authenticate verifies the token in the metadata of ctx, and returns ctx with
+// the token's claims added to it. Calls to exempt methods get ctx back as-is.
+func (c *config) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if _, ok := c.exempt[method]; ok {
+		return ctx, nil
+	}
+
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+
+	claims := c.claims()
+	if err := c.verify(token, claims, c.verifyOpts...); err != nil {
+		if errors.Is(err, jwt.ErrInsufficientScope) {
+			return nil, status.Error(codes.PermissionDenied, "insufficient scope")
+		}
+
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return jwt.NewRawTokenContext(jwt.NewContext(ctx, claims), token), nil
+}
+
+// bearerToken returns the token in the "authorization" metadata of ctx. There
+// must be exactly one "authorization" value, using the "Bearer" scheme.
+func bearerToken(ctx context.Context) ([]byte, bool) {
+	const prefix = "Bearer "
+
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) != 1 {
+		return nil, false
+	}
+
+	v := values[0]
+	if len(v) < len(prefix) || !strings.EqualFold(v[:len(prefix)], prefix) {
+		return nil, false
+	}
+
+	token := strings.TrimSpace(v[len(prefix):])
+	if token == "" {
+		return nil, false
+	}
+
+	return []byte(token), true
+}
@@ -0,0 +1,178 @@
+package jwtgrpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+	"github.com/ucarion/jwt/jwtgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var secret = []byte("my secret key")
+
+func verify(s []byte, v interface{}, opts ...jwt.VerifyOption) error {
+	return jwt.VerifyHS256(secret, s, v, opts...)
+}
+
+func sign(t *testing.T, claims interface{}) string {
+	token, err := jwt.SignHS256(secret, claims)
+	assert.NoError(t, err)
+	return string(token)
+}
+
+// incoming returns a context for a call with the given "authorization"
+// metadata.
+func incoming(authorization ...string) context.Context {
+	md := metadata.MD{}
+	for _, a := range authorization {
+		md.Append("authorization", a)
+	}
+
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+type claims struct {
+	jwt.StandardClaims
+	Tenant string `json:"tenant"`
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	now := time.Now().Unix()
+
+	// call makes a unary call to method through an interceptor configured with
+	// opts, and returns the status code and the claims the handler saw.
+	call := func(ctx context.Context, method string, opts ...jwtgrpc.Option) (codes.Code, interface{}) {
+		var seen interface{}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			seen, _ = jwt.ClaimsFromContext(ctx)
+			return "ok", nil
+		}
+
+		interceptor := jwtgrpc.UnaryServerInterceptor(verify, opts...)
+		res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		if err == nil {
+			assert.Equal(t, "ok", res)
+		}
+
+		return status.Code(err), seen
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		code, seen := call(incoming("Bearer "+sign(t, jwt.StandardClaims{Subject: "jdoe"})), "/pkg.Service/Method")
+		assert.Equal(t, codes.OK, code)
+		assert.Equal(t, &jwt.StandardClaims{Subject: "jdoe"}, seen)
+	})
+
+	t.Run("custom claims", func(t *testing.T) {
+		factory := jwtgrpc.WithClaims(func() interface{} { return &claims{} })
+		code, seen := call(incoming("bearer "+sign(t, claims{Tenant: "acme"})), "/pkg.Service/Method", factory)
+		assert.Equal(t, codes.OK, code)
+		assert.Equal(t, "acme", seen.(*claims).Tenant)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		forged, err := jwt.SignHS256([]byte("other"), jwt.StandardClaims{})
+		assert.NoError(t, err)
+
+		rejected := []context.Context{
+			context.Background(),
+			incoming(),
+			incoming("Basic amRvZTpodW50ZXIy"),
+			incoming("Bearer "),
+			incoming("Bearer " + string(forged)),
+			incoming("Bearer " + sign(t, jwt.StandardClaims{ExpirationTime: now - 60})),
+			incoming("Bearer " + sign(t, jwt.StandardClaims{NotBefore: now + 60})),
+			incoming("Bearer "+sign(t, jwt.StandardClaims{}), "Bearer "+sign(t, jwt.StandardClaims{})),
+		}
+
+		for i, ctx := range rejected {
+			code, seen := call(ctx, "/pkg.Service/Method")
+			assert.Equal(t, codes.Unauthenticated, code, i)
+			assert.Nil(t, seen, i)
+		}
+
+		// The status doesn't say why the token was rejected.
+		interceptor := jwtgrpc.UnaryServerInterceptor(verify)
+		_, err1 := interceptor(incoming("Bearer "+string(forged)), nil, &grpc.UnaryServerInfo{}, nil)
+		_, err2 := interceptor(incoming("Bearer "+sign(t, jwt.StandardClaims{ExpirationTime: now - 60})), nil, &grpc.UnaryServerInfo{}, nil)
+		assert.Equal(t, err1.Error(), err2.Error())
+	})
+
+	t.Run("verify options", func(t *testing.T) {
+		opt := jwtgrpc.WithVerifyOptions(jwt.WithExpectedAudience("api"), jwt.WithLeeway(time.Minute))
+
+		code, _ := call(incoming("Bearer "+sign(t, map[string]interface{}{"aud": "api", "exp": now - 30})), "/pkg.Service/Method", opt)
+		assert.Equal(t, codes.OK, code)
+
+		code, _ = call(incoming("Bearer "+sign(t, map[string]interface{}{"aud": "other"})), "/pkg.Service/Method", opt)
+		assert.Equal(t, codes.Unauthenticated, code)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		opt := jwtgrpc.WithVerifyOptions(jwt.WithRequiredScopes("write"))
+		code, _ := call(incoming("Bearer "+sign(t, map[string]string{"scope": "read"})), "/pkg.Service/Method", opt)
+		assert.Equal(t, codes.PermissionDenied, code)
+	})
+
+	t.Run("requires a verify func", func(t *testing.T) {
+		assert.Panics(t, func() {
+			jwtgrpc.UnaryServerInterceptor(nil)
+		})
+
+		assert.Panics(t, func() {
+			jwtgrpc.StreamServerInterceptor(nil)
+		})
+	})
+
+	t.Run("exempt methods", func(t *testing.T) {
+		opt := jwtgrpc.WithExemptMethods("/grpc.health.v1.Health/Check")
+
+		code, seen := call(context.Background(), "/grpc.health.v1.Health/Check", opt)
+		assert.Equal(t, codes.OK, code)
+		assert.Nil(t, seen)
+
+		code, _ = call(context.Background(), "/grpc.health.v1.Health/Watch", opt)
+		assert.Equal(t, codes.Unauthenticated, code)
+	})
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := jwtgrpc.StreamServerInterceptor(verify, jwtgrpc.WithExemptMethods("/grpc.health.v1.Health/Watch"))
+
+	var seen interface{}
+	var raw []byte
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		seen, _ = jwt.ClaimsFromContext(ss.Context())
+		raw, _ = jwt.RawTokenFromContext(ss.Context())
+		return nil
+	}
+
+	token := sign(t, jwt.StandardClaims{Subject: "jdoe"})
+	stream := &fakeStream{ctx: incoming("Bearer " + token)}
+	assert.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/pkg.Service/Stream"}, handler))
+	assert.Equal(t, &jwt.StandardClaims{Subject: "jdoe"}, seen)
+	assert.Equal(t, token, string(raw))
+
+	seen = nil
+	err := interceptor(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/pkg.Service/Stream"}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Nil(t, seen)
+
+	err = interceptor(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, handler)
+	assert.NoError(t, err)
+}
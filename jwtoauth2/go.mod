@@ -0,0 +1,18 @@
+module github.com/ucarion/jwt/jwtoauth2
+
+go 1.18
+
+require (
+	github.com/stretchr/testify v1.5.1
+	github.com/ucarion/jwt v0.1.0
+	golang.org/x/oauth2 v0.20.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
+)
+
+// Use this checkout of the root module when developing in this repository.
+replace github.com/ucarion/jwt => ../
@@ -0,0 +1,180 @@
+// Package jwtoauth2 adapts JWTs you sign yourself to golang.org/x/oauth2.
+//
+// Services that accept tokens signed by their callers, rather than by an
+// authorization server, still benefit from the plumbing in
+// golang.org/x/oauth2, such as oauth2.Transport and oauth2.NewClient. A
+// TokenSource from this package fits into that plumbing, minting and caching
+// a JWT using a jwt.Signer.
+//
+// This package lives in its own module so that users of
+// github.com/ucarion/jwt who don't use golang.org/x/oauth2 don't depend on it.
+package jwtoauth2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ucarion/jwt"
+	"golang.org/x/oauth2"
+)
+
+// DefaultSkew is how long before a token expires that a TokenSource mints a
+// new one, unless WithSkew says otherwise. It is the same as the margin that
+// oauth2.Token uses to decide that a token is no longer valid.
+const DefaultSkew = 10 * time.Second
+
+// Option configures a TokenSource.
+type Option interface {
+	apply(*TokenSource)
+}
+
+// optionFunc adapts a function into an Option.
+type optionFunc func(*TokenSource)
+
+func (f optionFunc) apply(s *TokenSource) {
+	f(s)
+}
+
+// WithSkew makes a TokenSource mint a new token once its current one is
+// within d of expiring, instead of within DefaultSkew. d must be less than the
+// ttl given to NewTokenSource.
+func WithSkew(d time.Duration) Option {
+	return optionFunc(func(s *TokenSource) {
+		s.skew = d
+	})
+}
+
+// WithClock makes a TokenSource use c to get the current time, instead of
+// jwt.RealClock. It is meant for tests, which can use a jwttest.FixedClock or
+// a Clock of their own.
+func WithClock(c jwt.Clock) Option {
+	return optionFunc(func(s *TokenSource) {
+		s.clock = c
+	})
+}
+
+// TokenSource is an oauth2.TokenSource that mints JWTs using a jwt.Signer.
+// It caches the token it mints, and mints a new one only once the cached one
+// is about to expire.
+//
+// A TokenSource is safe for concurrent use. Concurrent calls to Token that
+// need a new token wait for a single one to be minted, rather than each
+// minting their own. Construct one using NewTokenSource.
+type TokenSource struct {
+	signer jwt.Signer
+	claims map[string]json.RawMessage
+	ttl    time.Duration
+	skew   time.Duration
+	clock  jwt.Clock
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// NewTokenSource returns a TokenSource that mints tokens using signer. Each
+// token carries the claims in claims, which must encode as a JSON object, plus
+// an "iat" of the time the token was minted and an "exp" ttl after that. Any
+// "iat" or "exp" in claims is replaced.
+//
+// claims is encoded once, by NewTokenSource, and so changes to it afterwards
+// don't affect the tokens the TokenSource mints. NewTokenSource returns an
+// error if claims can't be encoded as a JSON object.
+//
+// NewTokenSource also returns an error if ttl is no longer than the skew,
+// which is DefaultSkew unless WithSkew says otherwise. Every token would be
+// within the skew of expiring as soon as it was minted, and so a TokenSource
+// would mint a new token on every call to Token.
+//
+// The algorithm and key tokens are signed with are up to signer. To sign with
+// HS256, for instance, use:
+//
+//	jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+//		return jwt.SignHS256(secret, v, opts...)
+//	})
+func NewTokenSource(signer jwt.Signer, claims interface{}, ttl time.Duration, opts ...Option) (*TokenSource, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil || m == nil {
+		return nil, fmt.Errorf("jwtoauth2: claims must encode as a JSON object, not %s", data)
+	}
+
+	s := &TokenSource{signer: signer, claims: m, ttl: ttl, skew: DefaultSkew, clock: jwt.RealClock{}}
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+
+	if ttl <= s.skew {
+		return nil, fmt.Errorf("jwtoauth2: ttl must be longer than the skew, but ttl is %v and the skew is %v", ttl, s.skew)
+	}
+
+	return s, nil
+}
+
+// Token implements oauth2.TokenSource. It returns the cached token, unless the
+// cached token expires within the skew, in which case it mints, caches, and
+// returns a new one. The returned token's Expiry is when its "exp" says it
+// expires, and its TokenType is "Bearer".
+//
+// If minting a new token fails, Token returns the error from signing it, and
+// keeps the token it had cached, if any.
+func (s *TokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.token == nil || !now.Add(s.skew).Before(s.token.Expiry) {
+		token, err := s.mint(now)
+		if err != nil {
+			return nil, err
+		}
+
+		s.token = token
+	}
+
+	// Return a copy, so that callers can't change the cached token.
+	token := *s.token
+	return &token, nil
+}
+
+// Expiry returns when the cached token expires. Because a TokenSource mints a
+// new token a little before that, Expiry is a good time by which to have
+// called Token again. Expiry returns the zero time if no token has been minted
+// yet.
+func (s *TokenSource) Expiry() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil {
+		return time.Time{}
+	}
+
+	return s.token.Expiry
+}
+
+// mint signs a new token, issued at now.
+func (s *TokenSource) mint(now time.Time) (*oauth2.Token, error) {
+	exp := now.Add(s.ttl)
+
+	claims := make(map[string]json.RawMessage, len(s.claims)+2)
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+
+	claims["iat"] = json.RawMessage(strconv.FormatInt(now.Unix(), 10))
+	claims["exp"] = json.RawMessage(strconv.FormatInt(exp.Unix(), 10))
+
+	token, err := s.signer.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// "exp" has whole-second precision, so report the expiry it actually says.
+	return &oauth2.Token{AccessToken: string(token), TokenType: "Bearer", Expiry: time.Unix(exp.Unix(), 0)}, nil
+}
@@ -0,0 +1,177 @@
+package jwtoauth2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+	"github.com/ucarion/jwt/jwtoauth2"
+	"golang.org/x/oauth2"
+)
+
+var _ oauth2.TokenSource = (*jwtoauth2.TokenSource)(nil)
+
+var secret = []byte("my secret key")
+
+// countingSigner signs with HS256, and counts how many tokens it has signed.
+type countingSigner struct {
+	n int32
+}
+
+func (s *countingSigner) Sign(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+	atomic.AddInt32(&s.n, 1)
+	return jwt.SignHS256(secret, v, opts...)
+}
+
+// clock is a jwt.Clock that tests can move forward.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTokenSource(t *testing.T) {
+	t.Run("claims", func(t *testing.T) {
+		c := &clock{now: time.Unix(1500000000, 0)}
+		src, err := jwtoauth2.NewTokenSource(&countingSigner{}, jwt.StandardClaims{Issuer: "svc", ExpirationTime: 1}, time.Minute, jwtoauth2.WithClock(c))
+		assert.NoError(t, err)
+
+		assert.Equal(t, time.Time{}, src.Expiry())
+
+		token, err := src.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, time.Unix(1500000060, 0), token.Expiry)
+		assert.Equal(t, time.Unix(1500000060, 0), src.Expiry())
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, []byte(token.AccessToken), &claims))
+		assert.Equal(t, jwt.StandardClaims{Issuer: "svc", IssuedAt: 1500000000, ExpirationTime: 1500000060}, claims)
+	})
+
+	t.Run("caching", func(t *testing.T) {
+		c := &clock{now: time.Unix(1500000000, 0)}
+		signer := &countingSigner{}
+		src, err := jwtoauth2.NewTokenSource(signer, map[string]string{"sub": "svc"}, time.Minute, jwtoauth2.WithClock(c), jwtoauth2.WithSkew(5*time.Second))
+		assert.NoError(t, err)
+
+		first, err := src.Token()
+		assert.NoError(t, err)
+
+		// Until the token is within the skew of expiring, it is reused.
+		c.Add(54 * time.Second)
+		token, err := src.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, first.AccessToken, token.AccessToken)
+		assert.Equal(t, int32(1), signer.n)
+
+		c.Add(time.Second)
+		token, err = src.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.AccessToken, token.AccessToken)
+		assert.Equal(t, time.Unix(1500000115, 0), token.Expiry)
+		assert.Equal(t, int32(2), signer.n)
+
+		// Changing the returned token doesn't change the cached one.
+		token.AccessToken = "changed"
+		token, err = src.Token()
+		assert.NoError(t, err)
+		assert.NotEqual(t, "changed", token.AccessToken)
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		signer := &countingSigner{}
+		src, err := jwtoauth2.NewTokenSource(signer, map[string]string{"sub": "svc"}, time.Hour)
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := src.Token()
+				assert.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, int32(1), signer.n)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := jwtoauth2.NewTokenSource(&countingSigner{}, []string{"not", "an", "object"}, time.Minute)
+		assert.Error(t, err)
+
+		_, err = jwtoauth2.NewTokenSource(&countingSigner{}, nil, time.Minute)
+		assert.Error(t, err)
+
+		// A ttl no longer than the skew would defeat caching.
+		_, err = jwtoauth2.NewTokenSource(&countingSigner{}, map[string]string{}, 5*time.Second)
+		assert.EqualError(t, err, "jwtoauth2: ttl must be longer than the skew, but ttl is 5s and the skew is 10s")
+
+		_, err = jwtoauth2.NewTokenSource(&countingSigner{}, map[string]string{}, time.Minute, jwtoauth2.WithSkew(time.Minute))
+		assert.EqualError(t, err, "jwtoauth2: ttl must be longer than the skew, but ttl is 1m0s and the skew is 1m0s")
+
+		_, err = jwtoauth2.NewTokenSource(&countingSigner{}, map[string]string{}, 5*time.Second, jwtoauth2.WithSkew(time.Second))
+		assert.NoError(t, err)
+
+		errSign := errors.New("sign failed")
+		failing := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+			return nil, errSign
+		})
+
+		src, err := jwtoauth2.NewTokenSource(failing, map[string]string{}, time.Minute)
+		assert.NoError(t, err)
+
+		_, err = src.Token()
+		assert.Equal(t, errSign, err)
+		assert.Equal(t, time.Time{}, src.Expiry())
+	})
+}
+
+func ExampleNewTokenSource() {
+	signer := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+		return jwt.SignHS256(secret, v, opts...)
+	})
+
+	src, err := jwtoauth2.NewTokenSource(signer, jwt.StandardClaims{Issuer: "billing"}, 5*time.Minute)
+	if err != nil {
+		panic(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims jwt.StandardClaims
+		token, _ := jwt.FromAuthorizationHeader(r)
+		fmt.Println(jwt.VerifyHS256(secret, token, &claims), claims.Issuer)
+	}))
+	defer server.Close()
+
+	// oauth2.NewClient adds the token to every request, and asks src for a new
+	// one when it's about to expire.
+	client := oauth2.NewClient(context.Background(), src)
+	if _, err := client.Get(server.URL); err != nil {
+		panic(err)
+	}
+	// Output:
+	//
+	// <nil> billing
+}
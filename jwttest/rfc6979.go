@@ -0,0 +1,136 @@
+package jwttest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// rfc6979Signer is a crypto.Signer that makes ECDSA signatures the way RFC6979
+// describes: the secret nonce that ECDSA needs for each signature is derived
+// from the private key and the digest, instead of being read from a source of
+// randomness. Signing the same digest with the same key always gives the same
+// signature.
+//
+// Signatures made this way are as secure as any other ECDSA signature, but
+// this implementation uses math/big, and so is not constant-time. It is for
+// tests only.
+//
+// https://tools.ietf.org/html/rfc6979
+type rfc6979Signer struct {
+	priv *ecdsa.PrivateKey
+}
+
+func (s rfc6979Signer) Public() crypto.PublicKey {
+	return &s.priv.PublicKey
+}
+
+// Sign returns an ASN.1 DER-encoded signature of digest, as
+// ecdsa.PrivateKey.Sign does. rand is ignored.
+func (s rfc6979Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts == nil || !opts.HashFunc().Available() {
+		return nil, errors.New("jwttest: deterministic ECDSA requires a hash function")
+	}
+
+	r, sig, err := signRFC6979(s.priv, opts.HashFunc(), digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(struct{ R, S *big.Int }{r, sig})
+}
+
+// signRFC6979 signs digest, made with hash, with priv, using a nonce derived as
+// described in RFC6979, section 3.2.
+func signRFC6979(priv *ecdsa.PrivateKey, hash crypto.Hash, digest []byte) (r, s *big.Int, err error) {
+	curve := priv.Curve.Params()
+	q := curve.N
+	qlen := q.BitLen()
+	rlen := (qlen + 7) / 8
+
+	// bits2int takes the leftmost qlen bits of b, as a big-endian integer.
+	bits2int := func(b []byte) *big.Int {
+		i := new(big.Int).SetBytes(b)
+		if excess := len(b)*8 - qlen; excess > 0 {
+			i.Rsh(i, uint(excess))
+		}
+
+		return i
+	}
+
+	// int2octets encodes i, which must be less than q, in rlen bytes.
+	int2octets := func(i *big.Int) []byte {
+		out := make([]byte, rlen)
+		b := i.Bytes()
+		copy(out[rlen-len(b):], b)
+		return out
+	}
+
+	// bits2octets is like bits2int, except that the result is reduced modulo q
+	// and then encoded with int2octets.
+	bits2octets := func(b []byte) []byte {
+		i := bits2int(b)
+		if i.Cmp(q) >= 0 {
+			i.Sub(i, q)
+		}
+
+		return int2octets(i)
+	}
+
+	mac := func(key []byte, parts ...[]byte) []byte {
+		h := hmac.New(hash.New, key)
+		for _, p := range parts {
+			h.Write(p)
+		}
+
+		return h.Sum(nil)
+	}
+
+	x := int2octets(priv.D)
+	h1 := bits2octets(digest)
+	e := bits2int(digest)
+
+	v := make([]byte, hash.Size())
+	k := make([]byte, hash.Size())
+	for i := range v {
+		v[i] = 0x01
+	}
+
+	k = mac(k, v, []byte{0x00}, x, h1)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h1)
+	v = mac(k, v)
+
+	for {
+		var t []byte
+		for len(t) < rlen {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+
+		nonce := bits2int(t)
+		if nonce.Sign() > 0 && nonce.Cmp(q) < 0 {
+			rx, _ := priv.Curve.ScalarBaseMult(int2octets(nonce))
+			r = new(big.Int).Mod(rx, q)
+
+			if r.Sign() != 0 {
+				// s = nonce^-1 * (e + x*r) mod q
+				s = new(big.Int).Mul(priv.D, r)
+				s.Add(s, e)
+				s.Mul(s, new(big.Int).ModInverse(nonce, q))
+				s.Mod(s, q)
+
+				if s.Sign() != 0 {
+					return r, s, nil
+				}
+			}
+		}
+
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
@@ -0,0 +1,81 @@
+package jwttest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignRFC6979(t *testing.T) {
+	// These test vectors are from:
+	//
+	// https://tools.ietf.org/html/rfc6979#appendix-A.2.5
+	fromHex := func(s string) *big.Int {
+		b, err := hex.DecodeString(s)
+		assert.NoError(t, err)
+		return new(big.Int).SetBytes(b)
+	}
+
+	priv := &ecdsa.PrivateKey{D: fromHex("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")}
+	priv.Curve = elliptic.P256()
+	priv.X, priv.Y = priv.Curve.ScalarBaseMult(priv.D.Bytes())
+
+	assert.Equal(t, fromHex("60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6"), priv.X)
+	assert.Equal(t, fromHex("7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299"), priv.Y)
+
+	sha256Sum := func(s string) []byte {
+		h := sha256.Sum256([]byte(s))
+		return h[:]
+	}
+
+	sha512Sum := func(s string) []byte {
+		h := sha512.Sum512([]byte(s))
+		return h[:]
+	}
+
+	testCases := []struct {
+		name   string
+		hash   crypto.Hash
+		digest []byte
+		r, s   string
+	}{
+		{
+			"SHA-256, sample",
+			crypto.SHA256,
+			sha256Sum("sample"),
+			"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			"SHA-256, test",
+			crypto.SHA256,
+			sha256Sum("test"),
+			"F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			"019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083",
+		},
+		{
+			"SHA-512, sample",
+			crypto.SHA512,
+			sha512Sum("sample"),
+			"8496A60B5E9B47C825488827E0495B0E3FA109EC4568FD3F8D1097678EB97F00",
+			"2362AB1ADBE2B8ADF9CB9EDAB740EA6049C028114F2460F96554F61FAE3302FE",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, s, err := signRFC6979(priv, tt.hash, tt.digest)
+			assert.NoError(t, err)
+			assert.Equal(t, fromHex(tt.r), r)
+			assert.Equal(t, fromHex(tt.s), s)
+			assert.True(t, ecdsa.Verify(&priv.PublicKey, tt.digest, r, s))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+)
+
+// minRSABits is the smallest RSA key size that RFC 7518 allows.
+const minRSABits = 2048
+
+// CheckPrivateKey returns an error if key can't be used to sign JWTs with alg.
+// It's meant to be called once, when a key is loaded, so that a key of the
+// wrong type or size is caught at startup rather than the first time a token
+// is signed.
+//
+// key must have the type that the Sign function for alg takes:
+//
+//	alg                  key
+//	---                  ---
+//	HS256, HS512         []byte, at least as long as the hash output
+//	RS256, RS384, PS256  *rsa.PrivateKey, at least 2048 bits
+//	ES256                *ecdsa.PrivateKey on P-256
+//	ES512                *ecdsa.PrivateKey on P-521
+//	EdDSA                ed25519.PrivateKey
+//
+// The minimum sizes for HMAC and RSA keys are the ones that RFC 7518 requires.
+//
+// If alg is not one of these, CheckPrivateKey returns ErrUnsupportedAlgorithm.
+// If an RSA key is too small, CheckPrivateKey returns an error wrapping
+// ErrWeakKey, and if a secret is too short, an error wrapping ErrWeakSecret.
+// Otherwise, if key can't be used with alg, CheckPrivateKey
+// returns an error wrapping ErrInvalidKey that says why.
+//
+// https://tools.ietf.org/html/rfc7518#section-3
+func CheckPrivateKey(alg string, key interface{}) error {
+	switch alg {
+	case algHS256:
+		return checkSecret(alg, key, crypto.SHA256)
+	case algHS512:
+		return checkSecret(alg, key, crypto.SHA512)
+	case algRS256, algRS384, algPS256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("%w: %s requires an RSA private key, not %s", ErrInvalidKey, alg, keyTypeName(key))
+		}
+
+		return checkRSAKeySize(alg, &priv.PublicKey, false)
+	case algES256:
+		return checkCurve(alg, key, elliptic.P256())
+	case algES512:
+		return checkCurve(alg, key, elliptic.P521())
+	case algEdDSA:
+		if _, ok := key.(ed25519.PrivateKey); !ok {
+			return fmt.Errorf("%w: %s requires an Ed25519 private key, not %s", ErrInvalidKey, alg, keyTypeName(key))
+		}
+
+		return nil
+	}
+
+	return ErrUnsupportedAlgorithm
+}
+
+func checkSecret(alg string, key interface{}, hash crypto.Hash) error {
+	secret, ok := key.([]byte)
+	if !ok {
+		return fmt.Errorf("%w: %s requires a []byte secret, not %s", ErrInvalidKey, alg, keyTypeName(key))
+	}
+
+	return checkSecretLength(alg, secret, hash, true)
+}
+
+func checkCurve(alg string, key interface{}, curve elliptic.Curve) error {
+	priv, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("%w: %s requires an ECDSA private key, not %s", ErrInvalidKey, alg, keyTypeName(key))
+	}
+
+	return checkECDSACurve(alg, priv.Curve, curve)
+}
@@ -0,0 +1,86 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestCheckPrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	smallRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	ok := []struct {
+		alg string
+		key interface{}
+	}{
+		{"HS256", make([]byte, 32)},
+		{"HS512", make([]byte, 64)},
+		{"RS256", rsaKey},
+		{"RS384", rsaKey},
+		{"PS256", rsaKey},
+		{"ES256", p256Key},
+		{"ES512", p521Key},
+		{"EdDSA", edKey},
+	}
+
+	for _, tt := range ok {
+		assert.NoError(t, jwt.CheckPrivateKey(tt.alg, tt.key), tt.alg)
+	}
+
+	invalid := []struct {
+		alg string
+		key interface{}
+		err string
+	}{
+		{"HS256", "secret", "jwt: invalid key: HS256 requires a []byte secret, not a string"},
+		{"RS256", &rsaKey.PublicKey, "jwt: invalid key: RS256 requires an RSA private key, not an RSA public key"},
+		{"PS256", p256Key, "jwt: invalid key: PS256 requires an RSA private key, not an ECDSA private key"},
+		{"ES256", p384Key, "jwt: invalid key: ES256 requires an ECDSA key on P-256, not P-384"},
+		{"ES512", p256Key, "jwt: invalid key: ES512 requires an ECDSA key on P-521, not P-256"},
+		{"ES256", rsaKey, "jwt: invalid key: ES256 requires an ECDSA private key, not an RSA private key"},
+		{"EdDSA", edPub, "jwt: invalid key: EdDSA requires an Ed25519 private key, not an Ed25519 public key"},
+	}
+
+	for _, tt := range invalid {
+		err := jwt.CheckPrivateKey(tt.alg, tt.key)
+		assert.True(t, errors.Is(err, jwt.ErrInvalidKey), tt.err)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	err = jwt.CheckPrivateKey("RS256", smallRSAKey)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+	assert.EqualError(t, err, "jwt: key is too weak: RS256 requires an RSA key of at least 2048 bits, not 1024")
+
+	err = jwt.CheckPrivateKey("HS256", make([]byte, 31))
+	assert.True(t, errors.Is(err, jwt.ErrWeakSecret))
+	assert.EqualError(t, err, "jwt: secret is too short: HS256 requires a secret of at least 32 bytes, not 31")
+
+	err = jwt.CheckPrivateKey("HS512", make([]byte, 32))
+	assert.True(t, errors.Is(err, jwt.ErrWeakSecret))
+	assert.EqualError(t, err, "jwt: secret is too short: HS512 requires a secret of at least 64 bytes, not 32")
+
+	assert.Equal(t, jwt.ErrUnsupportedAlgorithm, jwt.CheckPrivateKey("none", rsaKey))
+}
@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMissingKeyID is the error returned when verifying a JWT by its key ID, if
+// the JWT has no "kid" header.
+var ErrMissingKeyID = errors.New("jwt: token has no key ID")
+
+// ErrAlgorithmMismatch is the error returned by VerifyWithKeySet when the "alg"
+// header of a JWT is not the algorithm registered for the key its "kid" names.
+var ErrAlgorithmMismatch = errors.New("jwt: token algorithm does not match key")
+
+// KeySet is a set of keys, each with an ID and the one algorithm it may be used
+// with. VerifyWithKeySet uses the "kid" header of a JWT to pick which key in a
+// KeySet to verify the JWT with.
+//
+// Keys are added to a KeySet as AllowedAlgorithm values, constructed with
+// AllowHS256, AllowRS256, and the other Allow functions in this package:
+//
+//	var keys jwt.KeySet
+//	keys.Add("2024-05", jwt.AllowHS256(oldSecret))
+//	keys.Add("2024-06", jwt.AllowRS256(pub))
+//
+// The zero KeySet is empty and ready to use. A KeySet is safe for concurrent
+// use, so keys can be added and removed while tokens are being verified.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]AllowedAlgorithm
+}
+
+// Add adds a key to ks with the ID kid. Tokens whose "kid" is kid will be
+// verified with a, and must use a's algorithm.
+//
+// Add returns an error wrapping ErrDuplicateKeyID if ks already has a key with
+// the ID kid, and an error wrapping ErrInvalidKey if kid is empty or a is the
+// zero AllowedAlgorithm.
+func (ks *KeySet) Add(kid string, a AllowedAlgorithm) error {
+	if kid == "" {
+		return fmt.Errorf("%w: empty key ID", ErrInvalidKey)
+	}
+
+	if a.fn == nil {
+		return fmt.Errorf("%w: key %q accepts no algorithm", ErrInvalidKey, kid)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.keys[kid]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateKeyID, kid)
+	}
+
+	if ks.keys == nil {
+		ks.keys = map[string]AllowedAlgorithm{}
+	}
+
+	ks.keys[kid] = a
+	return nil
+}
+
+// Remove removes the key with the ID kid from ks, if there is one.
+func (ks *KeySet) Remove(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	delete(ks.keys, kid)
+}
+
+// key returns the key in ks with the ID kid.
+func (ks *KeySet) key(kid string) (AllowedAlgorithm, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	a, ok := ks.keys[kid]
+	return a, ok
+}
+
+// VerifyWithKeySet verifies a JWT using the key in ks whose ID is the JWT's
+// "kid". If the JWT is verified, VerifyWithKeySet will serialize the claims
+// inside the JWT into v.
+//
+// The key decides what algorithm is used, never the token. If the "alg" header
+// of the JWT isn't the algorithm the key was added with, VerifyWithKeySet
+// returns ErrAlgorithmMismatch without checking the signature.
+//
+// VerifyWithKeySet returns ErrMissingKeyID if the JWT has no "kid", and an
+// error wrapping ErrUnknownKeyID if ks has no key with the JWT's "kid".
+// Otherwise, it returns the same errors that the Verify function for the key's
+// algorithm would.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption. Passing AllowedAlgorithm values in opts has no effect; only the
+// keys in ks are used.
+func VerifyWithKeySet(ks *KeySet, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	h, err := PeekHeader(s)
+	if err != nil {
+		return err
+	}
+
+	if h.KeyID == "" {
+		return ErrMissingKeyID
+	}
+
+	a, ok := ks.key(h.KeyID)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, h.KeyID)
+	}
+
+	if h.Algorithm != a.alg {
+		return ErrAlgorithmMismatch
+	}
+
+	c := newVerifyConfig(opts)
+	header, claims, err := verify(a.alg, s, v, opts, a.fn(&c))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
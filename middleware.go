@@ -0,0 +1,295 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNoToken is the error returned by FromRequest, FromCookie, and the other
+// TokenSources in this package, and passed to a Middleware's error handler,
+// when a request carries no token.
+var ErrNoToken = errors.New("jwt: no token in request")
+
+// VerifyFunc is the signature shared by VerifyHS256, VerifyRS256, and the
+// other Verify functions in this package, with the key already chosen. It is
+// how a Middleware verifies tokens. For example:
+//
+//	func(s []byte, v interface{}, opts ...jwt.VerifyOption) error {
+//		return jwt.VerifyPS256(pub, s, v, opts...)
+//	}
+type VerifyFunc func(s []byte, v interface{}, opts ...VerifyOption) error
+
+// MiddlewareOption configures a Middleware. WithVerifyFunc, or one of the
+// options that wraps it, is required.
+//
+// WithNow and WithClock are MiddlewareOptions too. Passing one to Middleware is
+// the same as passing it to WithVerifyOptions.
+type MiddlewareOption interface {
+	applyMiddleware(*middlewareConfig)
+}
+
+func (o NowOption) applyMiddleware(c *middlewareConfig) {
+	c.verifyOpts = append(c.verifyOpts, o)
+}
+
+// middlewareConfig is the result of applying a set of MiddlewareOption.
+type middlewareConfig struct {
+	verify     VerifyFunc
+	verifyOpts []VerifyOption
+	claims     func() interface{}
+	onError    func(w http.ResponseWriter, r *http.Request, err error)
+	cookie     string
+	requireTLS bool
+}
+
+// middlewareOptionFunc adapts a function into a MiddlewareOption.
+type middlewareOptionFunc func(*middlewareConfig)
+
+func (f middlewareOptionFunc) applyMiddleware(c *middlewareConfig) {
+	f(c)
+}
+
+// WithVerifyFunc makes a Middleware verify tokens using fn. The options set by
+// WithVerifyOptions are passed along to fn.
+func WithVerifyFunc(fn VerifyFunc) MiddlewareOption {
+	return middlewareOptionFunc(func(c *middlewareConfig) {
+		c.verify = fn
+	})
+}
+
+// WithHS256Secret makes a Middleware verify tokens using VerifyHS256 and
+// secret.
+func WithHS256Secret(secret []byte) MiddlewareOption {
+	return WithVerifyFunc(func(s []byte, v interface{}, opts ...VerifyOption) error {
+		return VerifyHS256(secret, s, v, opts...)
+	})
+}
+
+// WithRS256Key makes a Middleware verify tokens using VerifyRS256 and pub.
+func WithRS256Key(pub *rsa.PublicKey) MiddlewareOption {
+	return WithVerifyFunc(func(s []byte, v interface{}, opts ...VerifyOption) error {
+		return VerifyRS256(pub, s, v, opts...)
+	})
+}
+
+// WithES256Key makes a Middleware verify tokens using VerifyES256 and pub.
+func WithES256Key(pub *ecdsa.PublicKey) MiddlewareOption {
+	return WithVerifyFunc(func(s []byte, v interface{}, opts ...VerifyOption) error {
+		return VerifyES256(pub, s, v, opts...)
+	})
+}
+
+// WithVerifyOptions makes a Middleware pass opts to its VerifyFunc. This is
+// how to have a Middleware check the issuer and audience of tokens, using
+// WithExpectedIssuer and WithExpectedAudience, or how much clock skew to
+// allow, using WithLeeway.
+//
+// A Middleware always checks "exp" and "nbf", as if WithLeeway(0) came before
+// opts.
+func WithVerifyOptions(opts ...VerifyOption) MiddlewareOption {
+	return middlewareOptionFunc(func(c *middlewareConfig) {
+		c.verifyOpts = append(c.verifyOpts, opts...)
+	})
+}
+
+// WithClaims makes a Middleware verify each token into a new value returned by
+// factory, instead of into a new *StandardClaims. factory must return a
+// non-nil pointer, as the Verify functions require. The pointer is what
+// ClaimsFromContext returns.
+func WithClaims(factory func() interface{}) MiddlewareOption {
+	return middlewareOptionFunc(func(c *middlewareConfig) {
+		c.claims = factory
+	})
+}
+
+// WithErrorHandler makes a Middleware call fn, instead of responding as
+// RFC6750 describes, when a request has no token or its token is rejected. err
+// is ErrNoToken if the request had no token, and otherwise is the error from
+// verifying the token. Use it to, for instance, respond with a JSON body.
+//
+// err says exactly why a token was rejected, such as whether its signature was
+// invalid or it had expired. Be careful about sending it to the client.
+// BearerError says what status code and RFC6750 error code go with err.
+func WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) MiddlewareOption {
+	return middlewareOptionFunc(func(c *middlewareConfig) {
+		c.onError = fn
+	})
+}
+
+// WithCookie makes a Middleware look for a token in the cookie called name
+// when a request has no bearer token in its Authorization header. Use it when
+// the token is set as a cookie by your server, as is common for browser apps.
+// The cookie is read using FromCookie.
+//
+// If requireTLS is true, the cookie is only looked at in requests that were
+// made over TLS. Cookies sent over plain HTTP can be read and replayed by
+// anyone on the network, and a server that accepts them there encourages
+// setting cookies without the Secure attribute. Only set requireTLS to false if
+// TLS is terminated before requests reach your server, such as by a load
+// balancer, and then make sure the cookie is set with Secure and HttpOnly.
+func WithCookie(name string, requireTLS bool) MiddlewareOption {
+	return middlewareOptionFunc(func(c *middlewareConfig) {
+		c.cookie = name
+		c.requireTLS = requireTLS
+	})
+}
+
+// Middleware returns a handler that verifies the bearer token in the
+// Authorization header of each request before calling next. Configure it
+// using opts. Middleware panics unless opts include WithVerifyFunc, or one of
+// the options that wraps it.
+//
+// If WithCookie is among opts, requests without a bearer token may carry the
+// token in a cookie instead.
+//
+// If the token is verified, the claims in it are added to the request's
+// context, where next can get them using ClaimsFromContext. So is the token
+// itself, which next can get using RawTokenFromContext. By default, the
+// claims are a *StandardClaims; use WithClaims to change that. "exp" and "nbf"
+// are always checked.
+//
+// If a request has no token, or its token is rejected, next is not called.
+// Instead, the Middleware responds with the status code and WWW-Authenticate
+// header that RFC6750 describes, as chosen by BearerError: 403 Forbidden if the
+// token lacks a scope required by WithRequiredScopes, and 401 Unauthorized
+// otherwise. The response doesn't say anything more about why the token was
+// rejected, so that clients can't use it to learn, for instance, whether a
+// forged token would have been accepted if it weren't expired. Use
+// WithErrorHandler to respond differently.
+//
+// https://tools.ietf.org/html/rfc6750#section-3
+func Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	c := middlewareConfig{
+		verifyOpts: []VerifyOption{WithLeeway(0)},
+		claims:     func() interface{} { return &StandardClaims{} },
+		onError:    unauthorized,
+	}
+
+	for _, opt := range opts {
+		opt.applyMiddleware(&c)
+	}
+
+	if c.verify == nil {
+		panic("jwt: Middleware requires WithVerifyFunc")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := c.token(r)
+		if err != nil {
+			c.onError(w, r, err)
+			return
+		}
+
+		claims := c.claims()
+		if err := c.verify(token, claims, c.verifyOpts...); err != nil {
+			c.onError(w, r, err)
+			return
+		}
+
+		ctx := NewRawTokenContext(NewContext(r.Context(), claims), token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// token returns the token in r, looking first in its Authorization header and
+// then, if c says to, in a cookie.
+func (c *middlewareConfig) token(r *http.Request) ([]byte, error) {
+	if token, ok := bearerToken(r); ok {
+		return token, nil
+	}
+
+	if c.cookie == "" || (c.requireTLS && r.TLS == nil) {
+		return nil, ErrNoToken
+	}
+
+	return FromCookie(r, c.cookie)
+}
+
+// FromCookie returns the token in the cookie of r called name. The cookie's
+// value may be URL-encoded; it is decoded before it is returned.
+//
+// FromCookie returns ErrNoToken if r has no such cookie, or if the cookie is
+// empty. It returns an error wrapping ErrMalformedToken if the cookie's value
+// is not validly URL-encoded. It does not otherwise check that the cookie
+// contains a JWT; that is up to the Verify function you pass the token to.
+func FromCookie(r *http.Request, name string) ([]byte, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, ErrNoToken
+	}
+
+	value, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cookie %q is not URL-encoded: %v", ErrMalformedToken, name, err)
+	}
+
+	if value == "" {
+		return nil, ErrNoToken
+	}
+
+	return []byte(value), nil
+}
+
+// bearerToken returns the token in the Authorization header of r, if it has
+// one. The "Bearer" scheme is case-insensitive.
+func bearerToken(r *http.Request) ([]byte, bool) {
+	const prefix = "Bearer "
+
+	h := r.Header.Get("Authorization")
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return nil, false
+	}
+
+	token := strings.TrimSpace(h[len(prefix):])
+	if token == "" {
+		return nil, false
+	}
+
+	return []byte(token), true
+}
+
+// BearerError returns the HTTP status code and RFC6750 error code that a
+// server should respond with when it rejects a request because of err, an
+// error from a TokenSource or a Verify function:
+//
+//   - An error wrapping ErrNoToken is 401 Unauthorized, with no error code,
+//     since RFC6750 says a request without credentials shouldn't be told more
+//     than that it needs them.
+//   - An error wrapping ErrMultipleTokens is 400 Bad Request, with
+//     "invalid_request".
+//   - An error wrapping ErrInsufficientScope is 403 Forbidden, with
+//     "insufficient_scope".
+//   - Any other error, such as ErrInvalidSignature or ErrExpiredToken, is 401
+//     Unauthorized, with "invalid_token".
+//
+// https://tools.ietf.org/html/rfc6750#section-3.1
+func BearerError(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, ErrNoToken):
+		return http.StatusUnauthorized, ""
+	case errors.Is(err, ErrMultipleTokens):
+		return http.StatusBadRequest, "invalid_request"
+	case errors.Is(err, ErrInsufficientScope):
+		return http.StatusForbidden, "insufficient_scope"
+	}
+
+	return http.StatusUnauthorized, "invalid_token"
+}
+
+// unauthorized is the default error handler of a Middleware.
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := BearerError(err)
+
+	challenge := "Bearer"
+	if code != "" {
+		challenge = fmt.Sprintf("Bearer error=%q", code)
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,180 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errNoClaims is the error returned by SignNested and SignDetachedHS256 if
+// they are given options that add claims to a token or check its claims. The
+// outer JWT of a nested JWT has no claims of its own, only the inner JWT, and
+// the payload of a detached JWS can be anything at all.
+var errNoClaims = errors.New("jwt: token has no claims to add to or check")
+
+// checkNoClaims returns errNoClaims if c has any options that add or check
+// claims.
+func (c *signConfig) checkNoClaims() error {
+	if len(c.policies) != 0 || c.issuedAtNow || c.randomID {
+		return errNoClaims
+	}
+
+	return nil
+}
+
+// errNestedIgnored is the error returned by SignNested if the outer Signer
+// produces a JWT whose payload isn't the inner JWT, as happens with a Signer
+// that doesn't honor the options it's given.
+var errNestedIgnored = errors.New("jwt: signer did not produce a nested JWT")
+
+// SignNested takes a signed JWT, and returns a nested JWT: a JWT whose payload
+// is inner, signed by outer. VerifyNested can verify tokens signed by
+// SignNested.
+//
+// Nesting lets one party vouch for a JWT that another party issued, while
+// keeping the original JWT intact. Services that receive the nested JWT need
+// only trust the outer key, and the inner JWT can still be verified, or
+// logged, exactly as its issuer signed it.
+//
+// inner is used as the payload verbatim; it is not decoded or re-encoded, and
+// its signature is not checked. SignNested only checks that inner has the form
+// of a JWT, and returns an error wrapping ErrMalformedToken if it doesn't. The
+// outer JWT has a "cty" header parameter of "JWT", as RFC7519 requires.
+//
+// outer is usually one of the Sign functions in this package, wrapped in a
+// SignerFunc:
+//
+//	token, err := jwt.SignNested(jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+//		return jwt.SignES256(priv, v, opts...)
+//	}), partnerToken)
+//
+// opts are passed on to outer. Options that set header parameters, such as
+// WithKeyID, apply to the outer JWT. Options that add or check claims, such
+// as WithIssuedAtNow or WithSignPolicy, make SignNested return an error,
+// because the outer JWT has no claims. So does using WithHeaderParams to set
+// "cty".
+//
+// The errors SignNested returns say which of the two JWTs was at fault, as
+// VerifyNested's do. outer must honor the opts it is given, as Signer
+// requires; if the JWT it returns doesn't contain inner, SignNested returns
+// an error.
+//
+// https://tools.ietf.org/html/rfc7519#section-5.2
+func SignNested(outer Signer, inner []byte, opts ...SignOption) ([]byte, error) {
+	if _, _, _, err := Split(inner); err != nil {
+		return nil, fmt.Errorf("jwt: inner token: %w", err)
+	}
+
+	nested := signOptionFunc(func(c *signConfig) {
+		c.nestedPayload = inner
+	})
+
+	s, err := outer.Sign(nil, append(opts[:len(opts):len(opts)], nested)...)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: outer token: %w", err)
+	}
+
+	if !isNested(s, inner) {
+		return nil, fmt.Errorf("jwt: outer token: %w", errNestedIgnored)
+	}
+
+	return s, nil
+}
+
+// isNested returns whether the JWT s has a "cty" of "JWT", and inner as its
+// payload.
+func isNested(s, inner []byte) bool {
+	h, payload, _, err := Split(s)
+	if err != nil {
+		return false
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(string(h))
+	if err != nil {
+		return false
+	}
+
+	var cty struct {
+		ContentType string `json:"cty"`
+	}
+
+	if err := json.Unmarshal(header, &cty); err != nil || cty.ContentType != headerTypeJWT {
+		return false
+	}
+
+	return base64.RawURLEncoding.EncodeToString(inner) == string(payload)
+}
+
+// ErrNotNested is the error returned by VerifyNested when the outer JWT is
+// validly signed, but its "cty" header parameter does not say that it contains
+// another JWT.
+var ErrNotNested = errors.New("jwt: token is not a nested JWT")
+
+// VerifyNested verifies a nested JWT: a JWT whose payload is itself a signed
+// JWT, rather than a set of claims, such as one produced by SignNested. If both
+// JWTs are verified, VerifyNested will serialize the claims inside the inner
+// JWT into v.
+//
+// outer is the algorithm and key that the outer JWT must be signed with, and
+// inner is the algorithm and key that the inner JWT must be signed with. As
+// with VerifyAny, the JWTs can't choose their own algorithms or keys. The two
+// may use different algorithms.
+//
+// The outer JWT must have a "cty" header parameter of "JWT", as RFC7519
+// requires. If it doesn't, VerifyNested returns ErrNotNested.
+//
+// opts apply to the inner JWT, except that WithCriticalHeaders and
+// WithUntrustedKeyHeaders apply to both. For instance, WithHeader gets the
+// header of the inner JWT, and the Validate method of v, if any, is called on
+// the claims of the inner JWT.
+//
+// The errors VerifyNested returns say which of the two JWTs was rejected. Use
+// errors.Is to check them:
+//
+//	err := jwt.VerifyNested(token, &claims, jwt.AllowRS256(outerKey), jwt.AllowRS256(innerKey))
+//	if errors.Is(err, jwt.ErrInvalidSignature) {
+//		// one of the two JWTs had an invalid signature
+//	}
+//
+// https://tools.ietf.org/html/rfc7519#section-5.2
+func VerifyNested(s []byte, v interface{}, outer, inner AllowedAlgorithm, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	c := newVerifyConfig(opts)
+
+	_, header, payload, err := verifySelectBuf(s, nil, true, c.lenientBase64, selectAllowed(&c, []AllowedAlgorithm{outer}))
+	if err != nil {
+		return fmt.Errorf("jwt: outer token: %w", err)
+	}
+
+	if err := c.checkCritical(header); err != nil {
+		return fmt.Errorf("jwt: outer token: %w", err)
+	}
+
+	if err := c.checkKeyHeaders(header); err != nil {
+		return fmt.Errorf("jwt: outer token: %w", err)
+	}
+
+	var cty struct {
+		ContentType string `json:"cty"`
+	}
+
+	if err := json.Unmarshal(header, &cty); err != nil || !strings.EqualFold(trimMediaType(cty.ContentType), "JWT") {
+		return fmt.Errorf("jwt: outer token: %w", ErrNotNested)
+	}
+
+	_, header, claims, err := verifySelect(payload, v, opts, selectAllowed(&c, []AllowedAlgorithm{inner}))
+	if err != nil {
+		return fmt.Errorf("jwt: inner token: %w", err)
+	}
+
+	if err := unmarshalClaims(header, claims, v, opts); err != nil {
+		return fmt.Errorf("jwt: inner token: %w", err)
+	}
+
+	return nil
+}
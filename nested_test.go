@@ -0,0 +1,196 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestVerifyNested(t *testing.T) {
+	outerSecret := []byte("outer secret")
+	innerSecret := []byte("inner secret")
+
+	inner, err := jwt.SignHS256(innerSecret, jwt.StandardClaims{Subject: "jdoe@example.com"}, jwt.WithKeyID("inner"))
+	assert.NoError(t, err)
+
+	outer := jwt.AllowHS256(outerSecret)
+	innerAllowed := jwt.AllowHS256(innerSecret)
+
+	t.Run("valid", func(t *testing.T) {
+		for _, cty := range []string{"JWT", "jwt", "application/jwt"} {
+			token := forgeToken(`{"alg":"HS256","cty":"`+cty+`"}`, string(inner), hmacSHA256(outerSecret))
+
+			var header jwt.Header
+			var claims jwt.StandardClaims
+			assert.NoError(t, jwt.VerifyNested(token, &claims, outer, innerAllowed, jwt.WithHeader(&header)), cty)
+			assert.Equal(t, "jdoe@example.com", claims.Subject, cty)
+			assert.Equal(t, "inner", header.KeyID, cty)
+		}
+	})
+
+	t.Run("outer signature invalid", func(t *testing.T) {
+		token := forgeToken(`{"alg":"HS256","cty":"JWT"}`, string(inner), hmacSHA256([]byte("other")))
+
+		var claims jwt.StandardClaims
+		err := jwt.VerifyNested(token, &claims, outer, innerAllowed)
+		assert.True(t, errors.Is(err, jwt.ErrInvalidSignature))
+		assert.True(t, errors.Is(err, jwt.ErrBadSignature))
+		assert.EqualError(t, err, "jwt: outer token: jwt: bad signature")
+	})
+
+	t.Run("inner signature invalid", func(t *testing.T) {
+		forged := forgeToken(`{"alg":"HS256"}`, `{"sub":"jdoe@example.com"}`, hmacSHA256([]byte("other")))
+		token := forgeToken(`{"alg":"HS256","cty":"JWT"}`, string(forged), hmacSHA256(outerSecret))
+
+		var claims jwt.StandardClaims
+		err := jwt.VerifyNested(token, &claims, outer, innerAllowed)
+		assert.True(t, errors.Is(err, jwt.ErrInvalidSignature))
+		assert.True(t, errors.Is(err, jwt.ErrBadSignature))
+		assert.EqualError(t, err, "jwt: inner token: jwt: bad signature")
+		assert.Equal(t, jwt.StandardClaims{}, claims)
+	})
+
+	t.Run("inner and outer keys are not interchangeable", func(t *testing.T) {
+		token := forgeToken(`{"alg":"HS256","cty":"JWT"}`, string(inner), hmacSHA256(outerSecret))
+
+		var claims jwt.StandardClaims
+		err := jwt.VerifyNested(token, &claims, innerAllowed, outer)
+		assert.EqualError(t, err, "jwt: outer token: jwt: bad signature")
+	})
+
+	t.Run("missing or wrong cty", func(t *testing.T) {
+		for _, header := range []string{`{"alg":"HS256"}`, `{"alg":"HS256","cty":"json"}`, `{"alg":"HS256","cty":1}`} {
+			token := forgeToken(header, string(inner), hmacSHA256(outerSecret))
+
+			var claims jwt.StandardClaims
+			err := jwt.VerifyNested(token, &claims, outer, innerAllowed)
+			assert.True(t, errors.Is(err, jwt.ErrNotNested), header)
+		}
+	})
+
+	t.Run("payload is not a JWT", func(t *testing.T) {
+		token := forgeToken(`{"alg":"HS256","cty":"JWT"}`, `{"sub":"jdoe@example.com"}`, hmacSHA256(outerSecret))
+
+		var claims jwt.StandardClaims
+		err := jwt.VerifyNested(token, &claims, outer, innerAllowed)
+		assert.EqualError(t, err, "jwt: inner token: jwt: malformed token")
+	})
+
+	t.Run("inner claims rejected", func(t *testing.T) {
+		token := forgeToken(`{"alg":"HS256","cty":"JWT"}`, string(inner), hmacSHA256(outerSecret))
+
+		var claims tenantClaims
+		err := jwt.VerifyNested(token, &claims, outer, innerAllowed)
+		assert.True(t, errors.Is(err, jwt.ErrClaimsRejected))
+		assert.True(t, errors.Is(err, errMissingTenant))
+	})
+}
+
+func TestSignNested(t *testing.T) {
+	partnerKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ourKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	es256 := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+		return jwt.SignES256(ourKey, v, opts...)
+	})
+
+	inner, err := jwt.SignRS256(partnerKey, jwt.StandardClaims{Subject: "jdoe@example.com"}, jwt.WithKeyID("partner"))
+	assert.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		token, err := jwt.SignNested(es256, inner, jwt.WithKeyID("ours"))
+		assert.NoError(t, err)
+
+		h, payload, _, err := jwt.Split(token)
+		assert.NoError(t, err)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(inner), string(payload))
+
+		header, err := base64.RawURLEncoding.DecodeString(string(h))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"typ":"JWT","alg":"ES256","kid":"ours","cty":"JWT"}`, string(header))
+
+		var innerHeader jwt.Header
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyNested(token, &claims, jwt.AllowES256(&ourKey.PublicKey), jwt.AllowRS256(&partnerKey.PublicKey), jwt.WithHeader(&innerHeader)))
+		assert.Equal(t, "jdoe@example.com", claims.Subject)
+		assert.Equal(t, "partner", innerHeader.KeyID)
+
+		// The algorithms of the two layers can't be swapped.
+		err = jwt.VerifyNested(token, &claims, jwt.AllowRS256(&partnerKey.PublicKey), jwt.AllowES256(&ourKey.PublicKey))
+		assert.EqualError(t, err, "jwt: outer token: jwt: wrong algorithm")
+	})
+
+	t.Run("inner signature invalid", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+
+		forged, err := jwt.SignRS256(otherKey, jwt.StandardClaims{Subject: "admin"})
+		assert.NoError(t, err)
+
+		// SignNested doesn't check the inner signature; that's up to verifiers.
+		token, err := jwt.SignNested(es256, forged)
+		assert.NoError(t, err)
+
+		var claims jwt.StandardClaims
+		err = jwt.VerifyNested(token, &claims, jwt.AllowES256(&ourKey.PublicKey), jwt.AllowRS256(&partnerKey.PublicKey))
+		assert.EqualError(t, err, "jwt: inner token: jwt: bad signature")
+		assert.Equal(t, jwt.StandardClaims{}, claims)
+	})
+
+	t.Run("inner token malformed", func(t *testing.T) {
+		for _, inner := range []string{"", "not a jwt", `{"sub":"jdoe@example.com"}`, "a.b", "a.b.c.d", "a=.b.c"} {
+			_, err := jwt.SignNested(es256, []byte(inner))
+			assert.True(t, errors.Is(err, jwt.ErrMalformedToken), inner)
+			assert.Contains(t, err.Error(), "jwt: inner token: ", inner)
+		}
+	})
+
+	t.Run("options that add or check claims", func(t *testing.T) {
+		for _, opt := range []jwt.SignOption{
+			jwt.WithIssuedAtNow(),
+			jwt.WithRandomID(jwt.IDUUID),
+			jwt.WithSignPolicy(jwt.SignPolicy{MaxTTL: time.Hour}),
+		} {
+			_, err := jwt.SignNested(es256, inner, opt)
+			assert.EqualError(t, err, "jwt: outer token: jwt: token has no claims to add to or check")
+		}
+	})
+
+	t.Run("header params", func(t *testing.T) {
+		token, err := jwt.SignNested(jwt.NewHS256Signer([]byte("secret")), inner, jwt.WithHeaderParams(map[string]interface{}{"x": 1}))
+		assert.NoError(t, err)
+
+		var header jwt.Header
+		assert.NoError(t, jwt.VerifyHS256([]byte("secret"), token, nil, jwt.WithHeader(&header)))
+		assert.Equal(t, map[string]interface{}{"cty": "JWT", "x": 1.0}, header.Extra)
+
+		for _, name := range []string{"cty", "CTY"} {
+			_, err := jwt.SignNested(es256, inner, jwt.WithHeaderParams(map[string]interface{}{name: "json"}))
+			assert.True(t, errors.Is(err, jwt.ErrReservedHeaderParam), name)
+		}
+
+		// Outside of SignNested, "cty" can be set like any other parameter.
+		_, err = jwt.SignHS256([]byte("secret"), nil, jwt.WithHeaderParams(map[string]interface{}{"cty": "json"}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("signer ignores options", func(t *testing.T) {
+		ignores := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+			return jwt.SignES256(ourKey, v)
+		})
+
+		_, err := jwt.SignNested(ignores, inner)
+		assert.EqualError(t, err, "jwt: outer token: jwt: signer did not produce a nested JWT")
+	})
+}
@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// WithJSONNumber makes a Verify function decode numbers in the claims of JWTs
+// as json.Number, rather than float64, wherever it decodes them into an
+// interface{}, such as the values of a map[string]interface{}. It is like
+// calling UseNumber on a json.Decoder.
+//
+// A float64 holds integers exactly only up to 2^53, and claims can hold
+// larger ones, such as IDs that are 64-bit integers. With WithJSONNumber, such
+// a claim is kept exactly as it was written in the token. Use ClaimInt64 and
+// ClaimTime to convert it.
+//
+// WithJSONNumber doesn't change how numbers are decoded into fields that have
+// a numeric type, such as those of StandardClaims, or how this package checks
+// claims like "exp" itself. It has no effect if SetJSONCodec has been given an
+// unmarshal function.
+func WithJSONNumber() VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.useNumber = true
+	})
+}
+
+// ClaimInt64 returns the value of a claim, as decoded into an interface{}, as
+// an int64. v may be a json.Number, as decoded with WithJSONNumber, or a
+// float64, as decoded without it.
+//
+// ClaimInt64 returns an error if v is not a whole number, or if it does not
+// fit in an int64. A json.Number must be written as an integer, without a
+// fractional part or an exponent. A float64 must be no larger than 2^53,
+// because a float64 larger than that may not be exactly the number that was in
+// the token.
+func ClaimInt64(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("jwt: claim is not an int64: %s", v)
+		}
+
+		return n, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("jwt: claim is not an integer: %v", v)
+		}
+
+		if math.Abs(v) > 1<<53 {
+			return 0, fmt.Errorf("jwt: claim is too large to be exact as a float64: %v", v)
+		}
+
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("jwt: claim is not a number: %T", v)
+	}
+}
+
+// ClaimTime returns the value of a claim, as decoded into an interface{}, as a
+// time.Time. The claim must be a NumericDate, like "exp", "nbf", and "iat": a
+// number of seconds since the Unix epoch. As with ClaimInt64, v may be a
+// json.Number or a float64.
+//
+// Fractional seconds are kept. A json.Number keeps them exactly, to the
+// nanosecond; a float64 keeps them only as precisely as it can hold them.
+//
+// ClaimTime returns an error if v is not a finite number, or if its whole
+// seconds don't fit in an int64.
+func ClaimTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case json.Number:
+		t, err := parseNumericDate(string(v))
+		if err == errNumericDateRange {
+			return time.Time{}, fmt.Errorf("jwt: claim is out of range for a timestamp: %s", v)
+		} else if err != nil {
+			return time.Time{}, fmt.Errorf("jwt: claim is not a timestamp: %s", v)
+		}
+
+		return t, nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return time.Time{}, fmt.Errorf("jwt: claim is not a timestamp: %v", v)
+		}
+
+		t, ok := numericDateToTime(v)
+		if !ok {
+			return time.Time{}, fmt.Errorf("jwt: claim is out of range for a timestamp: %v", v)
+		}
+
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("jwt: claim is not a number: %T", v)
+	}
+}
@@ -0,0 +1,149 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestWithJSONNumber(t *testing.T) {
+	if os.Getenv(fakeCodecEnv) != "" {
+		t.Skip("the codec decides how numbers are decoded")
+	}
+
+	secret := []byte("my secret key")
+	token := []byte(signJSON(t, secret, `{"sub":"jdoe","exp":1300819380,"big":9223372036854775807,"unsafe":9007199254740993,"ratio":0.25,"nested":{"ids":[9223372036854775807]}}`))
+
+	// Without WithJSONNumber, numbers become float64, and lose precision.
+	var claims map[string]interface{}
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &claims))
+	assert.Equal(t, 1300819380.0, claims["exp"])
+	assert.Equal(t, float64(math.MaxInt64), claims["big"])
+	assert.Equal(t, 9007199254740992.0, claims["unsafe"])
+
+	claims = nil
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &claims, jwt.WithJSONNumber()))
+	assert.Equal(t, map[string]interface{}{
+		"sub":    "jdoe",
+		"exp":    json.Number("1300819380"),
+		"big":    json.Number("9223372036854775807"),
+		"unsafe": json.Number("9007199254740993"),
+		"ratio":  json.Number("0.25"),
+		"nested": map[string]interface{}{"ids": []interface{}{json.Number("9223372036854775807")}},
+	}, claims)
+
+	big, err := jwt.ClaimInt64(claims["big"])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), big)
+
+	exp, err := jwt.ClaimTime(claims["exp"])
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1300819380, 0), exp)
+
+	// Fields with a numeric type are decoded as they always are, and
+	// WithJSONNumber works alongside the options that check claims.
+	var std jwt.StandardClaims
+	var decoded interface{}
+	now := func() time.Time { return time.Unix(1300819370, 0) }
+	assert.NoError(t, jwt.VerifyHS256(secret, token, jwt.Into(&std, &decoded), jwt.WithJSONNumber(), jwt.WithNow(now)))
+	assert.Equal(t, int64(1300819380), std.ExpirationTime)
+	assert.Equal(t, json.Number("9223372036854775807"), decoded.(map[string]interface{})["big"])
+
+	alg, err := jwt.VerifyAny(token, &decoded, jwt.AllowHS256(secret), jwt.WithJSONNumber())
+	assert.NoError(t, err)
+	assert.Equal(t, "HS256", alg)
+	assert.Equal(t, json.Number("9007199254740993"), decoded.(map[string]interface{})["unsafe"])
+
+	assert.Equal(t, jwt.ErrExpiredToken, jwt.VerifyHS256(secret, token, &claims, jwt.WithJSONNumber(), jwt.WithLeeway(0)))
+}
+
+// signJSON signs claims, which must be JSON, with secret using HS256.
+func signJSON(t *testing.T, secret []byte, claims string) string {
+	token, err := jwt.SignHS256(secret, json.RawMessage(claims))
+	assert.NoError(t, err)
+	return string(token)
+}
+
+func TestClaimInt64(t *testing.T) {
+	testCases := []struct {
+		in  interface{}
+		out int64
+		err string
+	}{
+		{json.Number("0"), 0, ""},
+		{json.Number("-42"), -42, ""},
+		{json.Number("9223372036854775807"), math.MaxInt64, ""},
+		{json.Number("-9223372036854775808"), math.MinInt64, ""},
+		{json.Number("9223372036854775808"), 0, "jwt: claim is not an int64: 9223372036854775808"},
+		{json.Number("1.5"), 0, "jwt: claim is not an int64: 1.5"},
+		{json.Number("1e3"), 0, "jwt: claim is not an int64: 1e3"},
+		{1300819380.0, 1300819380, ""},
+		{-1.0, -1, ""},
+		{float64(1 << 53), 1 << 53, ""},
+		{float64(1<<53 + 2), 0, "jwt: claim is too large to be exact as a float64: 9.007199254740994e+15"},
+		{1.5, 0, "jwt: claim is not an integer: 1.5"},
+		{math.Inf(1), 0, "jwt: claim is too large to be exact as a float64: +Inf"},
+		{math.NaN(), 0, "jwt: claim is not an integer: NaN"},
+		{"1", 0, "jwt: claim is not a number: string"},
+		{nil, 0, "jwt: claim is not a number: <nil>"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(fmt.Sprint(tt.in), func(t *testing.T) {
+			out, err := jwt.ClaimInt64(tt.in)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.out, out)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestClaimTime(t *testing.T) {
+	testCases := []struct {
+		in  interface{}
+		out time.Time
+		err string
+	}{
+		{json.Number("1300819380"), time.Unix(1300819380, 0), ""},
+		{json.Number("1300819380.000000001"), time.Unix(1300819380, 1), ""},
+		{json.Number("-1"), time.Unix(-1, 0), ""},
+		{json.Number("1.30081938e9"), time.Unix(1300819380, 0), ""},
+		{json.Number("tomorrow"), time.Time{}, "jwt: claim is not a timestamp: tomorrow"},
+		{1300819380.0, time.Unix(1300819380, 0), ""},
+		{1300819380.5, time.Unix(1300819380, 5e8), ""},
+		{math.Inf(-1), time.Time{}, "jwt: claim is not a timestamp: -Inf"},
+		{math.NaN(), time.Time{}, "jwt: claim is not a timestamp: NaN"},
+		{1e19, time.Time{}, "jwt: claim is out of range for a timestamp: 1e+19"},
+		{-1e19, time.Time{}, "jwt: claim is out of range for a timestamp: -1e+19"},
+		{1e300, time.Time{}, "jwt: claim is out of range for a timestamp: 1e+300"},
+		{float64(1 << 63), time.Time{}, "jwt: claim is out of range for a timestamp: 9.223372036854776e+18"},
+		{-float64(1 << 63), time.Unix(math.MinInt64, 0), ""},
+		{json.Number("1e19"), time.Time{}, "jwt: claim is out of range for a timestamp: 1e19"},
+		{json.Number("-1e19"), time.Time{}, "jwt: claim is out of range for a timestamp: -1e19"},
+		{json.Number("1e400"), time.Time{}, "jwt: claim is out of range for a timestamp: 1e400"},
+		{json.Number("10000000000000000000"), time.Time{}, "jwt: claim is out of range for a timestamp: 10000000000000000000"},
+		{json.Number("-10000000000000000000"), time.Time{}, "jwt: claim is out of range for a timestamp: -10000000000000000000"},
+		{"1300819380", time.Time{}, "jwt: claim is not a number: string"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(fmt.Sprint(tt.in), func(t *testing.T) {
+			out, err := jwt.ClaimTime(tt.in)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				assert.True(t, tt.out.Equal(out), "%v != %v", tt.out, out)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
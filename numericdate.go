@@ -0,0 +1,153 @@
+package jwt
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NumericDate is a timestamp, as used in the "exp", "nbf", and "iat" claims.
+//
+// In JSON, NumericDate is represented as the number of seconds since the Unix
+// epoch, as described in RFC7519. Unlike a plain int64, NumericDate can't be
+// mistakenly populated with milliseconds or nanoseconds: you construct it from
+// a time.Time, and it takes care of the conversion.
+//
+// https://tools.ietf.org/html/rfc7519#section-2
+type NumericDate struct {
+	t time.Time
+}
+
+// NumericDateFromTime returns a NumericDate for t, truncated to a whole number
+// of seconds.
+//
+// Timestamps are truncated because, though RFC7519 permits fractional
+// timestamps, many JWT implementations can only read whole numbers.
+func NumericDateFromTime(t time.Time) *NumericDate {
+	return &NumericDate{t: t.Truncate(time.Second)}
+}
+
+// Time returns n as a time.Time.
+func (n NumericDate) Time() time.Time {
+	return n.t
+}
+
+// MarshalJSON implements json.Marshaler. n is encoded as a whole number of
+// seconds, unless it has a fractional part, in which case as many decimal
+// places as are needed are used.
+func (n NumericDate) MarshalJSON() ([]byte, error) {
+	secs := n.t.Unix()
+	nanos := n.t.Nanosecond()
+
+	out := strconv.AppendInt(nil, secs, 10)
+	if nanos == 0 {
+		return out, nil
+	}
+
+	// Unix rounds toward negative infinity, so nanos is always positive. That
+	// doesn't work for negative timestamps with a fractional part, which are
+	// exceedingly rare; fall back to floats for those.
+	if secs < 0 {
+		return strconv.AppendFloat(nil, float64(n.t.UnixNano())/1e9, 'f', -1, 64), nil
+	}
+
+	frac := strconv.AppendInt(nil, int64(nanos)+1e9, 10)[1:] // zero-padded to 9 digits
+	out = append(out, '.')
+	out = append(out, bytes.TrimRight(frac, "0")...)
+	return out, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts any JSON number,
+// including ones with a fractional part. Fractional parts are kept, to
+// nanosecond precision. As is conventional, a JSON null leaves n unchanged.
+func (n *NumericDate) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+
+	t, err := parseNumericDate(string(data))
+	if err != nil {
+		return err
+	}
+
+	n.t = t
+	return nil
+}
+
+// errInvalidNumericDate is returned when a NumericDate is not a JSON number.
+var errInvalidNumericDate = errors.New("jwt: timestamp is not a number")
+
+// errNumericDateRange is returned when a NumericDate is a JSON number, but is
+// too large in magnitude for its whole seconds to fit in an int64.
+var errNumericDateRange = errors.New("jwt: timestamp is out of range")
+
+// parseNumericDate parses s, a JSON number, as a number of seconds since the
+// Unix epoch.
+func parseNumericDate(s string) (time.Time, error) {
+	// Numbers of the form 123 or 123.456, which are by far the most common, are
+	// parsed exactly, to the nanosecond.
+	digits := strings.TrimPrefix(s, "-")
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i != -1 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+		if fracPart == "" {
+			return time.Time{}, errInvalidNumericDate
+		}
+	}
+
+	if isDigits(intPart) && isDigits(fracPart) && len(intPart) > 0 {
+		// intPart is all digits, so the only error ParseInt can return is
+		// that it's out of range.
+		secs, err := strconv.ParseInt(intPart, 10, 64)
+		if err != nil {
+			return time.Time{}, errNumericDateRange
+		}
+
+		if len(fracPart) > 9 {
+			fracPart = fracPart[:9]
+		}
+
+		var nanos int64
+		if fracPart != "" {
+			nanos, _ = strconv.ParseInt(fracPart+strings.Repeat("0", 9-len(fracPart)), 10, 64)
+		}
+
+		if len(digits) != len(s) {
+			secs, nanos = -secs, -nanos
+		}
+
+		return time.Unix(secs, nanos), nil
+	}
+
+	// Anything else that is still a JSON number, such as numbers with an
+	// exponent, goes through float64.
+	if intPart == "" || !isDigits(intPart[:1]) {
+		return time.Time{}, errInvalidNumericDate
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return time.Time{}, errInvalidNumericDate
+	}
+
+	t, ok := numericDateToTime(f)
+	if !ok {
+		return time.Time{}, errNumericDateRange
+	}
+
+	return t, nil
+}
+
+// isDigits returns whether s consists only of ASCII digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,65 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestNumericDateFromTime(t *testing.T) {
+	n := jwt.NumericDateFromTime(time.Unix(1300819380, 999999999))
+	assert.Equal(t, time.Unix(1300819380, 0), n.Time())
+}
+
+func TestNumericDateJSON(t *testing.T) {
+	testCases := []struct {
+		in   string
+		time time.Time
+		out  string
+	}{
+		{`0`, time.Unix(0, 0), `0`},
+		{`1300819380`, time.Unix(1300819380, 0), `1300819380`},
+		{`1300819380.0`, time.Unix(1300819380, 0), `1300819380`},
+		{`1300819380.123`, time.Unix(1300819380, 123000000), `1300819380.123`},
+		{`1300819380.000000001`, time.Unix(1300819380, 1), `1300819380.000000001`},
+		{`1300819380.1234567891`, time.Unix(1300819380, 123456789), `1300819380.123456789`},
+		{`1.30081938e9`, time.Unix(1300819380, 0), `1300819380`},
+		{`-1`, time.Unix(-1, 0), `-1`},
+		{`-1.5`, time.Unix(-1, -5e8), `-1.5`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.in, func(t *testing.T) {
+			var n jwt.NumericDate
+			assert.NoError(t, json.Unmarshal([]byte(tt.in), &n))
+			assert.True(t, tt.time.Equal(n.Time()), "%v != %v", tt.time, n.Time())
+
+			out, err := json.Marshal(n)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, string(out))
+		})
+	}
+
+	for _, in := range []string{`"1300819380"`, `true`, `{}`, `[]`, `1e19`, `-1e19`, `1e300`, `10000000000000000000`} {
+		var n jwt.NumericDate
+		assert.Error(t, json.Unmarshal([]byte(in), &n), in)
+	}
+}
+
+func ExampleNumericDate() {
+	exp, _ := time.Parse(time.RFC3339, "2015-05-19T16:45:40-07:00")
+	claims := jwt.RegisteredClaims{
+		Subject:        "john@example.com",
+		ExpirationTime: jwt.NumericDateFromTime(exp),
+	}
+
+	s, _ := json.Marshal(claims)
+	fmt.Println(string(s))
+	// Output:
+	//
+	// {"sub":"john@example.com","exp":1432079140}
+}
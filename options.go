@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// SignOption configures the behavior of SignHS256, SignRS256, SignES256, and the
+// other Sign functions in this package.
+//
+// The zero set of options is always valid, and produces the same tokens this
+// package has always produced.
+type SignOption interface {
+	applySign(*signConfig)
+}
+
+// signConfig is the result of applying a set of SignOption.
+type signConfig struct {
+	policies      []SignPolicy
+	keyID         string
+	headerParams  map[string]interface{}
+	weakRSAKeys   bool
+	strictSecrets bool
+	issuedAtNow   bool
+	randomID      bool
+	idFormat      IDFormat
+	rand          io.Reader
+	now           func() time.Time
+	nestedPayload []byte
+}
+
+// signOptionFunc adapts a function into a SignOption.
+type signOptionFunc func(*signConfig)
+
+func (f signOptionFunc) applySign(c *signConfig) {
+	f(c)
+}
+
+// newSignConfig applies opts, in order, to an empty signConfig.
+func newSignConfig(opts []SignOption) signConfig {
+	var c signConfig
+	for _, opt := range opts {
+		opt.applySign(&c)
+	}
+
+	return c
+}
+
+// VerifyOption configures the behavior of VerifyHS256, VerifyRS256,
+// VerifyES256, and the other Verify functions in this package.
+//
+// The zero set of options is always valid, and verifies tokens the same way
+// this package always has.
+type VerifyOption interface {
+	applyVerify(*verifyConfig)
+}
+
+// verifyConfig is the result of applying a set of VerifyOption.
+type verifyConfig struct {
+	allowed             []AllowedAlgorithm
+	lenientNumericDates bool
+	useNumber           bool
+	noDuplicateKeys     bool
+	lenientBase64       bool
+	strictClaims        bool
+	replayStore         ReplayStore
+	allowMissingID      bool
+	ctx                 context.Context
+	header              *Header
+	expectedTypes       []string
+	accessTokenProfile  bool
+	critical            []string
+	detached            bool
+	allowKeyHeaders     bool
+	weakRSAKeys         bool
+	strictSecrets       bool
+	expectedIssuer      *string
+	expectedAudience    *string
+	checkTimes          bool
+	leeway              time.Duration
+	now                 func() time.Time
+	requiredScopes      []string
+	requiredClaims      []string
+}
+
+// verifyOptionFunc adapts a function into a VerifyOption.
+type verifyOptionFunc func(*verifyConfig)
+
+func (f verifyOptionFunc) applyVerify(c *verifyConfig) {
+	f(c)
+}
+
+// newVerifyConfig applies opts, in order, to an empty verifyConfig.
+func newVerifyConfig(opts []VerifyOption) verifyConfig {
+	var c verifyConfig
+	for _, opt := range opts {
+		opt.applyVerify(&c)
+	}
+
+	return c
+}
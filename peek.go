@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"encoding/json"
+)
+
+// ErrMalformedToken is the error returned by the Verify functions in this
+// package, as well as PeekHeader, DecodeHeader, PeekKeyID, InsecureDecodeClaims,
+// Split, and Parse, when they are given something that is not a well-formed
+// JWT. Some of the ways a JWT can be malformed are:
+//
+// * It doesn't have exactly three non-empty, period-separated parts. Whitespace
+// before or after the token is ignored, but whitespace inside it is not.
+//
+// * One of its parts isn't base64url-encoded, without padding, and with any
+// bits left over in its last character set to zero. This means that no two
+// different tokens decode to the same one.
+//
+// * Its header isn't a JSON object, has two members with the same name, or has
+// a member of the wrong type, such as a "kid" that isn't a string.
+//
+// * Its claims aren't JSON.
+//
+// The returned error wraps ErrMalformedToken, so check for it with errors.Is.
+// Unless the JWT couldn't even be split into its parts (see Split), the error
+// also says which part was malformed, and errors.Unwrap returns the error from
+// decoding that part, such as a base64.CorruptInputError or a
+// *json.SyntaxError. Those errors are for debugging; what they say about the
+// token is up to encoding/base64 and encoding/json, and may change.
+//
+// ErrMalformedToken, and the errors that wrap it, match ErrInvalidSignature
+// with errors.Is, the same as ErrWrongAlgorithm and ErrBadSignature do. A
+// token that is well-formed, but has the wrong "alg" or signature, is rejected
+// with one of those two instead.
+var ErrMalformedToken error = invalidSignatureError("jwt: malformed token")
+
+// malformedTokenError is an error wrapping ErrMalformedToken, along with the
+// error that explains what was malformed about the token.
+type malformedTokenError struct {
+	part string // "header", "claims", "signature", or one of the parts of a JWE
+	err  error
+}
+
+func (e *malformedTokenError) Error() string {
+	return ErrMalformedToken.Error() + ": " + e.part + ": " + e.err.Error()
+}
+
+// Is makes errors.Is(e, ErrMalformedToken) true.
+func (e *malformedTokenError) Is(target error) bool {
+	return target == ErrMalformedToken || target == ErrInvalidSignature
+}
+
+func (e *malformedTokenError) Unwrap() error {
+	return e.err
+}
+
+// malformedToken returns an error wrapping ErrMalformedToken, saying that err
+// is what's wrong with part of a token.
+func malformedToken(part string, err error) error {
+	return &malformedTokenError{part: part, err: err}
+}
+
+// PeekHeader returns the header of a JWT, without verifying the JWT.
+//
+// The header PeekHeader returns is UNTRUSTED. Anyone can construct a JWT with
+// any header they like. The only thing you should use the header for is to
+// decide how to verify the token, such as by picking which of a set of trusted
+// keys to verify it with based on its "kid". Never make any other decisions
+// based on it, and never let it decide what algorithm to verify the token with.
+// Once the token is verified, use WithHeader to get a header you can trust.
+//
+// PeekHeader never looks at the signature or claims of the JWT. It returns an
+// error wrapping ErrMalformedToken if s doesn't have three dot-separated parts,
+// or if the header isn't a base64url-encoded JSON object without duplicate
+// members.
+func PeekHeader(s []byte) (Header, error) {
+	decodedHeader, err := peekHeader(s)
+	if err != nil {
+		return Header{}, err
+	}
+
+	var h Header
+	if err := json.Unmarshal(decodedHeader, &h); err != nil {
+		return Header{}, malformedToken("header", err)
+	}
+
+	return h, nil
+}
+
+// peekHeader returns the JSON header of a JWT, without verifying the JWT. It
+// returns an error wrapping ErrMalformedToken in the same cases as PeekHeader,
+// except that it does not check that the header is an object.
+func peekHeader(s []byte) ([]byte, error) {
+	encodedHeader, _, _, ok := splitToken(s)
+	if !ok {
+		return nil, ErrMalformedToken
+	}
+
+	decodedHeader, _, err := decodeHeaderPart(nil, encodedHeader)
+	if err != nil {
+		return nil, malformedToken("header", err)
+	}
+
+	return decodedHeader, nil
+}
+
+// DecodeHeader is the same as PeekHeader. It returns the header of a JWT,
+// without verifying the JWT.
+//
+// The returned header is UNTRUSTED, and is controlled by whoever sent you the
+// token until the token is verified. It is safe to log, for instance to find
+// out what "alg" and "kid" a misconfigured client is sending, but see
+// PeekHeader for what not to use it for.
+//
+// DecodeHeader shares the code that splits a token into its parts and decodes
+// its header with the Verify functions, so a token that DecodeHeader rejects as
+// malformed is one that the Verify functions reject too.
+func DecodeHeader(s []byte) (Header, error) {
+	return PeekHeader(s)
+}
+
+// PeekKeyID returns the "kid" header parameter of a JWT, without verifying the
+// JWT. If the JWT has no "kid", PeekKeyID returns an empty string.
+//
+// Like everything that PeekHeader returns, the key ID that PeekKeyID returns
+// is UNTRUSTED. Only use it to pick which of a set of trusted keys to verify
+// the token with. See PeekHeader for details.
+func PeekKeyID(s []byte) (string, error) {
+	h, err := PeekHeader(s)
+	if err != nil {
+		return "", err
+	}
+
+	return h.KeyID, nil
+}
+
+// InsecureDecodeClaims decodes the claims of a JWT into v, WITHOUT VERIFYING
+// THE JWT. The signature is never looked at.
+//
+// The claims InsecureDecodeClaims returns are UNTRUSTED. Anyone can construct
+// a JWT with any claims they like, and a token whose claims you decode this way
+// may not have been issued by anyone you trust. InsecureDecodeClaims is for the
+// rare cases where you must look inside a token before you can verify it, such
+// as reading "iss" to find out which tenant's keys to verify the token with, or
+// where you only need to show the user something, such as when their expired
+// session ended. Never use what it returns to make an authorization decision.
+// If you can, use PeekHeader and "kid" instead.
+//
+// Unlike the Verify functions, InsecureDecodeClaims does not check "exp",
+// "nbf", or any other claim, and ignores any VerifyOption you would otherwise
+// pass.
+//
+// InsecureDecodeClaims returns an error wrapping ErrMalformedToken in the same
+// cases as PeekHeader, or if the claims aren't base64url-encoded JSON.
+// Otherwise, it returns any error from decoding the claims into v.
+func InsecureDecodeClaims(s []byte, v interface{}) error {
+	if _, err := peekHeader(s); err != nil {
+		return err
+	}
+
+	// peekHeader checked that s has exactly three parts.
+	_, encodedClaims, _, _ := splitToken(s)
+
+	claims, err := decodeNewPart(encodedClaims)
+	if err != nil {
+		return malformedToken("claims", err)
+	}
+
+	if err := checkJSON(claims); err != nil {
+		return malformedToken("claims", err)
+	}
+
+	return decodeClaims(claims, v, false, false)
+}
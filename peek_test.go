@@ -0,0 +1,227 @@
+package jwt_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestPeekHeader(t *testing.T) {
+	token := forgeToken(`{"typ":"JWT","alg":"RS256","kid":"2024-06"}`, `{}`, noSignature)
+
+	header, err := jwt.PeekHeader(token)
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.Header{Type: "JWT", Algorithm: "RS256", KeyID: "2024-06"}, header)
+
+	kid, err := jwt.PeekKeyID(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06", kid)
+
+	kid, err = jwt.PeekKeyID(forgeToken(`{"alg":"RS256"}`, `{}`, noSignature))
+	assert.NoError(t, err)
+	assert.Equal(t, "", kid)
+
+	// The claims and signature aren't looked at.
+	kid, err = jwt.PeekKeyID([]byte("eyJraWQiOiJhIn0.!!!.!!!"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a", kid)
+
+	malformed := []string{
+		"",
+		".",
+		"..",
+		"...",
+		"a",
+		"a.b",
+		"a.b.c.d",
+		"!!!.e30.",
+		"eyJraWQiOiJhIn0",
+		"eyJraWQiOiJhIn0.e30",
+		"eyJraWQiOjF9.e30.",                 // {"kid":1}
+		"eyJraWQiOiJhIiwia2lkIjoiYiJ9.e30.", // {"kid":"a","kid":"b"}
+		"WyJhIl0.e30.",                      // ["a"]
+		"eyJraWQiOiJh.e30.",                 // {"kid":"a
+	}
+
+	for _, s := range malformed {
+		_, err := jwt.PeekHeader([]byte(s))
+		assert.True(t, errors.Is(err, jwt.ErrMalformedToken), s)
+
+		_, err = jwt.PeekKeyID([]byte(s))
+		assert.True(t, errors.Is(err, jwt.ErrMalformedToken), s)
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	token, err := jwt.SignHS256([]byte("my secret key"), jwt.StandardClaims{}, jwt.WithKeyID("2024-06"))
+	assert.NoError(t, err)
+
+	header, err := jwt.DecodeHeader(token)
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.Header{Type: "JWT", Algorithm: "HS256", KeyID: "2024-06"}, header)
+
+	// Anything DecodeHeader rejects, the Verify functions reject too, even with
+	// a valid signature.
+	malformed := []string{
+		"a.b.c.d",
+		string(token) + ".",
+		string(forgeToken(`{"alg":"HS256","alg":"HS256"}`, `{}`, hmacSHA256([]byte("my secret key")))),
+		string(forgeToken(`["HS256"]`, `{}`, hmacSHA256([]byte("my secret key")))),
+	}
+
+	for _, s := range malformed {
+		_, err := jwt.DecodeHeader([]byte(s))
+		assert.True(t, errors.Is(err, jwt.ErrMalformedToken), s)
+
+		var claims jwt.StandardClaims
+		assert.True(t, errors.Is(jwt.VerifyHS256([]byte("my secret key"), []byte(s), &claims), jwt.ErrMalformedToken), s)
+	}
+}
+
+func TestInsecureDecodeClaims(t *testing.T) {
+	var claims jwt.StandardClaims
+
+	// The signature is ignored entirely; it needn't even be base64url.
+	token := forgeToken(`{"alg":"HS256"}`, `{"iss":"https://tenant.example.com","exp":1.5}`, noSignature)
+	assert.NoError(t, jwt.InsecureDecodeClaims(append(token, "!!!"...), &claims))
+	assert.Equal(t, jwt.StandardClaims{Issuer: "https://tenant.example.com", ExpirationTime: 1}, claims)
+
+	// Expired tokens are decoded; nothing is checked.
+	var m map[string]interface{}
+	assert.NoError(t, jwt.InsecureDecodeClaims(token, &m))
+	assert.Equal(t, map[string]interface{}{"iss": "https://tenant.example.com", "exp": 1.5}, m)
+
+	malformed := []string{
+		"",
+		"a.b",
+		"a.b.c.d",
+		"!!!.e30.",
+		"eyJraWQiOiJhIn0.!!!.",
+		"eyJraWQiOiJhIn0.eyJzdWIiOiJh.", // {"sub":"a
+		"eyJraWQiOiJhIn0..",
+		"eyJraWQiOiJhIn1.e30.", // header has trailing bits set
+		"eyJraWQiOiJhIn0.e31.", // claims have trailing bits set
+	}
+
+	for _, s := range malformed {
+		assert.True(t, errors.Is(jwt.InsecureDecodeClaims([]byte(s), &claims), jwt.ErrMalformedToken), s)
+	}
+
+	// Claims that are valid JSON, but don't fit in v, return the error from
+	// decoding them.
+	err := jwt.InsecureDecodeClaims(forgeToken(`{"alg":"HS256"}`, `{"exp":"soon"}`, noSignature), &claims)
+	assert.EqualError(t, err, `jwt: cannot decode "exp" claim: json: cannot unmarshal string into Go struct field StandardClaims.exp of type int64`)
+}
+
+func ExamplePeekKeyID() {
+	// These are the keys we trust, by key ID.
+	secrets := map[string][]byte{
+		"2024-05": []byte("my old secret key"),
+		"2024-06": []byte("my secret key"),
+	}
+
+	token, _ := jwt.SignHS256(secrets["2024-06"], jwt.StandardClaims{Subject: "jdoe@example.com"}, jwt.WithKeyID("2024-06"))
+
+	// The key ID is untrusted; it's only used to pick among trusted keys. If it
+	// names a key we don't have, reject the token.
+	kid, err := jwt.PeekKeyID(token)
+	if err != nil {
+		panic(err)
+	}
+
+	secret, ok := secrets[kid]
+	if !ok {
+		panic("unknown key")
+	}
+
+	var claims jwt.StandardClaims
+	err = jwt.VerifyHS256(secret, token, &claims)
+	fmt.Println(kid, claims.Subject, err)
+	// Output:
+	//
+	// 2024-06 jdoe@example.com <nil>
+}
+
+func TestMalformedToken(t *testing.T) {
+	secret := []byte("my secret key")
+
+	// signRaw signs an already-encoded header and claims, so that they don't
+	// have to be valid base64.
+	signRaw := func(header, claims string) []byte {
+		data := header + "." + claims
+		return []byte(data + "." + base64.RawURLEncoding.EncodeToString(hmacSHA256(secret)([]byte(data))))
+	}
+
+	var corruptInput base64.CorruptInputError
+	var syntax *json.SyntaxError
+	var unmarshalType *json.UnmarshalTypeError
+
+	// prefix is how the error message starts; the rest of it comes from
+	// encoding/base64 or encoding/json. cause is what errors.As finds in the
+	// error, if anything.
+	testCases := []struct {
+		name   string
+		token  []byte
+		prefix string
+		cause  interface{}
+	}{
+		{"two parts", []byte("e30.e30"), "jwt: malformed token", nil},
+		{"four parts", []byte("e30.e30.e30.e30"), "jwt: malformed token", nil},
+		{"padding", []byte("e30=.e30.AA"), "jwt: malformed token", nil},
+		{"header trailing bits", signRaw("e31", "e30"), "jwt: malformed token: header: ", &corruptInput},
+		{"header not JSON", forgeToken(`{"alg":`, `{}`, hmacSHA256(secret)), "jwt: malformed token: header: ", nil},
+		{"header not an object", forgeToken(`["HS256"]`, `{}`, hmacSHA256(secret)), "jwt: malformed token: header: ", &unmarshalType},
+		{"header duplicate", forgeToken(`{"alg":"HS256","alg":"HS256"}`, `{}`, hmacSHA256(secret)), "jwt: malformed token: header: ", nil},
+		{"header kid not a string", forgeToken(`{"alg":"HS256","kid":1}`, `{}`, hmacSHA256(secret)), "jwt: malformed token: header: ", &unmarshalType},
+		{"claims trailing bits", signRaw("eyJhbGciOiJIUzI1NiJ9", "e31"), "jwt: malformed token: claims: ", &corruptInput},
+		{"claims not JSON", forgeToken(`{"alg":"HS256"}`, `{"sub":`, hmacSHA256(secret)), "jwt: malformed token: claims: ", &syntax},
+		{"signature trailing bits", []byte("eyJhbGciOiJIUzI1NiJ9.e30.AB"), "jwt: malformed token: signature: ", &corruptInput},
+		{"signature length", []byte("eyJhbGciOiJIUzI1NiJ9.e30.AAAAA"), "jwt: malformed token: signature: ", &corruptInput},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims map[string]interface{}
+			err := jwt.VerifyHS256(secret, tt.token, &claims)
+			assert.True(t, errors.Is(err, jwt.ErrMalformedToken), err)
+			assert.True(t, errors.Is(err, jwt.ErrInvalidSignature))
+
+			assert.True(t, strings.HasPrefix(err.Error(), tt.prefix), err)
+
+			if tt.cause != nil {
+				assert.True(t, errors.As(err, tt.cause), err)
+			}
+
+			// Parse reports the same problems, except that it doesn't look at
+			// the signature, and so doesn't care what it was made with.
+			_, err = jwt.Parse(tt.token)
+			assert.True(t, errors.Is(err, jwt.ErrMalformedToken), err)
+		})
+	}
+
+	// Well-formed tokens with the wrong "alg" or signature get the other kinds
+	// of ErrInvalidSignature.
+	invalid := map[string]struct {
+		token []byte
+		err   error
+	}{
+		"wrong alg":    {forgeToken(`{"alg":"HS512"}`, `{}`, hmacSHA256(secret)), jwt.ErrWrongAlgorithm},
+		"no alg":       {forgeToken(`{}`, `{}`, hmacSHA256(secret)), jwt.ErrWrongAlgorithm},
+		"wrong secret": {forgeToken(`{"alg":"HS256"}`, `{}`, hmacSHA256([]byte("other secret"))), jwt.ErrBadSignature},
+		"wrong size":   {forgeToken(`{"alg":"HS256"}`, `{}`, func([]byte) []byte { return []byte("sig") }), jwt.ErrBadSignature},
+	}
+
+	for name, tt := range invalid {
+		var claims map[string]interface{}
+		err := jwt.VerifyHS256(secret, tt.token, &claims)
+		assert.Equal(t, tt.err, err, name)
+		assert.True(t, errors.Is(err, jwt.ErrInvalidSignature), name)
+		assert.False(t, errors.Is(err, jwt.ErrMalformedToken), name)
+	}
+}
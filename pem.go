@@ -0,0 +1,251 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// ParsePrivateKeyPEM parses the first private key in PEM-encoded data. The key
+// may be encoded as PKCS#1 ("BEGIN RSA PRIVATE KEY"), SEC1 ("BEGIN EC PRIVATE
+// KEY"), or PKCS#8 ("BEGIN PRIVATE KEY"). The returned key is an
+// *rsa.PrivateKey, an *ecdsa.PrivateKey, or an ed25519.PrivateKey, which are
+// the types that the Sign functions in this package take.
+//
+// The PEM block type is used only as a hint. If a block's contents aren't in
+// the encoding its type suggests, ParsePrivateKeyPEM tries the others before
+// giving up. "EC PARAMETERS" blocks, which OpenSSL puts before EC private
+// keys, are skipped.
+//
+// If data doesn't contain a private key, ParsePrivateKeyPEM returns an error
+// wrapping ErrInvalidKey that says which encodings it tried.
+func ParsePrivateKeyPEM(data []byte) (interface{}, error) {
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePrivateKey(block.Type, block.Bytes)
+}
+
+// ParsePrivateKey parses a DER-encoded private key. It is like
+// ParsePrivateKeyPEM, except that it takes the bytes inside the PEM block
+// rather than the PEM block itself. The key may be encoded as PKCS#1, PKCS#8,
+// or SEC1; there is no need to know which.
+//
+// To check that the returned key can be used with the algorithm you intend to
+// sign with, use CheckPrivateKey.
+func ParsePrivateKey(der []byte) (interface{}, error) {
+	return parsePrivateKey("", der)
+}
+
+// ParsePublicKeyPEM parses the first public key in PEM-encoded data. The key
+// may be encoded as PKIX ("BEGIN PUBLIC KEY") or PKCS#1 ("BEGIN RSA PUBLIC
+// KEY"), or may be the public key of an X.509 certificate ("BEGIN
+// CERTIFICATE"). The returned key is an *rsa.PublicKey, an *ecdsa.PublicKey,
+// or an ed25519.PublicKey, which are the types that the Verify functions in
+// this package take.
+//
+// As with ParsePrivateKeyPEM, the PEM block type is used only as a hint, and
+// the error returned if data doesn't contain a public key wraps ErrInvalidKey
+// and says which encodings were tried.
+//
+// If data is a certificate, ParsePublicKeyPEM does not verify it. Only use it
+// with certificates you already trust.
+func ParsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePublicKey(block.Type, block.Bytes)
+}
+
+// ParseRSAPrivateKeyPEM is like ParsePrivateKeyPEM, except that it returns an
+// error wrapping ErrInvalidKey if the key isn't an RSA key.
+func ParseRSAPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an RSA private key", ErrInvalidKey, keyTypeName(key))
+	}
+
+	return priv, nil
+}
+
+// ParseRSAPublicKeyPEM is like ParsePublicKeyPEM, except that it returns an
+// error wrapping ErrInvalidKey if the key isn't an RSA key.
+func ParseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	key, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an RSA public key", ErrInvalidKey, keyTypeName(key))
+	}
+
+	return pub, nil
+}
+
+// ParseECPrivateKeyPEM is like ParsePrivateKeyPEM, except that it returns an
+// error wrapping ErrInvalidKey if the key isn't an ECDSA key.
+func ParseECPrivateKeyPEM(data []byte) (*ecdsa.PrivateKey, error) {
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an ECDSA private key", ErrInvalidKey, keyTypeName(key))
+	}
+
+	return priv, nil
+}
+
+// ParseECPublicKeyPEM is like ParsePublicKeyPEM, except that it returns an
+// error wrapping ErrInvalidKey if the key isn't an ECDSA key.
+func ParseECPublicKeyPEM(data []byte) (*ecdsa.PublicKey, error) {
+	key, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an ECDSA public key", ErrInvalidKey, keyTypeName(key))
+	}
+
+	return pub, nil
+}
+
+// decodePEM returns the first PEM block in data that isn't "EC PARAMETERS".
+func decodePEM(data []byte) (*pem.Block, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%w: no PEM data found", ErrInvalidKey)
+		}
+
+		if block.Type != "EC PARAMETERS" {
+			return block, nil
+		}
+
+		data = rest
+	}
+}
+
+// keyEncoding is a way of encoding a key in DER.
+type keyEncoding struct {
+	name      string
+	blockType string
+	parse     func(der []byte) (interface{}, error)
+}
+
+var privateKeyEncodings = []keyEncoding{
+	{"PKCS#1", "RSA PRIVATE KEY", func(der []byte) (interface{}, error) { return x509.ParsePKCS1PrivateKey(der) }},
+	{"PKCS#8", "PRIVATE KEY", x509.ParsePKCS8PrivateKey},
+	{"SEC1", "EC PRIVATE KEY", func(der []byte) (interface{}, error) { return x509.ParseECPrivateKey(der) }},
+}
+
+var publicKeyEncodings = []keyEncoding{
+	{"PKIX", "PUBLIC KEY", x509.ParsePKIXPublicKey},
+	{"PKCS#1", "RSA PUBLIC KEY", func(der []byte) (interface{}, error) { return x509.ParsePKCS1PublicKey(der) }},
+	{"X.509 certificate", "CERTIFICATE", func(der []byte) (interface{}, error) {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		return cert.PublicKey, nil
+	}},
+}
+
+// parsePrivateKey parses der as a private key, trying first the encoding
+// blockType suggests and then the others.
+func parsePrivateKey(blockType string, der []byte) (interface{}, error) {
+	key, err := parseKey(privateKeyEncodings, blockType, der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidKey, key)
+}
+
+// parsePublicKey is like parsePrivateKey, but for public keys.
+func parsePublicKey(blockType string, der []byte) (interface{}, error) {
+	key, err := parseKey(publicKeyEncodings, blockType, der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported public key type %T", ErrInvalidKey, key)
+}
+
+func parseKey(encodings []keyEncoding, blockType string, der []byte) (interface{}, error) {
+	ordered := make([]keyEncoding, 0, len(encodings))
+	for _, e := range encodings {
+		if e.blockType == blockType {
+			ordered = append(ordered, e)
+		}
+	}
+
+	for _, e := range encodings {
+		if e.blockType != blockType {
+			ordered = append(ordered, e)
+		}
+	}
+
+	tried := make([]string, len(ordered))
+	for i, e := range ordered {
+		if key, err := e.parse(der); err == nil {
+			return key, nil
+		}
+
+		tried[i] = "not " + e.name
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidKey, strings.Join(tried, ", "))
+}
+
+// keyTypeName returns a human-readable name for the type of key.
+func keyTypeName(key interface{}) string {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return "an RSA private key"
+	case *rsa.PublicKey:
+		return "an RSA public key"
+	case *ecdsa.PrivateKey:
+		return "an ECDSA private key"
+	case *ecdsa.PublicKey:
+		return "an ECDSA public key"
+	case ed25519.PrivateKey:
+		return "an Ed25519 private key"
+	case ed25519.PublicKey:
+		return "an Ed25519 public key"
+	case []byte:
+		return "a []byte secret"
+	}
+
+	return fmt.Sprintf("a %T", key)
+}
@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPolicyViolation is the error returned by the Sign functions when a
+// SignPolicy refuses to sign a set of claims.
+//
+// The returned error wraps ErrPolicyViolation with a description of which rule
+// was violated. Use errors.Is to check for it.
+var ErrPolicyViolation = errors.New("jwt: sign policy violation")
+
+// SignPolicy describes rules that a set of claims must satisfy before this
+// package will sign them.
+//
+// Verifiers should always check the claims in the tokens they accept, but a
+// SignPolicy lets you catch mistakes at the source: a bug in an issuer that
+// mints tokens without an expiration time is much easier to find when the
+// issuer refuses to produce those tokens at all.
+//
+// A SignPolicy is evaluated against the JSON representation of the claims,
+// exactly as they will appear in the token. This means it works the same way
+// whether you sign StandardClaims, a struct embedding StandardClaims, or a map.
+// If any of the rules in the policy are enabled, the claims must be a JSON
+// object.
+//
+// The zero SignPolicy enforces nothing.
+type SignPolicy struct {
+	// RequireExpirationTime, if true, causes signing to fail if the claims do
+	// not include a nonzero "exp".
+	RequireExpirationTime bool
+
+	// MaxTTL, if nonzero, causes signing to fail if "exp" is more than MaxTTL
+	// after the current time, or if "exp" is missing.
+	MaxTTL time.Duration
+
+	// RejectExpired, if true, causes signing to fail if "exp" is present and is
+	// not after the current time.
+	RejectExpired bool
+
+	// RequireIssuer, if true, causes signing to fail if the claims do not
+	// include a nonempty "iss".
+	RequireIssuer bool
+
+	// RequireAudience, if true, causes signing to fail if the claims do not
+	// include a nonempty "aud".
+	RequireAudience bool
+}
+
+// WithSignPolicy causes signing to fail with an error wrapping
+// ErrPolicyViolation if the claims being signed do not satisfy p.
+//
+// The policy is checked before any signature is computed. If WithSignPolicy is
+// given more than once, the claims must satisfy every one of the policies, so
+// a policy given to NewHS256Signer or a Transport can't be loosened by the
+// options passed along with a particular call.
+func WithSignPolicy(p SignPolicy) SignOption {
+	return signOptionFunc(func(c *signConfig) {
+		c.policies = append(c.policies, p)
+	})
+}
+
+// check evaluates the policy against the JSON-encoded claims, using now as the
+// current time.
+func (p *SignPolicy) check(claims []byte, now time.Time) error {
+	if *p == (SignPolicy{}) {
+		return nil
+	}
+
+	var c struct {
+		Issuer         json.RawMessage `json:"iss"`
+		Audience       json.RawMessage `json:"aud"`
+		ExpirationTime *float64        `json:"exp"`
+	}
+
+	if err := json.Unmarshal(claims, &c); err != nil {
+		return fmt.Errorf("%w: claims must be a JSON object with well-formed registered claims: %v", ErrPolicyViolation, err)
+	}
+
+	hasExp := c.ExpirationTime != nil && *c.ExpirationTime != 0
+
+	if p.RequireExpirationTime && !hasExp {
+		return fmt.Errorf("%w: missing \"exp\" claim", ErrPolicyViolation)
+	}
+
+	if p.MaxTTL != 0 && !hasExp {
+		return fmt.Errorf("%w: missing \"exp\" claim", ErrPolicyViolation)
+	}
+
+	if (p.MaxTTL != 0 || p.RejectExpired) && hasExp {
+		exp, ok := numericDateToTime(*c.ExpirationTime)
+		if !ok {
+			return fmt.Errorf("%w: \"exp\" is out of range: %v", ErrPolicyViolation, *c.ExpirationTime)
+		}
+
+		if ttl := exp.Sub(now); p.MaxTTL != 0 && ttl > p.MaxTTL {
+			return fmt.Errorf("%w: \"exp\" is %v in the future, more than the maximum of %v", ErrPolicyViolation, ttl, p.MaxTTL)
+		}
+
+		if p.RejectExpired && !exp.After(now) {
+			return fmt.Errorf("%w: \"exp\" is not in the future", ErrPolicyViolation)
+		}
+	}
+
+	if p.RequireIssuer && isEmptyClaim(c.Issuer) {
+		return fmt.Errorf("%w: missing \"iss\" claim", ErrPolicyViolation)
+	}
+
+	if p.RequireAudience && isEmptyClaim(c.Audience) {
+		return fmt.Errorf("%w: missing \"aud\" claim", ErrPolicyViolation)
+	}
+
+	return nil
+}
+
+// isEmptyClaim returns whether a raw claim value is missing, null, an empty
+// string, or an empty array.
+func isEmptyClaim(raw json.RawMessage) bool {
+	switch string(raw) {
+	case "", "null", `""`, "[]":
+		return true
+	default:
+		return false
+	}
+}
+
+// numericDateToTime converts a JSON NumericDate, which may have a fractional
+// part, to a time.Time. It returns false if f is infinite, NaN, or too large in
+// magnitude for its whole seconds to fit in an int64, as converting such an f
+// to an int64 would give nonsense.
+func numericDateToTime(f float64) (time.Time, bool) {
+	// -(1 << 63) is math.MinInt64, and 1<<63 is one more than math.MaxInt64;
+	// both are exact as a float64. NaN fails both comparisons.
+	if !(f >= -(1<<63) && f < 1<<63) {
+		return time.Time{}, false
+	}
+
+	sec := int64(f)
+	nsec := int64((f - float64(sec)) * 1e9)
+	return time.Unix(sec, nsec), true
+}
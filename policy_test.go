@@ -0,0 +1,212 @@
+package jwt_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestSignPolicy(t *testing.T) {
+	secret := []byte("my secret key")
+	now := time.Now()
+
+	testCases := []struct {
+		name   string
+		policy jwt.SignPolicy
+		claims interface{}
+		err    bool
+	}{
+		{
+			name:   "zero policy",
+			policy: jwt.SignPolicy{},
+			claims: true,
+		},
+		{
+			name:   "require exp, present",
+			policy: jwt.SignPolicy{RequireExpirationTime: true},
+			claims: jwt.StandardClaims{ExpirationTime: now.Add(time.Hour).Unix()},
+		},
+		{
+			name:   "require exp, missing",
+			policy: jwt.SignPolicy{RequireExpirationTime: true},
+			claims: jwt.StandardClaims{Subject: "jdoe@example.com"},
+			err:    true,
+		},
+		{
+			name:   "require exp, map",
+			policy: jwt.SignPolicy{RequireExpirationTime: true},
+			claims: map[string]interface{}{"exp": now.Add(time.Hour).Unix()},
+		},
+		{
+			name:   "require exp, not an object",
+			policy: jwt.SignPolicy{RequireExpirationTime: true},
+			claims: "not an object",
+			err:    true,
+		},
+		{
+			name:   "max ttl, within",
+			policy: jwt.SignPolicy{MaxTTL: time.Hour},
+			claims: jwt.StandardClaims{ExpirationTime: now.Add(time.Minute).Unix()},
+		},
+		{
+			name:   "max ttl, exceeded",
+			policy: jwt.SignPolicy{MaxTTL: time.Hour},
+			claims: jwt.StandardClaims{ExpirationTime: now.Add(2 * time.Hour).Unix()},
+			err:    true,
+		},
+		{
+			name:   "max ttl, missing exp",
+			policy: jwt.SignPolicy{MaxTTL: time.Hour},
+			claims: jwt.StandardClaims{},
+			err:    true,
+		},
+		{
+			name:   "max ttl, measured from now and not from iat",
+			policy: jwt.SignPolicy{MaxTTL: time.Hour},
+			claims: jwt.StandardClaims{
+				IssuedAt:       now.Add(-24 * time.Hour).Unix(),
+				ExpirationTime: now.Add(time.Minute).Unix(),
+			},
+		},
+		{
+			name:   "reject expired, in future",
+			policy: jwt.SignPolicy{RejectExpired: true},
+			claims: jwt.StandardClaims{ExpirationTime: now.Add(time.Minute).Unix()},
+		},
+		{
+			name:   "reject expired, in past",
+			policy: jwt.SignPolicy{RejectExpired: true},
+			claims: jwt.StandardClaims{ExpirationTime: now.Add(-time.Minute).Unix()},
+			err:    true,
+		},
+		{
+			name:   "reject expired, fractional exp",
+			policy: jwt.SignPolicy{RejectExpired: true},
+			claims: map[string]interface{}{"exp": float64(now.Add(-time.Minute).UnixNano()) / 1e9},
+			err:    true,
+		},
+		{
+			name:   "require iss, present",
+			policy: jwt.SignPolicy{RequireIssuer: true},
+			claims: jwt.StandardClaims{Issuer: "https://example.com"},
+		},
+		{
+			name:   "require iss, missing",
+			policy: jwt.SignPolicy{RequireIssuer: true},
+			claims: jwt.StandardClaims{},
+			err:    true,
+		},
+		{
+			name:   "require iss, empty in map",
+			policy: jwt.SignPolicy{RequireIssuer: true},
+			claims: map[string]interface{}{"iss": ""},
+			err:    true,
+		},
+		{
+			name:   "require aud, present",
+			policy: jwt.SignPolicy{RequireAudience: true},
+			claims: jwt.StandardClaims{Audience: "api"},
+		},
+		{
+			name:   "require aud, array",
+			policy: jwt.SignPolicy{RequireAudience: true},
+			claims: map[string]interface{}{"aud": []string{"api"}},
+		},
+		{
+			name:   "max ttl, exp out of range",
+			policy: jwt.SignPolicy{MaxTTL: time.Hour},
+			claims: map[string]interface{}{"exp": -1e19},
+			err:    true,
+		},
+		{
+			name:   "reject expired, exp out of range",
+			policy: jwt.SignPolicy{RejectExpired: true},
+			claims: map[string]interface{}{"exp": 1e300},
+			err:    true,
+		},
+		{
+			name:   "require aud, empty array",
+			policy: jwt.SignPolicy{RequireAudience: true},
+			claims: map[string]interface{}{"aud": []string{}},
+			err:    true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.SignHS256(secret, tt.claims, jwt.WithSignPolicy(tt.policy))
+			if tt.err {
+				assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+				assert.Nil(t, token)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, token)
+			}
+		})
+	}
+}
+
+func TestSignPolicySigner(t *testing.T) {
+	secret := []byte("my secret key")
+	now := time.Now()
+	signer := jwt.NewHS256Signer(secret, jwt.WithSignPolicy(jwt.SignPolicy{MaxTTL: time.Hour}))
+
+	_, err := signer.Sign(jwt.StandardClaims{ExpirationTime: now.Add(time.Minute).Unix()})
+	assert.NoError(t, err)
+
+	_, err = signer.Sign(jwt.StandardClaims{})
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+
+	// A policy passed to Sign adds to the HS256Signer's policy, and can't
+	// loosen it.
+	_, err = signer.Sign(jwt.StandardClaims{}, jwt.WithSignPolicy(jwt.SignPolicy{}))
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+
+	_, err = signer.Sign(jwt.StandardClaims{ExpirationTime: now.Add(2 * time.Hour).Unix()}, jwt.WithSignPolicy(jwt.SignPolicy{MaxTTL: 3 * time.Hour}))
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+
+	_, err = signer.Sign(jwt.StandardClaims{ExpirationTime: now.Add(time.Minute).Unix()}, jwt.WithSignPolicy(jwt.SignPolicy{RequireIssuer: true}))
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+
+	// The policy is checked against "iat" and "exp" as the Transport sets
+	// them.
+	base := &recordingTransport{}
+	r := httptest.NewRequest("GET", "https://example.com/", nil)
+	transport := jwt.NewTransport(base, signer, jwt.StandardClaims{}, time.Minute)
+	_, err = transport.RoundTrip(r)
+	assert.NoError(t, err)
+
+	transport = jwt.NewTransport(base, signer, jwt.StandardClaims{}, 2*time.Hour)
+	_, err = transport.RoundTrip(r)
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+
+	transport = jwt.NewTransport(base, jwt.NewHS256Signer(secret), jwt.StandardClaims{}, time.Minute, jwt.WithSignOptions(jwt.WithSignPolicy(jwt.SignPolicy{RequireIssuer: true})))
+	_, err = transport.RoundTrip(r)
+	assert.True(t, errors.Is(err, jwt.ErrPolicyViolation), "%v", err)
+
+	transport = jwt.NewTransport(base, jwt.NewHS256Signer(secret), jwt.StandardClaims{Issuer: "billing"}, time.Minute, jwt.WithSignOptions(jwt.WithSignPolicy(jwt.SignPolicy{RequireIssuer: true})))
+	_, err = transport.RoundTrip(r)
+	assert.NoError(t, err)
+	assert.Len(t, base.headers, 2)
+}
+
+func ExampleWithSignPolicy() {
+	policy := jwt.SignPolicy{
+		RequireExpirationTime: true,
+		MaxTTL:                time.Hour,
+		RequireIssuer:         true,
+	}
+
+	secret := []byte("my secret key")
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+	_, err := jwt.SignHS256(secret, claims, jwt.WithSignPolicy(policy))
+	fmt.Println(err)
+	// Output:
+	//
+	// jwt: sign policy violation: missing "exp" claim
+}
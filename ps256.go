@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rsa"
+)
+
+const algPS256 = "PS256"
+
+// SignPS256 takes a RSA private key and a set of claims, and returns a
+// PS256-signed JWT containing those claims.
+//
+// VerifyPS256 can verify tokens signed by SignPS256.
+//
+// When using SignPS256 and VerifyPS256 in production, use an RSA keypair
+// generated by a tool like OpenSSL, and use at least 2048 bits. Do not leak or
+// give out the private key to any systems or people that don't need it, because
+// they will be able to generate JWTs that will be indistinguishable from the
+// ones you can generate yourself.
+//
+// PS256 is short for RSASSA-PSS using SHA-256 and MGF1 with SHA-256. Like
+// RS256, it uses RSA keys, but it uses a probabilistic signature scheme that is
+// generally preferred over RS256's in new systems. Tokens signed with SignPS256
+// are not accepted by VerifyRS256, nor vice-versa, even if the same RSA keypair
+// is used for both.
+//
+// PS256 is a mechanism for message authentication. By signing a set of claims
+// with SignPS256, you have not encrypted it. It is trivial for anyone to read
+// the data stored in the return value of SignPS256. All SignPS256 gives you is
+// a signature that proves that when you generated a JWT, you had a particular
+// RSA private key on hand -- and it does this without giving away what the
+// private key is. VerifyPS256 can verify the JWTs produced by SignPS256; to do
+// this, it needs to use the public key that corresponds to the private key you
+// used in SignPS256.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignPS256 will return an error wrapping ErrWeakKey if priv is smaller than
+// 2048 bits, unless opts includes WithWeakRSAKeys. Otherwise, it will return
+// an error only if calling json.Marshal on v returns an error, or if one of
+// opts rejects the claims.
+func SignPS256(priv *rsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algPS256, priv.Size(), v, opts, signRSA(nil, priv, algPS256, crypto.SHA256, true, newSignConfig(opts).weakRSAKeys))
+}
+
+// VerifyPS256 verifies a JWT using a RSA public key. If the JWT is verified,
+// VerifyPS256 will serialize the claims inside the JWT into v.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// verification succeeds, VerifyPS256 will deserialize the claims in the JWT
+// into v.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyPS256 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than PS256, and ErrBadSignature if it is not signed with the private
+// key that corresponds to the public key given. It will return an error
+// wrapping ErrWeakKey if pub is smaller than 2048 bits, unless opts includes
+// WithWeakRSAKeys.
+func VerifyPS256(pub *rsa.PublicKey, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	header, claims, err := verify(algPS256, s, v, opts, verifyRSA(pub, algPS256, crypto.SHA256, true, newVerifyConfig(opts).weakRSAKeys))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
@@ -0,0 +1,79 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestSignPS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+	token, err := jwt.SignPS256(privateKey, claims)
+	assert.NoError(t, err)
+
+	var out jwt.StandardClaims
+	assert.NoError(t, jwt.VerifyPS256(&privateKey.PublicKey, token, &out))
+	assert.Equal(t, claims, out)
+}
+
+func TestVerifyPS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	ps256, err := jwt.SignPS256(privateKey, claims)
+	assert.NoError(t, err)
+
+	rs256, err := jwt.SignRS256(privateKey, claims)
+	assert.NoError(t, err)
+
+	var out jwt.StandardClaims
+
+	// Wrong key.
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyPS256(&otherKey.PublicKey, ps256, &out))
+
+	// PS256 and RS256 use the same kind of key, but tokens from one must never
+	// be accepted by the other.
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyRS256(&privateKey.PublicKey, ps256, &out))
+	assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyPS256(&privateKey.PublicKey, rs256, &out))
+
+	// An RS256 signature under a forged PS256 header, and vice-versa, must be
+	// rejected too.
+	pkcs1v15 := forgeToken(`{"alg":"PS256"}`, `{"sub":"admin"}`, func(data []byte) []byte {
+		h := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, h[:])
+		assert.NoError(t, err)
+		return sig
+	})
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyPS256(&privateKey.PublicKey, pkcs1v15, &out))
+
+	pss := forgeToken(`{"alg":"RS256"}`, `{"sub":"admin"}`, func(data []byte) []byte {
+		h := sha256.Sum256(data)
+		sig, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, h[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		assert.NoError(t, err)
+		return sig
+	})
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyRS256(&privateKey.PublicKey, pss, &out))
+
+	// RFC7518 requires the salt to be as long as the hash. Signatures with any
+	// other salt length are rejected.
+	shortSalt := forgeToken(`{"alg":"PS256"}`, `{"sub":"admin"}`, func(data []byte) []byte {
+		h := sha256.Sum256(data)
+		sig, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, h[:], &rsa.PSSOptions{SaltLength: 16})
+		assert.NoError(t, err)
+		return sig
+	})
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyPS256(&privateKey.PublicKey, shortSalt, &out))
+}
@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package jwt_test
+
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrReplayedToken is the error returned by the Verify functions in this
+// package when they are given a ReplayStore, and the store reports that the
+// token's "jti" has already been seen.
+var ErrReplayedToken = errors.New("jwt: replayed token")
+
+// ReplayStore records the IDs ("jti") of tokens that have been accepted, so
+// that each token can only be accepted once. See WithReplayStore.
+//
+// Seen reports whether jti has been seen before, and records it as seen if it
+// has not. exp is the token's expiration time plus any leeway given to
+// WithLeeway, or the zero time.Time if the token has no "exp". Once exp has
+// passed, a Verify function that checks "exp" rejects the token anyway, so a
+// store can safely forget about jti at that point.
+//
+// A Verify function checks "exp" only if it's given an option like WithLeeway
+// or WithNow. If you instead check "exp" yourself, such as with
+// StandardClaims.Valid, make sure you don't accept a token after the store may
+// have forgotten it.
+//
+// Seen must be safe to call concurrently. If it returns an error, the token is
+// rejected.
+type ReplayStore interface {
+	Seen(ctx context.Context, jti string, exp time.Time) (bool, error)
+}
+
+// WithReplayStore makes a Verify function reject tokens whose "jti" claim store
+// has already seen. When a token is rejected this way, the Verify function
+// returns ErrReplayedToken.
+//
+// store is only consulted for tokens whose signature is valid, and whose claims
+// were accepted by Validate, if the claims implement Validator. This way,
+// tokens that are rejected for other reasons don't use up their "jti".
+//
+// If allowMissingID is false, then tokens that have no "jti" are rejected with
+// an error wrapping ErrMissingClaim. If allowMissingID is true, such tokens are
+// accepted without consulting store, and can be replayed.
+//
+// store is called with the context passed to WithContext, or
+// context.Background() if there is none.
+func WithReplayStore(store ReplayStore, allowMissingID bool) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.replayStore = store
+		c.allowMissingID = allowMissingID
+	})
+}
+
+// WithContext sets the context that a Verify function passes to any
+// ReplayStore it consults.
+func WithContext(ctx context.Context) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.ctx = ctx
+	})
+}
+
+// checkReplay consults c.replayStore, if there is one, about the token whose
+// claims are claims.
+func (c *verifyConfig) checkReplay(claims []byte) error {
+	if c.replayStore == nil {
+		return nil
+	}
+
+	var id struct {
+		ID  *string         `json:"jti"`
+		Exp json.RawMessage `json:"exp"`
+	}
+
+	if err := json.Unmarshal(claims, &id); err != nil {
+		return fmt.Errorf("jwt: cannot decode \"jti\" claim: %w", err)
+	}
+
+	if id.ID == nil || *id.ID == "" {
+		if c.allowMissingID {
+			return nil
+		}
+
+		return fmt.Errorf("%w: \"jti\"", ErrMissingClaim)
+	}
+
+	var exp time.Time
+	if len(id.Exp) > 0 && string(id.Exp) != "null" {
+		t, err := parseNumericDate(string(id.Exp))
+		if err != nil {
+			return fmt.Errorf("jwt: cannot decode \"exp\" claim: %w", err)
+		}
+
+		// A token is accepted until leeway after its "exp", so it must be
+		// remembered until then too.
+		exp = t.Add(c.leeway)
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	seen, err := c.replayStore.Seen(ctx, *id.ID, exp)
+	if err != nil {
+		return fmt.Errorf("jwt: replay store: %w", err)
+	}
+
+	if seen {
+		return ErrReplayedToken
+	}
+
+	return nil
+}
+
+// MemoryReplayStore is a ReplayStore that keeps track of token IDs in memory.
+// It is suitable for services that run as a single process. Because it is in
+// memory, tokens can be replayed after the process restarts, or against a
+// different process.
+//
+// MemoryReplayStore forgets each token ID once its expiration time, plus the
+// verifier's leeway, has passed. IDs of tokens with no expiration time are
+// never forgotten, so consider requiring "exp" if you use MemoryReplayStore.
+//
+// The zero MemoryReplayStore is empty and ready to use. A MemoryReplayStore must
+// not be copied after first use.
+type MemoryReplayStore struct {
+	// Clock decides when an expiration time has passed. If Clock is nil,
+	// RealClock is used. When verifying with WithClock, give the
+	// MemoryReplayStore the same Clock, or it may forget a token ID while the
+	// token is still valid.
+	Clock Clock
+
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	expiry replayHeap
+}
+
+// Seen implements ReplayStore.
+func (m *MemoryReplayStore) Seen(ctx context.Context, jti string, exp time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var now time.Time
+	if m.Clock != nil {
+		now = m.Clock.Now()
+	} else {
+		now = time.Now()
+	}
+
+	for len(m.expiry) > 0 && m.expiry[0].exp.Before(now) {
+		delete(m.seen, heap.Pop(&m.expiry).(replayEntry).jti)
+	}
+
+	if _, ok := m.seen[jti]; ok {
+		return true, nil
+	}
+
+	if m.seen == nil {
+		m.seen = map[string]struct{}{}
+	}
+
+	m.seen[jti] = struct{}{}
+	if !exp.IsZero() {
+		heap.Push(&m.expiry, replayEntry{jti: jti, exp: exp})
+	}
+
+	return false, nil
+}
+
+type replayEntry struct {
+	jti string
+	exp time.Time
+}
+
+// replayHeap is a min-heap of replayEntry, ordered by exp.
+type replayHeap []replayEntry
+
+func (h replayHeap) Len() int            { return len(h) }
+func (h replayHeap) Less(i, j int) bool  { return h[i].exp.Before(h[j].exp) }
+func (h replayHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *replayHeap) Push(x interface{}) { *h = append(*h, x.(replayEntry)) }
+
+func (h *replayHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
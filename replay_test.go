@@ -0,0 +1,186 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+type fakeReplayStore struct {
+	calls []string
+	exps  []time.Time
+	ctx   context.Context
+	err   error
+}
+
+func (s *fakeReplayStore) Seen(ctx context.Context, jti string, exp time.Time) (bool, error) {
+	s.ctx = ctx
+	s.exps = append(s.exps, exp)
+	for _, c := range s.calls {
+		if c == jti {
+			return true, s.err
+		}
+	}
+
+	s.calls = append(s.calls, jti)
+	return false, s.err
+}
+
+func TestWithReplayStore(t *testing.T) {
+	secret := []byte("my secret key")
+
+	sign := func(claims string) []byte {
+		return forgeToken(`{"alg":"HS256"}`, claims, hmacSHA256(secret))
+	}
+
+	t.Run("rejects replays", func(t *testing.T) {
+		store := &fakeReplayStore{}
+		token := sign(`{"jti":"a","exp":1300819380}`)
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &claims, jwt.WithReplayStore(store, false)))
+		assert.Equal(t, jwt.ErrReplayedToken, jwt.VerifyHS256(secret, token, &claims, jwt.WithReplayStore(store, false)))
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"jti":"b"}`), &claims, jwt.WithReplayStore(store, false)))
+
+		assert.Equal(t, []string{"a", "b"}, store.calls)
+		assert.Equal(t, []time.Time{time.Unix(1300819380, 0), time.Unix(1300819380, 0), {}}, store.exps)
+		assert.Equal(t, context.Background(), store.ctx)
+	})
+
+	t.Run("missing jti", func(t *testing.T) {
+		for _, claims := range []string{`{}`, `{"jti":""}`, `{"jti":null}`} {
+			store := &fakeReplayStore{}
+			token := sign(claims)
+
+			var out jwt.StandardClaims
+			err := jwt.VerifyHS256(secret, token, &out, jwt.WithReplayStore(store, false))
+			assert.True(t, errors.Is(err, jwt.ErrMissingClaim), claims)
+			assert.EqualError(t, err, `jwt: missing required claim: "jti"`, claims)
+
+			assert.NoError(t, jwt.VerifyHS256(secret, token, &out, jwt.WithReplayStore(store, true)), claims)
+			assert.NoError(t, jwt.VerifyHS256(secret, token, &out, jwt.WithReplayStore(store, true)), claims)
+			assert.Empty(t, store.calls, claims)
+		}
+	})
+
+	t.Run("not consulted for rejected tokens", func(t *testing.T) {
+		store := &fakeReplayStore{}
+
+		var claims jwt.StandardClaims
+		err := jwt.VerifyHS256([]byte("other"), sign(`{"jti":"a"}`), &claims, jwt.WithReplayStore(store, false))
+		assert.Equal(t, jwt.ErrBadSignature, err)
+
+		var tenant tenantClaims
+		err = jwt.VerifyHS256(secret, sign(`{"jti":"a"}`), &tenant, jwt.WithReplayStore(store, false))
+		assert.True(t, errors.Is(err, jwt.ErrClaimsRejected))
+
+		assert.Empty(t, store.calls)
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		errStore := errors.New("store unavailable")
+		store := &fakeReplayStore{err: errStore}
+
+		var claims jwt.StandardClaims
+		err := jwt.VerifyHS256(secret, sign(`{"jti":"a"}`), &claims, jwt.WithReplayStore(store, false))
+		assert.True(t, errors.Is(err, errStore))
+	})
+
+	t.Run("context", func(t *testing.T) {
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "value")
+		store := &fakeReplayStore{}
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"jti":"a"}`), &claims, jwt.WithContext(ctx), jwt.WithReplayStore(store, false)))
+		assert.Equal(t, ctx, store.ctx)
+	})
+
+	t.Run("lenient exp", func(t *testing.T) {
+		store := &fakeReplayStore{}
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"jti":"a","exp":"1300819380"}`), &claims, jwt.WithLenientNumericDates(), jwt.WithReplayStore(store, false)))
+		assert.Equal(t, []time.Time{time.Unix(1300819380, 0)}, store.exps)
+	})
+
+	t.Run("leeway", func(t *testing.T) {
+		store := &fakeReplayStore{}
+		now := func() time.Time { return time.Unix(1300819380, 0) }
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"jti":"a","exp":1300819380}`), &claims, jwt.WithNow(now), jwt.WithLeeway(time.Minute), jwt.WithReplayStore(store, false)))
+		assert.Equal(t, []time.Time{time.Unix(1300819440, 0)}, store.exps)
+	})
+
+	t.Run("expired within leeway", func(t *testing.T) {
+		var store jwt.MemoryReplayStore
+		token := sign(fmt.Sprintf(`{"jti":"a","exp":%d}`, time.Now().Add(-30*time.Second).Unix()))
+
+		// The token expired 30 seconds ago, but is still within the leeway, so
+		// the store must not have forgotten it.
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &claims, jwt.WithLeeway(time.Minute), jwt.WithReplayStore(&store, false)))
+		assert.Equal(t, jwt.ErrReplayedToken, jwt.VerifyHS256(secret, token, &claims, jwt.WithLeeway(time.Minute), jwt.WithReplayStore(&store, false)))
+	})
+}
+
+func TestMemoryReplayStore(t *testing.T) {
+	ctx := context.Background()
+	var store jwt.MemoryReplayStore
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	seen, err := store.Seen(ctx, "a", future)
+	assert.NoError(t, err)
+	assert.False(t, seen)
+
+	seen, err = store.Seen(ctx, "a", future)
+	assert.NoError(t, err)
+	assert.True(t, seen)
+
+	seen, err = store.Seen(ctx, "b", time.Time{})
+	assert.NoError(t, err)
+	assert.False(t, seen)
+
+	seen, err = store.Seen(ctx, "b", time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, seen)
+
+	// Once a token's expiration time has passed, its ID is forgotten.
+	seen, err = store.Seen(ctx, "c", past)
+	assert.NoError(t, err)
+	assert.False(t, seen)
+
+	seen, err = store.Seen(ctx, "c", past)
+	assert.NoError(t, err)
+	assert.False(t, seen)
+
+	seen, err = store.Seen(ctx, "a", future)
+	assert.NoError(t, err)
+	assert.True(t, seen)
+}
+
+func ExampleWithReplayStore() {
+	secret := []byte("my secret key")
+	token, _ := jwt.SignHS256(secret, jwt.StandardClaims{
+		ID:             "b8e1f2a4",
+		ExpirationTime: time.Now().Add(time.Minute).Unix(),
+	})
+
+	var store jwt.MemoryReplayStore
+
+	var claims jwt.StandardClaims
+	fmt.Println(jwt.VerifyHS256(secret, token, &claims, jwt.WithReplayStore(&store, false)))
+	fmt.Println(jwt.VerifyHS256(secret, token, &claims, jwt.WithReplayStore(&store, false)))
+	// Output:
+	//
+	// <nil>
+	// jwt: replayed token
+}
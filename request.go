@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ErrMultipleTokens is the error returned by FromRequest, FromQuery, and
+// FromForm when a request carries more than one token. RFC6750 forbids
+// clients from sending more than one, so a request that does is rejected
+// rather than having one of its tokens picked.
+//
+// https://tools.ietf.org/html/rfc6750#section-2
+var ErrMultipleTokens = errors.New("jwt: more than one token in request")
+
+// TokenSource is a place in an HTTP request where a token can be carried.
+// FromAuthorizationHeader, FromQuery, and FromForm are TokenSources, as is the
+// function CookieSource returns.
+//
+// A TokenSource returns ErrNoToken if r carries no token in the place it
+// looks. The token it returns is not verified.
+type TokenSource func(r *http.Request) ([]byte, error)
+
+// FromRequest returns the token in r, looking for it in each of sources. If
+// no sources are given, FromRequest looks in the places RFC6750 describes: the
+// Authorization header, the "access_token" form field, and the "access_token"
+// query parameter.
+//
+// FromRequest returns the token from the first of sources that has one. If
+// more than one of sources has a token, FromRequest returns ErrMultipleTokens
+// instead. If none of them does, it returns ErrNoToken. Any other error from a
+// TokenSource is returned as-is.
+//
+// If sources include FromForm, FromRequest may read the body of r. See
+// FromForm.
+//
+// https://tools.ietf.org/html/rfc6750#section-2
+func FromRequest(r *http.Request, sources ...TokenSource) ([]byte, error) {
+	if len(sources) == 0 {
+		sources = []TokenSource{FromAuthorizationHeader, FromForm, FromQuery}
+	}
+
+	var found []byte
+	for _, source := range sources {
+		token, err := source(r)
+		if err == ErrNoToken {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if found != nil {
+			return nil, ErrMultipleTokens
+		}
+
+		found = token
+	}
+
+	if found == nil {
+		return nil, ErrNoToken
+	}
+
+	return found, nil
+}
+
+// FromAuthorizationHeader returns the bearer token in the Authorization header
+// of r. The "Bearer" scheme is case-insensitive. If r has no Authorization
+// header, or it uses a scheme other than "Bearer", FromAuthorizationHeader
+// returns ErrNoToken.
+//
+// https://tools.ietf.org/html/rfc6750#section-2.1
+func FromAuthorizationHeader(r *http.Request) ([]byte, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, ErrNoToken
+	}
+
+	return token, nil
+}
+
+// CookieSource returns a TokenSource that calls FromCookie with name.
+func CookieSource(name string) TokenSource {
+	return func(r *http.Request) ([]byte, error) {
+		return FromCookie(r, name)
+	}
+}
+
+// FromQuery returns the token in the "access_token" query parameter of r. It
+// returns ErrNoToken if there is no such parameter or it is empty, and
+// ErrMultipleTokens if there is more than one.
+//
+// Tokens in URLs tend to end up in logs and browser histories. Only accept them
+// from clients that can't send an Authorization header.
+//
+// https://tools.ietf.org/html/rfc6750#section-2.3
+func FromQuery(r *http.Request) ([]byte, error) {
+	return accessToken(r.URL.Query()["access_token"])
+}
+
+// FromForm returns the token in the "access_token" field of the form-encoded
+// body of r. It only looks at requests with a body whose Content-Type is
+// application/x-www-form-urlencoded, and whose method isn't GET or HEAD. It
+// returns ErrNoToken for any other request, or if the form has no such field
+// or it is empty, and ErrMultipleTokens if the form has more than one.
+//
+// To read the form, FromForm calls r.ParseForm, which reads r.Body. Once it
+// has, r.Body is empty, but the form is still available to later handlers
+// through r.PostForm, r.Form, r.FormValue, and r.PostFormValue, since
+// ParseForm stores it there. Handlers that read r.Body directly will not see
+// it. The body is only read if the request looks like it carries a form.
+//
+// https://tools.ietf.org/html/rfc6750#section-2.2
+func FromForm(r *http.Request) ([]byte, error) {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil, ErrNoToken
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return nil, ErrNoToken
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("jwt: parsing form: %w", err)
+	}
+
+	return accessToken(r.PostForm["access_token"])
+}
+
+// accessToken returns the only one of values, or an error if there isn't
+// exactly one non-empty value.
+func accessToken(values []string) ([]byte, error) {
+	switch {
+	case len(values) > 1:
+		return nil, ErrMultipleTokens
+	case len(values) == 0 || values[0] == "":
+		return nil, ErrNoToken
+	}
+
+	return []byte(values[0]), nil
+}
@@ -0,0 +1,115 @@
+package jwt_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestFromRequest(t *testing.T) {
+	form := func(target, body string) *http.Request {
+		r := httptest.NewRequest("POST", target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	t.Run("header", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer a.b.c")
+
+		token, err := jwt.FromRequest(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "a.b.c", string(token))
+	})
+
+	t.Run("query", func(t *testing.T) {
+		token, err := jwt.FromRequest(httptest.NewRequest("GET", "/?access_token=a.b.c", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "a.b.c", string(token))
+
+		_, err = jwt.FromRequest(httptest.NewRequest("GET", "/?access_token=a.b.c&access_token=d.e.f", nil))
+		assert.Equal(t, jwt.ErrMultipleTokens, err)
+
+		_, err = jwt.FromRequest(httptest.NewRequest("GET", "/?access_token=", nil))
+		assert.Equal(t, jwt.ErrNoToken, err)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		r := form("/", "access_token=a.b.c&other=x")
+		token, err := jwt.FromRequest(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "a.b.c", string(token))
+
+		// Later handlers can still get at the form, though not at the body.
+		assert.Equal(t, "x", r.PostFormValue("other"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Empty(t, body)
+
+		// Bodies that aren't forms aren't read.
+		r = httptest.NewRequest("POST", "/", strings.NewReader("access_token=a.b.c"))
+		r.Header.Set("Content-Type", "application/json")
+		_, err = jwt.FromRequest(r)
+		assert.Equal(t, jwt.ErrNoToken, err)
+		body, err = ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "access_token=a.b.c", string(body))
+
+		// Neither are the bodies of GET requests.
+		r = httptest.NewRequest("GET", "/", strings.NewReader("access_token=a.b.c"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		_, err = jwt.FromRequest(r)
+		assert.Equal(t, jwt.ErrNoToken, err)
+
+		// The query isn't mistaken for the form.
+		_, err = jwt.FromForm(form("/?access_token=a.b.c", ""))
+		assert.Equal(t, jwt.ErrNoToken, err)
+
+		_, err = jwt.FromRequest(form("/", "access_token=a.b.c&access_token=d.e.f"))
+		assert.Equal(t, jwt.ErrMultipleTokens, err)
+
+		_, err = jwt.FromRequest(form("/", "%zz"))
+		assert.Error(t, err)
+		assert.NotEqual(t, jwt.ErrNoToken, err)
+	})
+
+	t.Run("more than one location", func(t *testing.T) {
+		r := form("/?access_token=a.b.c", "access_token=d.e.f")
+		_, err := jwt.FromRequest(r)
+		assert.Equal(t, jwt.ErrMultipleTokens, err)
+
+		r = httptest.NewRequest("GET", "/?access_token=a.b.c", nil)
+		r.Header.Set("Authorization", "Bearer a.b.c")
+		_, err = jwt.FromRequest(r)
+		assert.Equal(t, jwt.ErrMultipleTokens, err)
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/?access_token=a.b.c", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: "d.e.f"})
+
+		// Only the given sources are looked at.
+		token, err := jwt.FromRequest(r, jwt.CookieSource("session"))
+		assert.NoError(t, err)
+		assert.Equal(t, "d.e.f", string(token))
+
+		_, err = jwt.FromRequest(r, jwt.FromAuthorizationHeader)
+		assert.Equal(t, jwt.ErrNoToken, err)
+
+		_, err = jwt.FromRequest(r, jwt.FromAuthorizationHeader, jwt.CookieSource("session"), jwt.FromQuery)
+		assert.Equal(t, jwt.ErrMultipleTokens, err)
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Basic amRvZTpodW50ZXIy")
+
+		_, err := jwt.FromRequest(r)
+		assert.Equal(t, jwt.ErrNoToken, err)
+	})
+}
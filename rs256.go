@@ -1,11 +1,11 @@
 package jwt
 
 import (
+	"context"
 	"crypto"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
-	"encoding/json"
+	"fmt"
+	"io"
 )
 
 const algRS256 = "RS256"
@@ -34,15 +34,60 @@ const algRS256 = "RS256"
 // encoding/json package of the standard library. The JSON representation of v
 // will be used as the claims part of the returned JWT.
 //
-// SignRS256 will return an error only if calling json.Marshal on v returns an
-// error.
-func SignRS256(priv *rsa.PrivateKey, v interface{}) ([]byte, error) {
-	return sign(algRS256, 256, v, func(data []byte) ([]byte, error) {
-		h := crypto.SHA256.New()
-		h.Write(data)
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignRS256 will return an error wrapping ErrWeakKey if priv is smaller than
+// 2048 bits, unless opts includes WithWeakRSAKeys. Otherwise, it will return
+// an error only if calling json.Marshal on v returns an error, or if one of
+// opts rejects the claims.
+func SignRS256(priv *rsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return SignRS256WithRand(nil, priv, v, opts...)
+}
+
+// SignRS256WithRand is like SignRS256, except that any randomness used while
+// signing is read from rand. If rand is nil, crypto/rand.Reader is used, just as
+// SignRS256 does.
+//
+// RS256 signatures themselves are not randomized: the same key and claims
+// always give the same token. Go versions before 1.20 read from rand only to
+// blind the private key operation against timing attacks, and later versions
+// don't read from it at all.
+func SignRS256WithRand(rand io.Reader, priv *rsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algRS256, priv.Size(), v, opts, signRSA(rand, priv, algRS256, crypto.SHA256, false, newSignConfig(opts).weakRSAKeys))
+}
+
+// SignRS256Signer is like SignRS256, except that the signature is made by
+// signer. This lets you sign JWTs with keys that are kept in a hardware
+// security module or a cloud key management service, and which can't be
+// exported as an *rsa.PrivateKey.
+//
+// signer.Sign is passed a SHA-256 digest and crypto.SHA256 as its options, and
+// must return an RSASSA-PKCS1-v1_5 signature. *rsa.PrivateKey is itself a
+// crypto.Signer that does this.
+//
+// SignRS256Signer returns an error wrapping ErrInvalidKey if signer.Public() is
+// not an *rsa.PublicKey. Otherwise, it returns any error that signer.Sign
+// returns, and otherwise only returns an error in the same cases as SignRS256.
+func SignRS256Signer(signer crypto.Signer, v interface{}, opts ...SignOption) ([]byte, error) {
+	return SignRS256Context(context.Background(), signer, v, opts...)
+}
+
+// SignRS256Context is like SignRS256Signer, except that it takes a context. If
+// signer is a ContextSigner, ctx is passed to its SignContext method, so that a
+// call to a remote key management service can be given a deadline or be
+// cancelled.
+//
+// If ctx is done before the token is signed, SignRS256Context returns
+// ctx.Err(), and never a token. This is true even if signer is not a
+// ContextSigner, though in that case signer.Sign runs to completion first.
+func SignRS256Context(ctx context.Context, signer crypto.Signer, v interface{}, opts ...SignOption) ([]byte, error) {
+	pub, ok := signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: RS256 requires an RSA key, not %s", ErrInvalidKey, keyTypeName(signer.Public()))
+	}
 
-		return rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, h.Sum(nil))
-	})
+	return sign(algRS256, pub.Size(), v, opts, signRSASigner(ctx, signer, pub, algRS256, crypto.SHA256, newSignConfig(opts).weakRSAKeys))
 }
 
 // VerifyRS256 verifies a JWT using a RSA public key. If the JWT is verified,
@@ -53,24 +98,49 @@ func SignRS256(priv *rsa.PrivateKey, v interface{}) ([]byte, error) {
 // verification succeeds, VerifyRS256 will deserialize the claims in the JWT
 // into v.
 //
-// VerifyRS256 will return InvalidSignature if the JWT is malformed, uses any
-// algorithm other than RS256, or is not signed with the private key that
-// corresponds to the public key given.
-func VerifyRS256(pub *rsa.PublicKey, s []byte, v interface{}) error {
-	claims, err := verify(algRS256, s, func(data, sig []byte) error {
-		h := sha256.New()
-		h.Write(data)
+// If v is a *json.RawMessage, VerifyRS256 sets it to the claims of the JWT
+// exactly as they were signed, without decoding them. This is useful for
+// passing the claims along verbatim. The claims are in a newly allocated slice
+// that nothing else refers to.
+//
+// If v is nil, VerifyRS256 checks the signature and nothing else. No claims
+// are decoded or validated. See VerifyHS256.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyRS256 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than RS256, and ErrBadSignature if it is not signed with the private
+// key that corresponds to the public key given. It will return an error
+// wrapping ErrWeakKey if pub is smaller than 2048 bits, unless opts includes
+// WithWeakRSAKeys.
+func VerifyRS256(pub *rsa.PublicKey, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
 
-		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, h.Sum(nil), sig) != nil {
-			return ErrInvalidSignature
-		}
+	header, claims, err := verify(algRS256, s, v, opts, verifyRSA(pub, algRS256, crypto.SHA256, false, newVerifyConfig(opts).weakRSAKeys))
+	if err != nil {
+		return err
+	}
 
-		return nil
-	})
+	return unmarshalClaims(header, claims, v, opts)
+}
 
+// SignRS256String is like SignRS256, except that it returns the token as a
+// string. The string is made without copying the token.
+func SignRS256String(priv *rsa.PrivateKey, v interface{}, opts ...SignOption) (string, error) {
+	token, err := SignRS256(priv, v, opts...)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return json.Unmarshal(claims, v)
+	return bytesString(token), nil
+}
+
+// VerifyRS256String is like VerifyRS256, except that it takes the token as a
+// string, such as one taken from an HTTP header. The token is not copied.
+func VerifyRS256String(pub *rsa.PublicKey, s string, v interface{}, opts ...VerifyOption) error {
+	return VerifyRS256(pub, stringBytes(s), v, opts...)
 }
@@ -1,12 +1,18 @@
 package jwt_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"reflect"
 	"testing"
@@ -107,6 +113,170 @@ func TestSignRS256(t *testing.T) {
 	}))
 }
 
+func TestSignRS256WithRand(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	want, err := jwt.SignRS256(priv, claims)
+	assert.NoError(t, err)
+
+	// RS256 signatures aren't randomized, so where the randomness comes from
+	// makes no difference to the token.
+	for _, r := range []io.Reader{nil, rand.Reader, &countingReader{r: rand.Reader}} {
+		token, err := jwt.SignRS256WithRand(r, priv, claims)
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(token))
+	}
+
+	_, err = jwt.SignRS256WithRand(nil, &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: big.NewInt(3233), E: 17}}, claims)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+}
+
+// remoteSigner is a crypto.Signer that, like a key in an HSM, is not a
+// concrete private key type. If sign is nil, it signs with key.
+type remoteSigner struct {
+	key  crypto.Signer
+	pub  crypto.PublicKey
+	sign func(digest []byte, opts crypto.SignerOpts) ([]byte, error)
+}
+
+func (s remoteSigner) Public() crypto.PublicKey {
+	if s.pub != nil {
+		return s.pub
+	}
+
+	return s.key.Public()
+}
+
+func (s remoteSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if s.sign != nil {
+		return s.sign(digest, opts)
+	}
+
+	return s.key.Sign(rand, digest, opts)
+}
+
+func TestSignRS256Signer(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	// RSASSA-PKCS1-v1_5 is deterministic, so signing with the key directly and
+	// through a crypto.Signer should give the same token.
+	want, err := jwt.SignRS256(priv, claims)
+	assert.NoError(t, err)
+
+	got, err := jwt.SignRS256Signer(remoteSigner{key: priv}, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	var out jwt.StandardClaims
+	assert.NoError(t, jwt.VerifyRS256(&priv.PublicKey, got, &out))
+	assert.Equal(t, claims, out)
+
+	var gotOpts crypto.SignerOpts
+	_, err = jwt.SignRS256Signer(remoteSigner{key: priv, sign: func(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+		gotOpts = opts
+		assert.Len(t, digest, sha256.Size)
+		return nil, errors.New("kms unavailable")
+	}}, claims)
+	assert.EqualError(t, err, "kms unavailable")
+	assert.Equal(t, crypto.SHA256, gotOpts)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = jwt.SignRS256Signer(ecKey, claims)
+	assert.True(t, errors.Is(err, jwt.ErrInvalidKey))
+}
+
+func TestRS256String(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	s, err := jwt.SignRS256String(priv, claims)
+	assert.NoError(t, err)
+
+	var out jwt.StandardClaims
+	assert.NoError(t, jwt.VerifyRS256String(&priv.PublicKey, s, &out))
+	assert.Equal(t, claims, out)
+	assert.NoError(t, jwt.VerifyRS256(&priv.PublicKey, []byte(s), &out))
+
+	assert.True(t, errors.Is(jwt.VerifyRS256String(&priv.PublicKey, s[:len(s)-1], &out), jwt.ErrMalformedToken))
+}
+
+func TestRS256WeakKey(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	// Weak keys are rejected for signing, even through a crypto.Signer.
+	_, err = jwt.SignRS256(weakKey, claims)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+	assert.EqualError(t, err, "jwt: key is too weak: RS256 requires an RSA key of at least 2048 bits, not 1024")
+
+	_, err = jwt.SignRS256Signer(remoteSigner{key: weakKey}, claims)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+
+	_, err = jwt.SignRS384(weakKey, claims)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+
+	_, err = jwt.SignPS256(weakKey, claims)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+
+	// Tests can opt out.
+	token, err := jwt.SignRS256(weakKey, claims, jwt.WithWeakRSAKeys())
+	assert.NoError(t, err)
+
+	// Verifying with a weak key is also rejected, and the error is not
+	// ErrInvalidSignature.
+	var out jwt.StandardClaims
+	err = jwt.VerifyRS256(&weakKey.PublicKey, token, &out)
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+	assert.False(t, errors.Is(err, jwt.ErrInvalidSignature))
+
+	_, err = jwt.VerifyAny(token, &out, jwt.AllowRS256(&weakKey.PublicKey))
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+
+	// The options passed to VerifyAny and VerifyWithKeySet decide whether
+	// AllowRS256 accepts weak keys.
+	_, err = jwt.VerifyAny(token, &out, jwt.AllowRS256(&weakKey.PublicKey), jwt.WithWeakRSAKeys())
+	assert.NoError(t, err)
+
+	var ks jwt.KeySet
+	assert.NoError(t, ks.Add("weak", jwt.AllowRS256(&weakKey.PublicKey)))
+
+	kidToken, err := jwt.SignRS256(weakKey, claims, jwt.WithWeakRSAKeys(), jwt.WithKeyID("weak"))
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(jwt.VerifyWithKeySet(&ks, kidToken, &out), jwt.ErrWeakKey))
+	assert.NoError(t, jwt.VerifyWithKeySet(&ks, kidToken, &out, jwt.WithWeakRSAKeys()))
+
+	assert.NoError(t, jwt.VerifyRS256(&weakKey.PublicKey, token, &out, jwt.WithWeakRSAKeys()))
+	assert.Equal(t, claims, out)
+
+	token, err = jwt.SignRS384(weakKey, claims, jwt.WithWeakRSAKeys())
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(jwt.VerifyRS384(&weakKey.PublicKey, token, &out), jwt.ErrWeakKey))
+	assert.NoError(t, jwt.VerifyRS384(&weakKey.PublicKey, token, &out, jwt.WithWeakRSAKeys()))
+	_, err = jwt.VerifyAny(token, &out, jwt.AllowRS384(&weakKey.PublicKey), jwt.WithWeakRSAKeys())
+	assert.NoError(t, err)
+
+	token, err = jwt.SignPS256(weakKey, claims, jwt.WithWeakRSAKeys())
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(jwt.VerifyPS256(&weakKey.PublicKey, token, &out), jwt.ErrWeakKey))
+	assert.NoError(t, jwt.VerifyPS256(&weakKey.PublicKey, token, &out, jwt.WithWeakRSAKeys()))
+	_, err = jwt.VerifyAny(token, &out, jwt.AllowPS256(&weakKey.PublicKey))
+	assert.True(t, errors.Is(err, jwt.ErrWeakKey))
+	_, err = jwt.VerifyAny(token, &out, jwt.AllowPS256(&weakKey.PublicKey), jwt.WithWeakRSAKeys())
+	assert.NoError(t, err)
+}
+
 func ExampleSignRS256() {
 	// You can generate PEM files like this by running:
 	//
@@ -143,21 +313,8 @@ d1GiozKs/3biveU5QDtciiMi7KTgyPuY19sdEH/I2BrTT0Ose63T
 	privateKey, err := x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
 	fmt.Println(err)
 
-	// DO NOT DO THIS IN PRODUCTION
-	//
-	// This line is *just* here to make the output of SignRS256 predictable. The
-	// default value for crypto/rand.Reader is a good one that you should not
-	// reassign. RSA signing uses a random seed, which makes the result of RSA
-	// signatures otherwise unpredictable. This is a good thing in the real world.
-	// It's only inconvenient in examples like these, where the output needs to be
-	// predicatable in order for tests to pass.
-	//
-	// Everything after this assignment to rand.Reader is appropriate usage of
-	// this package.
-	//
-	// Again: DO NOT REASSIGN TO rand.Reader IN REAL-WORLD CODE!
-	rand.Reader = zeroReader{}
-
+	// RS256 signatures aren't randomized, so signing the same claims with the
+	// same key always gives the same token.
 	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
 	token, err := jwt.SignRS256(privateKey, claims)
 	fmt.Println(err)
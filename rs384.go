@@ -0,0 +1,73 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rsa"
+)
+
+const algRS384 = "RS384"
+
+// SignRS384 takes a RSA private key and a set of claims, and returns a
+// RS384-signed JWT containing those claims.
+//
+// VerifyRS384 can verify tokens signed by SignRS384.
+//
+// When using SignRS384 and VerifyRS384 in production, use an RSA keypair
+// generated by a tool like OpenSSL, and use at least 2048 bits. Do not leak or
+// give out the private key to any systems or people that don't need it, because
+// they will be able to generate JWTs that will be indistinguishable from the
+// ones you can generate yourself.
+//
+// RS384 is short for RSA Signature with SHA-384. It is a mechanism for message
+// authentication. By signing a set of claims with SignRS384, you have not
+// encrypted it. It is trivial for anyone to read the data stored in the return
+// value of SignRS384. All SignRS384 gives you is a signature that proves that
+// when you generated a JWT, you had a particular RSA private key on hand -- and
+// it does this without giving away what the private key is. VerifyRS384 can
+// verify the JWTs produced by SignRS384; to do this, it needs to use the public
+// key that corresponds to the private key you used in SignRS384.
+//
+// The second parameter to this function, v, should be compatible with the
+// encoding/json package of the standard library. The JSON representation of v
+// will be used as the claims part of the returned JWT.
+//
+// opts can be used to further configure how the token is signed. See
+// SignOption.
+//
+// SignRS384 will return an error wrapping ErrWeakKey if priv is smaller than
+// 2048 bits, unless opts includes WithWeakRSAKeys. Otherwise, it will return
+// an error only if calling json.Marshal on v returns an error, or if one of
+// opts rejects the claims.
+func SignRS384(priv *rsa.PrivateKey, v interface{}, opts ...SignOption) ([]byte, error) {
+	return sign(algRS384, priv.Size(), v, opts, signRSA(nil, priv, algRS384, crypto.SHA384, false, newSignConfig(opts).weakRSAKeys))
+}
+
+// VerifyRS384 verifies a JWT using a RSA public key. If the JWT is verified,
+// VerifyRS384 will serialize the claims inside the JWT into v.
+//
+// The second parameter to this function, v, should be a pointer to something
+// compatible with the encoding/json package of the standard library. If
+// verification succeeds, VerifyRS384 will deserialize the claims in the JWT
+// into v.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyRS384 will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than RS384, and ErrBadSignature if it is not signed with the private
+// key that corresponds to the public key given. It will return an error
+// wrapping ErrWeakKey if pub is smaller than 2048 bits, unless opts includes
+// WithWeakRSAKeys.
+func VerifyRS384(pub *rsa.PublicKey, s []byte, v interface{}, opts ...VerifyOption) error {
+	if err := checkClaimsTarget(v); err != nil {
+		return err
+	}
+
+	header, claims, err := verify(algRS384, s, v, opts, verifyRSA(pub, algRS384, crypto.SHA384, false, newVerifyConfig(opts).weakRSAKeys))
+	if err != nil {
+		return err
+	}
+
+	return unmarshalClaims(header, claims, v, opts)
+}
@@ -0,0 +1,64 @@
+package jwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestSignRS384(t *testing.T) {
+	for _, bits := range []int{2048, 3072} {
+		t.Run(fmt.Sprint(bits), func(t *testing.T) {
+			privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+			assert.NoError(t, err)
+
+			claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+			rs384, err := jwt.SignRS384(privateKey, claims)
+			assert.NoError(t, err)
+
+			// The signature must be exactly as long as the key's modulus, with no
+			// trailing junk.
+			parts := strings.Split(string(rs384), ".")
+			assert.Len(t, parts, 3)
+			assert.Equal(t, base64.RawURLEncoding.EncodedLen(bits/8), len(parts[2]))
+
+			var out jwt.StandardClaims
+			assert.NoError(t, jwt.VerifyRS384(&privateKey.PublicKey, rs384, &out))
+			assert.Equal(t, claims, out)
+
+			// The same is true of RS256, which shares the same key type.
+			rs256, err := jwt.SignRS256(privateKey, claims)
+			assert.NoError(t, err)
+
+			parts = strings.Split(string(rs256), ".")
+			assert.Equal(t, base64.RawURLEncoding.EncodedLen(bits/8), len(parts[2]))
+			assert.NoError(t, jwt.VerifyRS256(&privateKey.PublicKey, rs256, &out))
+
+			// Tokens from one algorithm must never be accepted by the other, even
+			// with the same key.
+			assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyRS384(&privateKey.PublicKey, rs256, &out))
+			assert.Equal(t, jwt.ErrWrongAlgorithm, jwt.VerifyRS256(&privateKey.PublicKey, rs384, &out))
+		})
+	}
+}
+
+func TestVerifyRS384(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	token, err := jwt.SignRS384(privateKey, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+
+	var claims jwt.StandardClaims
+	assert.Equal(t, jwt.ErrBadSignature, jwt.VerifyRS384(&otherKey.PublicKey, token, &claims))
+}
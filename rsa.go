@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrWeakKey is the error returned when signing or verifying a JWT with an RSA
+// key smaller than 2048 bits. RFC7518 requires RSA keys to be at least that
+// large, and smaller keys can be broken. The returned error wraps ErrWeakKey,
+// and says how large the key is.
+//
+// Use WithWeakRSAKeys to permit smaller keys in tests.
+var ErrWeakKey = errors.New("jwt: key is too weak")
+
+// WeakRSAKeysOption is the option returned by WithWeakRSAKeys. It is both a
+// SignOption and a VerifyOption.
+type WeakRSAKeysOption struct{}
+
+func (WeakRSAKeysOption) applySign(c *signConfig) {
+	c.weakRSAKeys = true
+}
+
+func (WeakRSAKeysOption) applyVerify(c *verifyConfig) {
+	c.weakRSAKeys = true
+}
+
+// WithWeakRSAKeys permits RSA keys smaller than 2048 bits, which are otherwise
+// rejected with ErrWeakKey. It can be passed to SignRS256, VerifyRS256, and
+// the other RS256, RS384, and PS256 functions.
+//
+// WithWeakRSAKeys exists for tests that use small keys because they are quick
+// to generate. Do not use it in production. Passed to VerifyAny,
+// VerifyWithKeySet, VerifyNested, or VerifyX5C, it applies to the keys given
+// by AllowRS256, AllowRS384, and AllowPS256.
+func WithWeakRSAKeys() WeakRSAKeysOption {
+	return WeakRSAKeysOption{}
+}
+
+// checkRSAKeySize returns an error wrapping ErrWeakKey if pub is too small to
+// be used with alg, unless weak is true.
+func checkRSAKeySize(alg string, pub *rsa.PublicKey, weak bool) error {
+	if weak {
+		return nil
+	}
+
+	if bits := pub.N.BitLen(); bits < minRSABits {
+		return fmt.Errorf("%w: %s requires an RSA key of at least %d bits, not %d", ErrWeakKey, alg, minRSABits, bits)
+	}
+
+	return nil
+}
+
+// pssOptions are the options used for RSA-PSS. RFC7518, Section 3.5 requires
+// that the salt be as long as the output of the hash function.
+var pssOptions = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+
+// signRSA returns a function suitable for passing to sign. The returned
+// function signs data with priv using RSASSA-PKCS1-v1_5, or RSASSA-PSS if pss
+// is true. Any randomness the signature needs is read from random, or from
+// crypto/rand.Reader if random is nil.
+//
+// Unless weak is true, the returned function returns an error wrapping
+// ErrWeakKey if priv is too small to be used with alg.
+func signRSA(random io.Reader, priv *rsa.PrivateKey, alg string, hash crypto.Hash, pss, weak bool) func(data []byte) ([]byte, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+
+	return func(data []byte) ([]byte, error) {
+		if err := checkRSAKeySize(alg, &priv.PublicKey, weak); err != nil {
+			return nil, err
+		}
+
+		h := hash.New()
+		h.Write(data)
+
+		if pss {
+			return rsa.SignPSS(random, priv, hash, h.Sum(nil), pssOptions)
+		}
+
+		return rsa.SignPKCS1v15(random, priv, hash, h.Sum(nil))
+	}
+}
+
+// signRSASigner is like signRSA, except that the signature is made by signer,
+// using RSASSA-PKCS1-v1_5. signer must have the RSA key pub. See signDigest
+// for how ctx is used.
+func signRSASigner(ctx context.Context, signer crypto.Signer, pub *rsa.PublicKey, alg string, hash crypto.Hash, weak bool) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		if err := checkRSAKeySize(alg, pub, weak); err != nil {
+			return nil, err
+		}
+
+		h := hash.New()
+		h.Write(data)
+
+		return signDigest(ctx, signer, h.Sum(nil), hash)
+	}
+}
+
+// verifyRSA returns a function suitable for passing to verify. It is the
+// counterpart of signRSA.
+func verifyRSA(pub *rsa.PublicKey, alg string, hash crypto.Hash, pss, weak bool) func(data, sig []byte) error {
+	return func(data, sig []byte) error {
+		if err := checkRSAKeySize(alg, pub, weak); err != nil {
+			return err
+		}
+
+		h := hash.New()
+		h.Write(data)
+
+		var err error
+		if pss {
+			err = rsa.VerifyPSS(pub, hash, h.Sum(nil), sig, pssOptions)
+		} else {
+			err = rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig)
+		}
+
+		if err != nil {
+			return ErrBadSignature
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInsufficientScope is the error returned by the Verify functions in this
+// package when WithRequiredScopes is used, and a JWT wasn't granted all of the
+// required scopes. The returned error wraps ErrInsufficientScope, and says
+// which scopes were missing.
+var ErrInsufficientScope = errors.New("jwt: insufficient scope")
+
+// Scopes is the set of OAuth scopes granted to a JWT.
+//
+// Identity providers represent scopes in one of two ways: as a space-delimited
+// string in a "scope" claim, as described in RFC8693, or as an array of strings
+// in a "scp" claim. Scopes accepts both forms when it is unmarshalled from
+// JSON, and it is always marshalled as a space-delimited string.
+//
+// Scopes does not pick a claim name on its own. Embed it in your own claims
+// struct alongside StandardClaims or RegisteredClaims, with whichever name your
+// identity provider uses:
+//
+//	type CustomClaims struct {
+//		jwt.RegisteredClaims
+//		Scope jwt.Scopes `json:"scope,omitempty"`
+//	}
+//
+// https://tools.ietf.org/html/rfc8693#section-4.2
+type Scopes []string
+
+// MarshalJSON implements json.Marshaler.
+func (s Scopes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.Join(s, " "))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a space-delimited JSON
+// string, an array of JSON strings, or null.
+func (s *Scopes) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, []byte("null")) {
+		*s = nil
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+
+		*s = Scopes(strings.Fields(str))
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return errors.New("jwt: scopes must be a string or an array of strings")
+	}
+
+	*s = Scopes(ss)
+	return nil
+}
+
+// Contains returns whether scope is one of the entries in s.
+func (s Scopes) Contains(scope string) bool {
+	for _, t := range s {
+		if t == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsAll returns whether every one of scopes is an entry in s. It returns
+// true if scopes is empty.
+func (s Scopes) ContainsAll(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !s.Contains(scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithRequiredScopes makes a Verify function reject JWTs that weren't granted
+// every one of scopes. Such tokens are rejected with an error wrapping
+// ErrInsufficientScope.
+//
+// The scopes granted to a JWT are read from its "scope" claim, or, if it has
+// none, from its "scp" claim, in either of the forms Scopes accepts. As with
+// WithExpectedIssuer, the check is done on the claims in the JWT, not on v.
+func WithRequiredScopes(scopes ...string) VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.requiredScopes = append(c.requiredScopes, scopes...)
+	})
+}
+
+// checkScopes checks the "scope" or "scp" claim in claims against the scopes
+// set by WithRequiredScopes.
+func (c *verifyConfig) checkScopes(claims map[string]json.RawMessage) error {
+	if len(c.requiredScopes) == 0 {
+		return nil
+	}
+
+	name := "scope"
+	if _, ok := claims[name]; !ok {
+		name = "scp"
+	}
+
+	var granted Scopes
+	if err := json.Unmarshal(orNull(claims[name]), &granted); err != nil {
+		return fmt.Errorf("jwt: cannot decode %q claim: %w", name, err)
+	}
+
+	var missing []string
+	for _, scope := range c.requiredScopes {
+		if !granted.Contains(scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %q", ErrInsufficientScope, missing)
+	}
+
+	return nil
+}
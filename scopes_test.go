@@ -0,0 +1,119 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestScopesJSON(t *testing.T) {
+	testCases := []struct {
+		in     string
+		scopes jwt.Scopes
+		out    string
+	}{
+		{`"read"`, jwt.Scopes{"read"}, `"read"`},
+		{`"read write"`, jwt.Scopes{"read", "write"}, `"read write"`},
+		{`"  read   write "`, jwt.Scopes{"read", "write"}, `"read write"`},
+		{`["read","write"]`, jwt.Scopes{"read", "write"}, `"read write"`},
+		{`""`, jwt.Scopes{}, `""`},
+		{`[]`, jwt.Scopes{}, `""`},
+		{`null`, nil, `""`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.in, func(t *testing.T) {
+			var scopes jwt.Scopes
+			assert.NoError(t, json.Unmarshal([]byte(tt.in), &scopes))
+			assert.Equal(t, tt.scopes, scopes)
+
+			out, err := json.Marshal(scopes)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, string(out))
+		})
+	}
+
+	for _, in := range []string{`1`, `true`, `{}`, `[1]`, `["read",null,1]`} {
+		var scopes jwt.Scopes
+		assert.Error(t, json.Unmarshal([]byte(in), &scopes), in)
+	}
+}
+
+func TestScopesContains(t *testing.T) {
+	scopes := jwt.Scopes{"read", "write"}
+	assert.True(t, scopes.Contains("read"))
+	assert.True(t, scopes.Contains("write"))
+	assert.False(t, scopes.Contains("READ"))
+	assert.False(t, scopes.Contains("read write"))
+	assert.False(t, jwt.Scopes(nil).Contains(""))
+
+	assert.True(t, scopes.ContainsAll())
+	assert.True(t, scopes.ContainsAll("read"))
+	assert.True(t, scopes.ContainsAll("write", "read"))
+	assert.False(t, scopes.ContainsAll("read", "admin"))
+	assert.True(t, jwt.Scopes(nil).ContainsAll())
+}
+
+func TestWithRequiredScopes(t *testing.T) {
+	secret := []byte("my secret key")
+	opt := jwt.WithRequiredScopes("read", "write")
+
+	testCases := []struct {
+		claims string
+		err    string
+	}{
+		{`{"scope":"read write admin"}`, ""},
+		{`{"scp":["write","read"]}`, ""},
+		{`{"scope":"read"}`, `jwt: insufficient scope: missing ["write"]`},
+		{`{}`, `jwt: insufficient scope: missing ["read" "write"]`},
+		{`{"scope":null,"scp":["read","write"]}`, `jwt: insufficient scope: missing ["read" "write"]`},
+		{`{"scope":"read","scp":["write"]}`, `jwt: insufficient scope: missing ["write"]`},
+		{`{"scope":1}`, `jwt: cannot decode "scope" claim: jwt: scopes must be a string or an array of strings`},
+	}
+
+	for _, tt := range testCases {
+		var claims map[string]interface{}
+		err := jwt.VerifyHS256(secret, forgeToken(`{"alg":"HS256"}`, tt.claims, hmacSHA256(secret)), &claims, opt)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.claims)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.claims)
+		}
+	}
+
+	var claims map[string]interface{}
+	err := jwt.VerifyHS256(secret, forgeToken(`{"alg":"HS256"}`, `{}`, hmacSHA256(secret)), &claims, opt)
+	assert.True(t, errors.Is(err, jwt.ErrInsufficientScope))
+
+	// Without WithRequiredScopes, scopes aren't looked at.
+	assert.NoError(t, jwt.VerifyHS256(secret, forgeToken(`{"alg":"HS256"}`, `{"scope":1}`, hmacSHA256(secret)), &claims))
+}
+
+func ExampleScopes() {
+	type CustomClaims struct {
+		jwt.StandardClaims
+		Scope jwt.Scopes `json:"scope,omitempty"` // or `json:"scp,omitempty"`
+	}
+
+	secret := []byte("my secret key")
+	token, _ := jwt.SignHS256(secret, map[string]interface{}{
+		"sub":   "jdoe@example.com",
+		"scope": "orders:read orders:write",
+	})
+
+	var claims CustomClaims
+	if err := jwt.VerifyHS256(secret, token, &claims); err != nil {
+		panic(err)
+	}
+
+	fmt.Println(claims.Scope.Contains("orders:read"))
+	fmt.Println(claims.Scope.ContainsAll("orders:read", "orders:delete"))
+	// Output:
+	//
+	// true
+	// false
+}
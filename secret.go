@@ -0,0 +1,110 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSecret is the error returned by DecodeSecret and the SecretFrom
+// functions when they are given a string they can't decode. The returned error
+// wraps ErrInvalidSecret, and says what is wrong.
+var ErrInvalidSecret = errors.New("jwt: invalid secret")
+
+// DecodeSecret decodes a secret for use with HS256 or HS512 from its text
+// representation. s may be hex, standard base64, or URL-safe base64, with or
+// without padding. Leading and trailing whitespace, such as the newline at the
+// end of a file, is ignored.
+//
+// The encoding is detected as follows:
+//
+// * If s consists only of hex digits, it is decoded as hex. Any string made of
+// hex digits is also valid base64, so a hex string is never decoded as base64.
+// Hex strings must have an even length.
+//
+// * Otherwise, if s contains "-" or "_", it is decoded as URL-safe base64.
+//
+// * Otherwise, s is decoded as standard base64.
+//
+// DecodeSecret never treats s as the raw bytes of the secret. If s is not
+// valid in the encoding it is detected as, or mixes characters from the
+// standard and URL-safe base64 alphabets, DecodeSecret returns an error
+// wrapping ErrInvalidSecret. If you know what encoding your secrets use,
+// prefer SecretFromHex, SecretFromBase64, or SecretFromBase64URL.
+//
+// DecodeSecret does not check that the secret is long enough. Use
+// CheckPrivateKey for that.
+func DecodeSecret(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidSecret)
+	}
+
+	if isHex(s) {
+		return SecretFromHex(s)
+	}
+
+	std := strings.ContainsAny(s, "+/")
+	url := strings.ContainsAny(s, "-_")
+	if std && url {
+		return nil, fmt.Errorf("%w: mixes standard and URL-safe base64", ErrInvalidSecret)
+	}
+
+	if url {
+		return SecretFromBase64URL(s)
+	}
+
+	return SecretFromBase64(s)
+}
+
+// SecretFromHex decodes a hex-encoded secret for use with HS256 or HS512. If s
+// is not valid hex, SecretFromHex returns an error wrapping ErrInvalidSecret.
+func SecretFromHex(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not hex: %v", ErrInvalidSecret, err)
+	}
+
+	return b, nil
+}
+
+// SecretFromBase64 decodes a secret encoded in standard base64, with or without
+// padding, for use with HS256 or HS512. If s is not valid standard base64,
+// SecretFromBase64 returns an error wrapping ErrInvalidSecret.
+func SecretFromBase64(s string) ([]byte, error) {
+	return decodeBase64Secret("base64", base64.RawStdEncoding, strings.TrimSpace(s))
+}
+
+// SecretFromBase64URL decodes a secret encoded in URL-safe base64, with or
+// without padding, for use with HS256 or HS512. This is the encoding that JWKs
+// use. If s is not valid URL-safe base64, SecretFromBase64URL returns an error
+// wrapping ErrInvalidSecret.
+func SecretFromBase64URL(s string) ([]byte, error) {
+	return decodeBase64Secret("URL-safe base64", base64.RawURLEncoding, strings.TrimSpace(s))
+}
+
+func decodeBase64Secret(name string, enc *base64.Encoding, s string) ([]byte, error) {
+	b, err := enc.Strict().DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not %s: %v", ErrInvalidSecret, name, err)
+	}
+
+	// Padding is optional, but if it's there it has to be right.
+	if strings.HasSuffix(s, "=") && len(s)%4 != 0 {
+		return nil, fmt.Errorf("%w: not %s: incorrect padding", ErrInvalidSecret, name)
+	}
+
+	return b, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+
+	return true
+}
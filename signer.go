@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"io"
+)
+
+// Signer is implemented by anything that can produce a signed JWT from a set of
+// claims.
+//
+// Signer exists so that helpers built on top of this package, such as the
+// webhook subpackage, can be handed a key and an algorithm without having to
+// know which algorithm is in use. Which algorithm gets used is decided entirely
+// by whoever constructs the Signer, never by the helper that calls it.
+//
+// The easiest way to get a Signer is to wrap one of the Sign functions in a
+// SignerFunc.
+//
+// Implementations must honor any opts they are given. Helpers use opts to ask
+// for things like a SignPolicy to be enforced.
+type Signer interface {
+	Sign(v interface{}, opts ...SignOption) ([]byte, error)
+}
+
+// SignerFunc adapts an ordinary function into a Signer.
+type SignerFunc func(v interface{}, opts ...SignOption) ([]byte, error)
+
+// Sign calls f(v, opts...).
+func (f SignerFunc) Sign(v interface{}, opts ...SignOption) ([]byte, error) {
+	return f(v, opts...)
+}
+
+// Verifier is implemented by anything that can verify a JWT and deserialize its
+// claims into v.
+//
+// Like Signer, Verifier exists so that helpers can verify tokens without
+// choosing an algorithm. Implementations must always verify using a fixed
+// algorithm and key; they must never let the token decide how it gets
+// verified.
+//
+// The easiest way to get a Verifier is to wrap one of the Verify functions in a
+// VerifierFunc.
+type Verifier interface {
+	Verify(s []byte, v interface{}) error
+}
+
+// VerifierFunc adapts an ordinary function into a Verifier.
+type VerifierFunc func(s []byte, v interface{}) error
+
+// Verify calls f(s, v).
+func (f VerifierFunc) Verify(s []byte, v interface{}) error {
+	return f(s, v)
+}
+
+// ContextSigner is a crypto.Signer that can be given a context when it signs.
+// Signers backed by a remote key management service should implement
+// ContextSigner, so that SignRS256Context and SignES256Context can pass along
+// deadlines and cancellation.
+//
+// SignContext must do the same thing as Sign, except that it should give up
+// and return an error once ctx is done.
+type ContextSigner interface {
+	crypto.Signer
+	SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+}
+
+// signDigest signs digest with signer. If signer is a ContextSigner, it is
+// given ctx. Otherwise, signer can't be interrupted, but its signature is
+// discarded if ctx is done by the time it returns.
+//
+// If ctx is done before or after signing, signDigest returns ctx.Err(), so
+// that a token is never produced after its context was cancelled.
+func signDigest(ctx context.Context, signer crypto.Signer, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var sig []byte
+	var err error
+	if cs, ok := signer.(ContextSigner); ok {
+		sig, err = cs.SignContext(ctx, rand.Reader, digest, opts)
+	} else {
+		sig, err = signer.Sign(rand.Reader, digest, opts)
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	return sig, err
+}
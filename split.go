@@ -0,0 +1,150 @@
+package jwt
+
+import "bytes"
+
+// Split splits a JWT into its three parts: the header, the claims, and the
+// signature, each still base64url-encoded. It does not decode or verify
+// anything. The returned slices are subslices of s, not copies.
+//
+// Spaces, tabs, and line breaks before or after the token are ignored, because
+// tokens read from files, environment variables, and the output of commands
+// usually end with a newline. Whitespace anywhere else is not.
+//
+// Split returns ErrMalformedToken unless s is exactly three non-empty parts
+// separated by periods, each made up only of the characters of the base64url
+// alphabet, without padding. The Verify functions in this package split tokens
+// using Split, so every token they accept is one that Split accepts.
+func Split(s []byte) (header, claims, sig []byte, err error) {
+	return splitParts(s, false)
+}
+
+// WithLenientBase64 makes a Verify function accept JWTs whose parts are
+// encoded with padding, or with the standard base64 alphabet ("+" and "/")
+// instead of the URL-safe one ("-" and "_"), or both. RFC7515 allows neither,
+// and by default the Verify functions reject such tokens as malformed. Only use
+// WithLenientBase64 if you must accept tokens from an issuer that gets this
+// wrong, and can't be fixed.
+//
+// The signature is still checked against the token exactly as it was sent, so
+// a token is only accepted if it was signed in the encoding it arrived in. Any
+// padding must be the right length for its part, and must come at the end of
+// it; the bits left over in the last character of a part must still be zero.
+func WithLenientBase64() VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.lenientBase64 = true
+	})
+}
+
+// splitParts is Split, except that if lenient is true, it also accepts parts
+// that WithLenientBase64 allows. The parts are returned as they are, and must
+// be passed through normalizeBase64 before they're decoded.
+func splitParts(s []byte, lenient bool) (header, claims, sig []byte, err error) {
+	header, claims, sig, ok := splitToken(s)
+	if !ok {
+		return nil, nil, nil, ErrMalformedToken
+	}
+
+	valid := isBase64URL
+	if lenient {
+		valid = isLenientBase64
+	}
+
+	for _, part := range [][]byte{header, claims, sig} {
+		if len(part) == 0 || !valid(part) {
+			return nil, nil, nil, ErrMalformedToken
+		}
+	}
+
+	return header, claims, sig, nil
+}
+
+// splitToken splits s at its periods, and returns false unless there are
+// exactly two of them. It is the part of Split that PeekHeader shares;
+// PeekHeader is more lenient than Split, because it only looks at the header.
+func splitToken(s []byte) (header, claims, sig []byte, ok bool) {
+	s = trimToken(s)
+
+	i := bytes.IndexByte(s, '.')
+	if i == -1 {
+		return nil, nil, nil, false
+	}
+
+	j := bytes.IndexByte(s[i+1:], '.')
+	if j == -1 {
+		return nil, nil, nil, false
+	}
+
+	if bytes.IndexByte(s[i+1+j+1:], '.') != -1 {
+		return nil, nil, nil, false
+	}
+
+	return s[:i], s[i+1 : i+1+j], s[i+1+j+1:], true
+}
+
+// isBase64URL returns whether b is made up only of the characters of the
+// base64url alphabet.
+//
+// This matters because the decoders in encoding/base64 skip over newlines, and
+// so would otherwise accept two different strings as the same token.
+func isBase64URL(b []byte) bool {
+	for _, c := range b {
+		if !isBase64URLChar(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isBase64URLChar returns whether c is in the base64url alphabet.
+func isBase64URLChar(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_'
+}
+
+// isLenientBase64 returns whether b is base64 in a form WithLenientBase64
+// allows: either alphabet, or a mix of the two, and padded to a multiple of four
+// characters or not padded at all.
+func isLenientBase64(b []byte) bool {
+	unpadded := bytes.TrimRight(b, "=")
+	if padding := len(b) - len(unpadded); padding > 0 {
+		if padding > 2 || len(b)%4 != 0 || len(unpadded) == 0 {
+			return false
+		}
+	}
+
+	for _, c := range unpadded {
+		if c != '+' && c != '/' && !isBase64URLChar(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeBase64 returns b, a part that isLenientBase64 accepts, in the form
+// that isBase64URL accepts. It only allocates if b isn't already in that form.
+func normalizeBase64(b []byte) []byte {
+	b = bytes.TrimRight(b, "=")
+	if bytes.IndexAny(b, "+/") == -1 {
+		return b
+	}
+
+	out := make([]byte, len(b))
+	for i, c := range b {
+		switch c {
+		case '+':
+			c = '-'
+		case '/':
+			c = '_'
+		}
+
+		out[i] = c
+	}
+
+	return out
+}
+
+// trimToken returns s without any ASCII whitespace at its start or end.
+func trimToken(s []byte) []byte {
+	return bytes.Trim(s, " \t\n\r\v\f")
+}
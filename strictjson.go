@@ -0,0 +1,241 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// WithNoDuplicateKeys makes a Verify function reject a JWT if any object in its
+// claims, at any depth, has two members with the same name. The returned error
+// wraps ErrMalformedToken.
+//
+// encoding/json, like many JSON parsers, silently keeps the last of a repeated
+// member, but not every parser does. So a JWT with claims such as
+// {"exp":9999999999,"exp":1} may expire at different times for different
+// systems that look at it. WithNoDuplicateKeys makes sure there's only one way
+// to read the claims. Names are compared the way encoding/json matches them to
+// struct fields, without regard to case, so {"sub":"a","SUB":"b"} is rejected
+// too.
+//
+// Headers with duplicate members are always rejected, with or without
+// WithNoDuplicateKeys.
+func WithNoDuplicateKeys() VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.noDuplicateKeys = true
+	})
+}
+
+// ErrUnknownClaim is the error returned by a Verify function with
+// WithStrictClaims among its options, if the claims of a JWT have a member that
+// the claims target doesn't declare. The returned error wraps ErrUnknownClaim,
+// and names the member.
+var ErrUnknownClaim = errors.New("jwt: unknown claim")
+
+// WithStrictClaims makes a Verify function reject a JWT if its claims have a
+// member that the claims target has no field for, the way
+// json.Decoder.DisallowUnknownFields does. Use it for tokens where a claim you
+// forgot to model shouldn't be silently ignored. The returned error wraps
+// ErrUnknownClaim.
+//
+// Fields are matched the same way encoding/json matches them, so fields of
+// embedded structs count, and so do fields of nested structs: with
+// WithStrictClaims, {"ctx":{"role":"admin"}} is rejected if the struct that
+// "ctx" decodes into has no "role" field. Targets that accept any member, such
+// as maps, interface{}, json.RawMessage, and types with their own UnmarshalJSON
+// method, never cause an error. With Into, a member is only unknown if none of
+// the targets declare it.
+//
+// The check is done with encoding/json, even if another codec was set with
+// SetJSONCodec; the codec is still what decodes the claims afterwards.
+func WithStrictClaims() VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.strictClaims = true
+	})
+}
+
+// unknownFieldPrefix is how encoding/json begins the errors it returns when
+// DisallowUnknownFields is on and an object has a member the target doesn't
+// declare. The rest of the error is the member's name, quoted.
+const unknownFieldPrefix = "json: unknown field "
+
+// checkUnknownClaims returns an error wrapping ErrUnknownClaim if claims have a
+// member that none of the targets in v declare.
+//
+// Each member is checked on its own, by decoding an object containing only
+// that member into a new value of each target's type. Decoding all of the
+// claims at once wouldn't do: encoding/json reports only the first error it
+// runs into, so a member of the wrong type, like a fractional "exp" that
+// decodeClaims knows how to handle, would hide any unknown members after it.
+//
+// Errors other than unknown members are left for decodeClaims to report.
+func checkUnknownClaims(claims []byte, v interface{}) error {
+	targets := []interface{}{v}
+	if c, ok := v.(*claimsTargets); ok {
+		targets = c.targets
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(claims, &members); err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		member, err := json.Marshal(map[string]json.RawMessage{name: members[name]})
+		if err != nil {
+			return nil
+		}
+
+		field, ok := unknownField(name, member, targets)
+		if !ok {
+			continue
+		}
+
+		if field == name {
+			return fmt.Errorf("%w %q", ErrUnknownClaim, name)
+		}
+
+		return fmt.Errorf("%w %q: unknown field %q", ErrUnknownClaim, name, field)
+	}
+
+	return nil
+}
+
+// unknownField decodes member, an object whose only member is called name,
+// into a new value of the type of each of targets, with unknown fields
+// disallowed. If every one of them fails because of an unknown field,
+// unknownField returns the name of that field.
+//
+// A target that complains about a field other than name does declare name, but
+// not something inside it. That complaint is more useful than "name is
+// unknown", so it's the one returned.
+func unknownField(name string, member []byte, targets []interface{}) (string, bool) {
+	field := name
+	for _, target := range targets {
+		d := json.NewDecoder(bytes.NewReader(member))
+		d.DisallowUnknownFields()
+
+		err := d.Decode(reflect.New(reflect.TypeOf(target).Elem()).Interface())
+		if err == nil || !strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+			return "", false
+		}
+
+		var f string
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(err.Error(), unknownFieldPrefix)), &f); err != nil {
+			return "", false
+		}
+
+		if f != name {
+			field = f
+		}
+	}
+
+	return field, true
+}
+
+// errDuplicateKey is returned by checkDuplicateKeys when an object has two
+// members with the same name.
+var errDuplicateKey = errors.New("jwt: duplicate key in JSON object")
+
+// checkDuplicateKeys returns an error if data is not valid JSON, or if any
+// object in data, at any depth, has two members whose names are equal.
+//
+// Names are compared case-insensitively, because encoding/json matches member
+// names to struct fields case-insensitively. If we compared names exactly, then
+// {"alg":"none","ALG":"HS256"} would slip through, and encoding/json would
+// still see two values for the same field.
+//
+// This is done iteratively rather than recursively, so that deeply nested
+// input cannot exhaust the stack.
+func checkDuplicateKeys(data []byte) error {
+	type frame struct {
+		keys    map[string]struct{} // nil for arrays
+		wantKey bool                // for objects, whether the next token is a key
+	}
+
+	var stack []frame
+	d := json.NewDecoder(bytes.NewReader(data))
+
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if s, ok := t.(string); ok && len(stack) > 0 && stack[len(stack)-1].wantKey {
+			key := foldKey(s)
+			top := &stack[len(stack)-1]
+			if _, ok := top.keys[key]; ok {
+				return errDuplicateKey
+			}
+
+			top.keys[key] = struct{}{}
+			top.wantKey = false
+			continue
+		}
+
+		switch t {
+		case json.Delim('{'):
+			stack = append(stack, frame{keys: map[string]struct{}{}, wantKey: true})
+			continue
+		case json.Delim('['):
+			stack = append(stack, frame{})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+
+		// We just finished reading a value. If that value was inside an object,
+		// then a key comes next.
+		if len(stack) > 0 && stack[len(stack)-1].keys != nil {
+			stack[len(stack)-1].wantKey = true
+		}
+	}
+}
+
+// foldKey maps s to a canonical representative of all the strings that are
+// equal to s under Unicode case-folding.
+func foldKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+
+		return min
+	}, s)
+}
+
+// checkJSON returns nil if data is valid JSON, and otherwise the
+// *json.SyntaxError that says why it isn't.
+func checkJSON(data []byte) error {
+	if json.Valid(data) {
+		return nil
+	}
+
+	var v interface{}
+	return json.Unmarshal(data, &v)
+}
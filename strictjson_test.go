@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDuplicateKeys(t *testing.T) {
+	ok := []string{
+		`{}`,
+		`[]`,
+		`true`,
+		`"a"`,
+		`{"a":1,"b":2}`,
+		`{"a":"a","b":"a"}`,
+		`{"a":{"a":1},"b":{"a":1}}`,
+		`{"a":[{"a":1},{"a":1}]}`,
+		`[{"a":1},{"a":1}]`,
+		`{"a":[],"b":{},"c":[[]]}`,
+		`{"a":{"b":{}},"b":1}`,
+	}
+
+	for _, s := range ok {
+		assert.NoError(t, checkDuplicateKeys([]byte(s)), s)
+	}
+
+	duplicate := []string{
+		`{"a":1,"a":2}`,
+		`{"a":1,"A":2}`,
+		`{"a":{"b":1,"b":1}}`,
+		`{"a":[{"b":1,"b":1}]}`,
+		`[{"a":1,"a":1}]`,
+		`{"a":{"b":{}},"a":1}`,
+		`{"k":1,"K":1}`, // U+212A is the Kelvin sign, which folds to "k"
+	}
+
+	for _, s := range duplicate {
+		assert.Equal(t, errDuplicateKey, checkDuplicateKeys([]byte(s)), s)
+	}
+
+	assert.Error(t, checkDuplicateKeys([]byte(`{"a":}`)))
+	assert.Error(t, checkDuplicateKeys([]byte(`{"a":1`)))
+}
+
+func TestWithNoDuplicateKeys(t *testing.T) {
+	secret := []byte("my secret key")
+
+	testCases := []struct {
+		claims    string
+		duplicate bool
+	}{
+		{`{"sub":"jdoe","exp":9999999999}`, false},
+		{`{"aud":["a","a"]}`, false},
+		{`{"a":{"x":1},"b":{"x":1}}`, false},
+		{`{"exp":9999999999,"exp":1}`, true},
+		{`{"sub":"jdoe","SUB":"admin"}`, true},
+		{`{"ctx":{"role":"user","role":"admin"}}`, true},
+		{`{"ctx":[{"role":"user"},{"role":"user","role":"admin"}]}`, true},
+		{`{"a":{"b":{"c":{"d":1,"d":2}}}}`, true},
+	}
+
+	for _, tt := range testCases {
+		token, err := SignHS256(secret, json.RawMessage(tt.claims))
+		assert.NoError(t, err)
+
+		var claims map[string]interface{}
+		err = VerifyHS256(secret, token, &claims, WithNoDuplicateKeys())
+		if !tt.duplicate {
+			assert.NoError(t, err, tt.claims)
+			continue
+		}
+
+		assert.True(t, errors.Is(err, ErrMalformedToken), tt.claims)
+		assert.True(t, errors.Is(err, errDuplicateKey), tt.claims)
+
+		// Without the option, encoding/json keeps the last value.
+		assert.NoError(t, VerifyHS256(secret, token, &claims), tt.claims)
+	}
+
+	// Headers are checked either way.
+	token, err := SignHS256(secret, StandardClaims{}, WithHeaderParams(map[string]interface{}{"x": json.RawMessage(`{"y":1,"y":2}`)}))
+	assert.NoError(t, err)
+
+	var claims StandardClaims
+	assert.True(t, errors.Is(VerifyHS256(secret, token, &claims), ErrMalformedToken))
+}
+
+func TestWithStrictClaims(t *testing.T) {
+	secret := []byte("my secret key")
+
+	type role struct {
+		Name string `json:"name"`
+	}
+
+	type claims struct {
+		StandardClaims
+		Role  role   `json:"role"`
+		Email string `json:"email"`
+	}
+
+	testCases := []struct {
+		claims string
+		err    string
+	}{
+		{`{"sub":"jdoe","email":"jdoe@example.com"}`, ""},
+		{`{"sub":"jdoe","role":{"name":"user"}}`, ""},
+		{`{"SUB":"jdoe","Email":"jdoe@example.com"}`, ""},
+		{`{"exp":9999999999.5,"iat":1}`, ""},
+		{`{"sub":"jdoe","admin":true}`, `jwt: unknown claim "admin"`},
+		{`{"zzz":1,"aaa":1}`, `jwt: unknown claim "aaa"`},
+		{`{"exp":9999999999.5,"zzz":1}`, `jwt: unknown claim "zzz"`},
+		{`{"role":{"name":"user","level":9}}`, `jwt: unknown claim "role": unknown field "level"`},
+	}
+
+	for _, tt := range testCases {
+		token, err := SignHS256(secret, json.RawMessage(tt.claims))
+		assert.NoError(t, err)
+
+		var c claims
+		err = VerifyHS256(secret, token, &c, WithStrictClaims())
+		if tt.err == "" {
+			assert.NoError(t, err, tt.claims)
+		} else {
+			assert.True(t, errors.Is(err, ErrUnknownClaim), tt.claims)
+			assert.EqualError(t, err, tt.err, tt.claims)
+		}
+
+		// Without the option, unknown members are ignored.
+		assert.NoError(t, VerifyHS256(secret, token, &c), tt.claims)
+
+		// Targets that take any member never complain.
+		var m map[string]interface{}
+		assert.NoError(t, VerifyHS256(secret, token, &m, WithStrictClaims()), tt.claims)
+
+		var raw json.RawMessage
+		assert.NoError(t, VerifyHS256(secret, token, &raw, WithStrictClaims()), tt.claims)
+	}
+
+	token, err := SignHS256(secret, json.RawMessage(`{"sub":"jdoe","email":"jdoe@example.com","admin":true}`))
+	assert.NoError(t, err)
+
+	// With Into, a member only has to be declared by one of the targets.
+	var std StandardClaims
+	var extra struct {
+		Email string `json:"email"`
+		Admin bool   `json:"admin"`
+	}
+
+	assert.NoError(t, VerifyHS256(secret, token, Into(&std, &extra), WithStrictClaims()))
+	assert.Equal(t, "jdoe", std.Subject)
+	assert.True(t, extra.Admin)
+
+	var c claims
+	err = VerifyHS256(secret, token, Into(&std, &c), WithStrictClaims())
+	assert.True(t, errors.Is(err, ErrUnknownClaim))
+	assert.EqualError(t, err, `jwt: unknown claim "admin"`)
+
+	// Strict claims can't be checked without a claims target.
+	assert.True(t, errors.Is(VerifyHS256(secret, token, nil, WithStrictClaims()), ErrInvalidClaimsTarget))
+}
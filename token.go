@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+)
+
+// ErrUnverifiedToken is the error returned by (*Token).Claims when none of the
+// Token's Verify methods has succeeded.
+var ErrUnverifiedToken = errors.New("jwt: token has not been verified")
+
+// Token is a JWT that has been split into its parts and decoded, but not
+// necessarily verified. Construct one using Parse.
+//
+// A Token is for inspecting a JWT -- logging what a client sent, or picking a
+// key based on its "kid" -- and then verifying it. Until one of its Verify
+// methods succeeds, everything in a Token is UNTRUSTED, in the same way that
+// what PeekHeader returns is. The only way to get claims you can trust out of
+// a Token is to verify it, and then call Claims.
+//
+// A Token is not safe for concurrent use.
+type Token struct {
+	// Header is the decoded header of the token. It is UNTRUSTED until the
+	// token is verified. See PeekHeader for what it is safe to use it for.
+	Header Header
+
+	// RawClaims is the claims of the token, decoded from base64 but not from
+	// JSON. It is UNTRUSTED, even once the token is verified; use Claims to
+	// decode claims you can trust.
+	RawClaims []byte
+
+	// Signature is the signature of the token, decoded from base64.
+	Signature []byte
+
+	// SigningInput is the part of the token that Signature is a signature of:
+	// the encoded header, a period, and the encoded claims.
+	SigningInput []byte
+
+	raw    []byte          // the token passed to Parse, trimmed of whitespace
+	claims json.RawMessage // the verified claims; nil until verified
+}
+
+// Parse splits a JWT into its parts and decodes them, without verifying the
+// JWT. Call one of the Verify methods of the returned Token to verify it.
+//
+// Parse returns an error wrapping ErrMalformedToken unless s is a JWT that
+// Split accepts, whose header is a JSON object PeekHeader would accept, and
+// whose claims are base64url-encoded JSON.
+func Parse(s []byte) (*Token, error) {
+	s = trimToken(s)
+	encodedHeader, encodedClaims, encodedSignature, err := Split(s)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedHeader, _, err := decodeHeaderPart(nil, encodedHeader)
+	if err != nil {
+		return nil, malformedToken("header", err)
+	}
+
+	var h Header
+	if err := json.Unmarshal(decodedHeader, &h); err != nil {
+		return nil, malformedToken("header", err)
+	}
+
+	claims, err := decodeNewPart(encodedClaims)
+	if err != nil {
+		return nil, malformedToken("claims", err)
+	}
+
+	if err := checkJSON(claims); err != nil {
+		return nil, malformedToken("claims", err)
+	}
+
+	sig, err := decodeNewPart(encodedSignature)
+	if err != nil {
+		return nil, malformedToken("signature", err)
+	}
+
+	// Copy s, so that changes to s after Parse returns can't change what gets
+	// verified.
+	raw := append([]byte(nil), s...)
+
+	return &Token{
+		Header:       h,
+		RawClaims:    claims,
+		Signature:    sig,
+		SigningInput: raw[:len(encodedHeader)+1+len(encodedClaims)],
+		raw:          raw,
+	}, nil
+}
+
+// VerifyHS256 verifies the token using a secret. It verifies the token the
+// same way VerifyHS256 does, and takes the same opts.
+//
+// If VerifyHS256 succeeds, Claims can be used to decode the token's claims.
+// If it fails, the token is left as it was.
+func (t *Token) VerifyHS256(secret []byte, opts ...VerifyOption) error {
+	return t.verify(func(claims *json.RawMessage) error {
+		return VerifyHS256(secret, t.raw, claims, opts...)
+	})
+}
+
+// VerifyRS256 is like VerifyHS256, except that it verifies the token using an
+// RSA public key, the same way VerifyRS256 does.
+func (t *Token) VerifyRS256(pub *rsa.PublicKey, opts ...VerifyOption) error {
+	return t.verify(func(claims *json.RawMessage) error {
+		return VerifyRS256(pub, t.raw, claims, opts...)
+	})
+}
+
+// VerifyES256 is like VerifyHS256, except that it verifies the token using an
+// ECDSA public key, the same way VerifyES256 does.
+func (t *Token) VerifyES256(pub *ecdsa.PublicKey, opts ...VerifyOption) error {
+	return t.verify(func(claims *json.RawMessage) error {
+		return VerifyES256(pub, t.raw, claims, opts...)
+	})
+}
+
+// VerifyWith is like VerifyHS256, except that it verifies the token using
+// verifier. This is how to verify a Token with an algorithm that Token has no
+// method for.
+func (t *Token) VerifyWith(verifier Verifier) error {
+	return t.verify(func(claims *json.RawMessage) error {
+		return verifier.Verify(t.raw, claims)
+	})
+}
+
+// Verified returns whether one of the Verify methods of t has succeeded.
+func (t *Token) Verified() bool {
+	return t.claims != nil
+}
+
+// Claims decodes the claims of t into v, the same way the Verify functions in
+// this package do. If v implements Validator, its Validate method is called.
+//
+// Claims returns ErrUnverifiedToken if t has not been verified.
+func (t *Token) Claims(v interface{}) error {
+	if !t.Verified() {
+		return ErrUnverifiedToken
+	}
+
+	if err := checkClaimsPointer(v); err != nil {
+		return err
+	}
+
+	// decodeClaims hands *json.RawMessage targets the slice it's given, so
+	// give it a copy that the caller is free to modify.
+	if err := decodeClaims(append([]byte(nil), t.claims...), v, false, false); err != nil {
+		return err
+	}
+
+	return validateClaims(v)
+}
+
+// verify calls fn, which must verify t.raw and decode its claims into the
+// given json.RawMessage. If fn succeeds, t is marked as verified.
+func (t *Token) verify(fn func(claims *json.RawMessage) error) error {
+	var claims json.RawMessage
+	if err := fn(&claims); err != nil {
+		return err
+	}
+
+	t.claims = claims
+	return nil
+}
@@ -0,0 +1,298 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TransportOption configures a Transport. WithRequestClaims, WithSignOptions,
+// WithNow, and WithClock are TransportOptions.
+type TransportOption interface {
+	applyTransport(*Transport)
+}
+
+// transportOptionFunc adapts a function into a TransportOption.
+type transportOptionFunc func(*Transport)
+
+func (f transportOptionFunc) applyTransport(t *Transport) {
+	f(t)
+}
+
+func (o NowOption) applyTransport(t *Transport) {
+	t.now = o.now
+}
+
+// WithRequestClaims makes a Transport call fn before sending each request, so
+// that the token attached to the request can depend on the request. fn gets
+// the request, and a fresh copy of the Transport's claims, decoded into a map;
+// it may change the map however it likes. Numbers in the map are json.Number,
+// so that they are sent exactly as they were given to NewTransport. For
+// instance, to set "aud" to the host a request is being sent to:
+//
+//	jwt.WithRequestClaims(func(r *http.Request, claims map[string]interface{}) {
+//		claims["aud"] = "https://" + r.URL.Host
+//	})
+//
+// Tokens are cached by the claims fn leaves in the map, so requests that end
+// up with the same claims share a token. fn should not modify r.
+func WithRequestClaims(fn func(r *http.Request, claims map[string]interface{})) TransportOption {
+	return transportOptionFunc(func(t *Transport) {
+		t.requestClaims = fn
+	})
+}
+
+// WithSignOptions makes a Transport pass opts to its Signer every time it signs
+// a token. For instance, to make sure a Transport never produces a token that
+// lives longer than a minute:
+//
+//	jwt.WithSignOptions(jwt.WithSignPolicy(jwt.SignPolicy{MaxTTL: time.Minute}))
+//
+// A Transport sets "iat" and "exp" itself, so opts should not include options
+// like WithIssuedAtNow.
+func WithSignOptions(opts ...SignOption) TransportOption {
+	return transportOptionFunc(func(t *Transport) {
+		t.signOpts = append(t.signOpts, opts...)
+	})
+}
+
+// Transport is an http.RoundTripper that attaches a short-lived signed JWT to
+// each request it sends, in an "Authorization: Bearer" header. It is for
+// service-to-service authentication, where the service being called verifies
+// tokens signed by its callers. Construct one using NewTransport.
+//
+// A Transport doesn't sign a token for every request. It caches the token it
+// signs, and signs a new one only once the cached one has used up between 75%
+// and 90% of its lifetime. The point in that range is chosen at random for
+// each token, so that a fleet of services started at the same moment don't all
+// sign new tokens at the same moment either.
+//
+// A Transport is safe for concurrent use, and doesn't hold any locks while it
+// signs. Requests that need a new token while another request is signing one
+// with the same claims use the cached token if it hasn't yet expired, and
+// otherwise wait for the new token, rather than signing their own.
+type Transport struct {
+	base          http.RoundTripper
+	signer        Signer
+	claims        []byte
+	claimsErr     error
+	ttl           time.Duration
+	requestClaims func(r *http.Request, claims map[string]interface{})
+	signOpts      []SignOption
+	now           func() time.Time
+
+	mu      sync.Mutex
+	tokens  map[string]*transportToken   // keyed by the JSON claims, sans "iat" and "exp"
+	signing map[string]*transportSigning // keyed like tokens
+	rand    *rand.Rand
+}
+
+// transportToken is a token cached by a Transport.
+type transportToken struct {
+	header    string // "Bearer " followed by the token
+	refreshAt time.Time
+	expiresAt time.Time
+}
+
+// transportSigning is a token a Transport is in the middle of signing. done
+// is closed once token or err is set.
+type transportSigning struct {
+	done  chan struct{}
+	token *transportToken
+	err   error
+}
+
+// NewTransport returns a Transport that sends requests using base, after
+// adding a token signed by signer to each of them. If base is nil,
+// http.DefaultTransport is used instead.
+//
+// Each token carries the claims in claims, which must encode as a JSON object,
+// plus an "iat" of the time the token was signed and an "exp" ttl after that.
+// Any "iat" or "exp" in claims is replaced. claims is encoded once, by
+// NewTransport; if it can't be encoded as a JSON object, every request the
+// Transport sends fails with an error saying so.
+//
+// The algorithm and key tokens are signed with are up to signer. See
+// SignerFunc.
+//
+// NewTransport panics if ttl isn't positive, because every token it signed
+// would have expired before it could be sent.
+func NewTransport(base http.RoundTripper, signer Signer, claims interface{}, ttl time.Duration, opts ...TransportOption) *Transport {
+	if ttl <= 0 {
+		panic(fmt.Sprintf("jwt: NewTransport requires a positive ttl, but ttl is %v", ttl))
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &Transport{
+		base:    base,
+		signer:  signer,
+		ttl:     ttl,
+		now:     time.Now,
+		tokens:  map[string]*transportToken{},
+		signing: map[string]*transportSigning{},
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	t.claims, t.claimsErr = encodeTransportClaims(claims)
+
+	for _, opt := range opts {
+		opt.applyTransport(t)
+	}
+
+	return t
+}
+
+// encodeTransportClaims encodes claims as a JSON object, with its members in
+// a consistent order.
+func encodeTransportClaims(claims interface{}) ([]byte, error) {
+	data, err := marshalClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil || m == nil {
+		return nil, fmt.Errorf("jwt: Transport claims must encode as a JSON object, not %s", data)
+	}
+
+	return json.Marshal(m)
+}
+
+// RoundTrip implements http.RoundTripper. It sends a copy of r, with an
+// Authorization header carrying a token, using the Transport's base
+// http.RoundTripper. r itself is not modified.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	header, err := t.authorization(r)
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", header)
+	return t.base.RoundTrip(r)
+}
+
+// authorization returns the Authorization header to send with r, signing a new
+// token if there isn't a fresh one cached.
+func (t *Transport) authorization(r *http.Request) (string, error) {
+	if t.claimsErr != nil {
+		return "", t.claimsErr
+	}
+
+	claims := t.claims
+	if t.requestClaims != nil {
+		var m map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(t.claims))
+		d.UseNumber()
+		if err := d.Decode(&m); err != nil {
+			return "", err
+		}
+
+		t.requestClaims(r, m)
+
+		var err error
+		if claims, err = json.Marshal(m); err != nil {
+			return "", err
+		}
+	}
+
+	key := string(claims)
+
+	t.mu.Lock()
+	now := t.now()
+	cached, ok := t.tokens[key]
+	if ok && now.Before(cached.refreshAt) {
+		t.mu.Unlock()
+		return cached.header, nil
+	}
+
+	// Another request is already signing a token with these claims. Use the
+	// cached token while it's still valid, and otherwise wait for the new one.
+	if s, signing := t.signing[key]; signing {
+		t.mu.Unlock()
+		if ok && now.Before(cached.expiresAt) {
+			return cached.header, nil
+		}
+
+		select {
+		case <-s.done:
+		case <-r.Context().Done():
+			return "", r.Context().Err()
+		}
+
+		if s.err != nil {
+			return "", s.err
+		}
+
+		return s.token.header, nil
+	}
+
+	s := &transportSigning{done: make(chan struct{})}
+	t.signing[key] = s
+	jitter := t.rand.Float64()
+	t.mu.Unlock()
+
+	s.token, s.err = t.sign(claims, now, jitter)
+
+	t.mu.Lock()
+	delete(t.signing, key)
+	if s.err == nil {
+		// Drop any expired tokens, so that the cache doesn't grow without
+		// bound when WithRequestClaims produces many different claims.
+		for k, cached := range t.tokens {
+			if !now.Before(cached.expiresAt) {
+				delete(t.tokens, k)
+			}
+		}
+
+		t.tokens[key] = s.token
+	}
+	t.mu.Unlock()
+	close(s.done)
+
+	if s.err != nil {
+		return "", s.err
+	}
+
+	return s.token.header, nil
+}
+
+// sign signs a token with the JSON object claims, issued at now. jitter, which
+// is in [0, 1), decides how far through the token's lifetime it is refreshed.
+func (t *Transport) sign(claims []byte, now time.Time, jitter float64) (*transportToken, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(claims, &m); err != nil {
+		return nil, err
+	}
+
+	exp := now.Add(t.ttl)
+	m["iat"] = json.RawMessage(strconv.FormatInt(now.Unix(), 10))
+	m["exp"] = json.RawMessage(strconv.FormatInt(exp.Unix(), 10))
+
+	s, err := t.signer.Sign(m, t.signOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh somewhere between 75% and 90% of the way through the token's
+	// lifetime.
+	refresh := time.Duration((0.75 + 0.15*jitter) * float64(t.ttl))
+
+	return &transportToken{
+		header:    "Bearer " + string(s),
+		refreshAt: now.Add(refresh),
+		expiresAt: exp,
+	}, nil
+}
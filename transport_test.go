@@ -0,0 +1,280 @@
+package jwt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// roundTripperFunc adapts a function into an http.RoundTripper.
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// recordingTransport is an http.RoundTripper that records the Authorization
+// header of each request it's given, instead of sending it.
+type recordingTransport struct {
+	mu      sync.Mutex
+	headers []string
+}
+
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.headers = append(t.headers, r.Header.Get("Authorization"))
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+}
+
+// hs256Signer is an HS256 jwt.Signer that counts how many tokens it has signed.
+type hs256Signer struct {
+	n int32
+}
+
+func (s *hs256Signer) Sign(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+	atomic.AddInt32(&s.n, 1)
+	return jwt.SignHS256([]byte("my secret key"), v, opts...)
+}
+
+func TestTransport(t *testing.T) {
+	secret := []byte("my secret key")
+
+	claimsOf := func(t *testing.T, header string) map[string]interface{} {
+		var claims map[string]interface{}
+		assert.NoError(t, jwt.VerifyHS256(secret, []byte(header[len("Bearer "):]), &claims))
+		return claims
+	}
+
+	t.Run("attaches a token", func(t *testing.T) {
+		base := &recordingTransport{}
+		now := time.Unix(1500000000, 0)
+		transport := jwt.NewTransport(base, &hs256Signer{}, jwt.StandardClaims{Issuer: "billing"}, time.Minute, jwt.WithNow(func() time.Time { return now }))
+
+		r := httptest.NewRequest("GET", "https://example.com/", nil)
+		_, err := transport.RoundTrip(r)
+		assert.NoError(t, err)
+
+		// The request passed in is left alone.
+		assert.Equal(t, "", r.Header.Get("Authorization"))
+
+		assert.Len(t, base.headers, 1)
+		assert.Equal(t, map[string]interface{}{"iss": "billing", "iat": 1500000000.0, "exp": 1500000060.0}, claimsOf(t, base.headers[0]))
+	})
+
+	t.Run("re-signs before expiry", func(t *testing.T) {
+		base := &recordingTransport{}
+		signer := &hs256Signer{}
+		start := time.Unix(1500000000, 0)
+		now := start
+		transport := jwt.NewTransport(base, signer, map[string]string{"iss": "billing"}, 100*time.Second, jwt.WithNow(func() time.Time { return now }))
+
+		send := func() {
+			_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://example.com/", nil))
+			assert.NoError(t, err)
+		}
+
+		// Up to 75% of the way through the token's lifetime, the token is
+		// always reused.
+		for ; now.Before(start.Add(75 * time.Second)); now = now.Add(5 * time.Second) {
+			send()
+		}
+
+		assert.Equal(t, int32(1), signer.n)
+		for _, h := range base.headers {
+			assert.Equal(t, base.headers[0], h)
+		}
+
+		// By 90% of the way, the token has been replaced.
+		for ; now.Before(start.Add(91 * time.Second)); now = now.Add(time.Second) {
+			send()
+		}
+
+		assert.Equal(t, int32(2), signer.n)
+		assert.NotEqual(t, base.headers[0], base.headers[len(base.headers)-1])
+	})
+
+	t.Run("per-request claims", func(t *testing.T) {
+		base := &recordingTransport{}
+		signer := &hs256Signer{}
+		transport := jwt.NewTransport(base, signer, jwt.StandardClaims{Issuer: "billing"}, time.Minute, jwt.WithRequestClaims(func(r *http.Request, claims map[string]interface{}) {
+			claims["aud"] = "https://" + r.URL.Host
+		}))
+
+		for _, url := range []string{"https://a.example.com/x", "https://b.example.com/", "https://a.example.com/y"} {
+			_, err := transport.RoundTrip(httptest.NewRequest("GET", url, nil))
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, "https://a.example.com", claimsOf(t, base.headers[0])["aud"])
+		assert.Equal(t, "https://b.example.com", claimsOf(t, base.headers[1])["aud"])
+		assert.Equal(t, base.headers[0], base.headers[2])
+		assert.Equal(t, int32(2), signer.n)
+	})
+
+	t.Run("per-request claims keep numbers exact", func(t *testing.T) {
+		if os.Getenv(fakeCodecEnv) != "" {
+			t.Skip("the codec decides how numbers are decoded")
+		}
+
+		base := &recordingTransport{}
+		transport := jwt.NewTransport(base, &hs256Signer{}, json.RawMessage(`{"uid":9007199254740993}`), time.Minute, jwt.WithRequestClaims(func(r *http.Request, claims map[string]interface{}) {
+			claims["aud"] = "https://" + r.URL.Host
+		}))
+
+		_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://example.com/", nil))
+		assert.NoError(t, err)
+
+		var claims map[string]interface{}
+		assert.NoError(t, jwt.VerifyHS256(secret, []byte(base.headers[0][len("Bearer "):]), &claims, jwt.WithJSONNumber()))
+		assert.Equal(t, json.Number("9007199254740993"), claims["uid"])
+	})
+
+	t.Run("signs without holding a lock", func(t *testing.T) {
+		base := &recordingTransport{}
+		signing := make(chan struct{})
+		unblock := make(chan struct{})
+		slow := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+			if string(v.(map[string]json.RawMessage)["aud"]) == `"https://slow.example.com"` {
+				close(signing)
+				<-unblock
+			}
+
+			return jwt.SignHS256(secret, v, opts...)
+		})
+
+		start := time.Unix(1500000000, 0)
+		var now atomic.Value
+		now.Store(start)
+		transport := jwt.NewTransport(base, slow, map[string]string{}, 100*time.Second, jwt.WithNow(func() time.Time { return now.Load().(time.Time) }), jwt.WithRequestClaims(func(r *http.Request, claims map[string]interface{}) {
+			claims["aud"] = "https://" + r.URL.Host
+		}))
+
+		// A token for other claims can be signed while one is being signed
+		// slowly.
+		done := make(chan error)
+		go func() {
+			_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://slow.example.com/", nil))
+			done <- err
+		}()
+
+		<-signing
+		_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://fast.example.com/", nil))
+		assert.NoError(t, err)
+
+		// A request that needs the token being signed waits for it, unless its
+		// context is done first.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = transport.RoundTrip(httptest.NewRequest("GET", "https://slow.example.com/", nil).WithContext(ctx))
+		assert.Equal(t, context.Canceled, err)
+
+		close(unblock)
+		assert.NoError(t, <-done)
+
+		// While a token is being refreshed, other requests use the cached one
+		// if it hasn't yet expired.
+		signing = make(chan struct{})
+		unblock = make(chan struct{})
+		now.Store(start.Add(95 * time.Second))
+		go func() {
+			_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://slow.example.com/", nil))
+			done <- err
+		}()
+
+		<-signing
+		_, err = transport.RoundTrip(httptest.NewRequest("GET", "https://slow.example.com/", nil))
+		assert.NoError(t, err)
+
+		close(unblock)
+		assert.NoError(t, <-done)
+
+		assert.Len(t, base.headers, 4)
+		assert.Equal(t, base.headers[1], base.headers[2])
+		assert.NotEqual(t, base.headers[1], base.headers[3])
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		base := &recordingTransport{}
+		signer := &hs256Signer{}
+		transport := jwt.NewTransport(base, signer, map[string]string{"iss": "billing"}, time.Hour)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://example.com/", nil))
+				assert.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, int32(1), signer.n)
+		assert.Len(t, base.headers, 50)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			t.Fatal("request should not have been sent")
+			return nil, nil
+		})
+
+		transport := jwt.NewTransport(base, &hs256Signer{}, []string{"not an object"}, time.Minute)
+		_, err := transport.RoundTrip(httptest.NewRequest("GET", "https://example.com/", nil))
+		assert.EqualError(t, err, `jwt: Transport claims must encode as a JSON object, not ["not an object"]`)
+
+		errSign := errors.New("sign failed")
+		failing := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+			return nil, errSign
+		})
+
+		transport = jwt.NewTransport(base, failing, map[string]string{}, time.Minute)
+		_, err = transport.RoundTrip(httptest.NewRequest("GET", "https://example.com/", nil))
+		assert.Equal(t, errSign, err)
+	})
+
+	t.Run("requires a positive ttl", func(t *testing.T) {
+		assert.PanicsWithValue(t, "jwt: NewTransport requires a positive ttl, but ttl is 0s", func() {
+			jwt.NewTransport(nil, &hs256Signer{}, map[string]string{}, 0)
+		})
+
+		assert.PanicsWithValue(t, "jwt: NewTransport requires a positive ttl, but ttl is -1m0s", func() {
+			jwt.NewTransport(nil, &hs256Signer{}, map[string]string{}, -time.Minute)
+		})
+	})
+}
+
+func ExampleNewTransport() {
+	secret := []byte("my secret key")
+
+	server := httptest.NewServer(jwt.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := jwt.StandardClaimsFromContext(r.Context())
+		fmt.Println(claims.Issuer)
+	}), jwt.WithHS256Secret(secret)))
+	defer server.Close()
+
+	signer := jwt.SignerFunc(func(v interface{}, opts ...jwt.SignOption) ([]byte, error) {
+		return jwt.SignHS256(secret, v, opts...)
+	})
+
+	client := &http.Client{Transport: jwt.NewTransport(nil, signer, jwt.StandardClaims{Issuer: "billing"}, 5*time.Minute)}
+	if _, err := client.Get(server.URL); err != nil {
+		panic(err)
+	}
+	// Output:
+	//
+	// billing
+}
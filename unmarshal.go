@@ -0,0 +1,386 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithLenientNumericDates makes a Verify function accept "exp", "nbf", and
+// "iat" claims that are encoded as JSON strings containing a number, such as
+// "exp":"1300819380". Such claims are decoded as if they were JSON numbers.
+//
+// RFC7519 requires these claims to be numbers, and by default the Verify
+// functions in this package reject tokens that encode them as strings. Only use
+// WithLenientNumericDates if you must accept tokens from an issuer that gets
+// this wrong.
+//
+// Strings that do not contain a number, such as "exp":"tomorrow", are still
+// rejected.
+func WithLenientNumericDates() VerifyOption {
+	return verifyOptionFunc(func(c *verifyConfig) {
+		c.lenientNumericDates = true
+	})
+}
+
+// ErrInvalidClaimsTarget is the error returned by the Verify functions in this
+// package when the value they are asked to decode the claims into is not a
+// non-nil pointer, and so could never be decoded into. The returned error wraps
+// ErrInvalidClaimsTarget, and says what the value was.
+//
+// The Verify functions check for this before doing anything else, so it is
+// returned no matter what token you pass them:
+//
+//	var claims jwt.StandardClaims
+//	err := jwt.VerifyHS256(secret, token, claims) // should be &claims
+//	errors.Is(err, jwt.ErrInvalidClaimsTarget)     // true
+//
+// A plain nil, rather than a nil pointer, is not an invalid target: it means
+// that only the signature should be verified. See VerifyHS256. Passing nil
+// along with options that check claims, such as WithExpectedIssuer, is an
+// error wrapping ErrInvalidClaimsTarget, but one that's only returned once
+// the signature has been verified.
+var ErrInvalidClaimsTarget = errors.New("jwt: claims target must be a non-nil pointer")
+
+// checkClaimsTarget returns an error wrapping ErrInvalidClaimsTarget if v is
+// neither nil nor a non-nil pointer.
+func checkClaimsTarget(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+
+	return checkClaimsPointer(v)
+}
+
+// checkClaimsPointer returns an error wrapping ErrInvalidClaimsTarget if v is
+// not a non-nil pointer. Each of the targets passed to Into must be one; nil
+// means "no claims" only when it's the whole target.
+func checkClaimsPointer(v interface{}) error {
+	if c, ok := v.(*claimsTargets); ok {
+		return c.each(checkClaimsPointer)
+	}
+
+	if v == nil {
+		return fmt.Errorf("%w, not nil", ErrInvalidClaimsTarget)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("%w, not a %T", ErrInvalidClaimsTarget, v)
+	}
+
+	if rv.IsNil() {
+		return fmt.Errorf("%w, not a nil %T", ErrInvalidClaimsTarget, v)
+	}
+
+	return nil
+}
+
+// unmarshalClaims decodes the claims of a verified JWT into v, validates them
+// if v implements Validator, and then checks them against any ReplayStore in
+// opts. All of the Verify functions in this package use it, instead of calling
+// json.Unmarshal directly.
+//
+// header is the verified JWT's header. It's only decoded if opts ask for it.
+func unmarshalClaims(header, claims []byte, v interface{}, opts []VerifyOption) error {
+	c := newVerifyConfig(opts)
+	return c.unmarshalClaims(header, claims, v, false)
+}
+
+// unmarshalClaims is like the function of the same name, for callers that
+// have already applied their options to c. shared is passed on to
+// decodeClaims.
+func (c *verifyConfig) unmarshalClaims(header, claims []byte, v interface{}, shared bool) error {
+	if c.header != nil {
+		if err := json.Unmarshal(header, c.header); err != nil {
+			return malformedToken("header", err)
+		}
+	}
+
+	if err := c.checkCritical(header); err != nil {
+		return err
+	}
+
+	if err := c.checkKeyHeaders(header); err != nil {
+		return err
+	}
+
+	if err := c.checkType(header); err != nil {
+		return err
+	}
+
+	if err := c.checkAccessTokenHeader(header); err != nil {
+		return err
+	}
+
+	// A nil v means the caller wants only the signature checked, and so the
+	// claims weren't even decoded. Options that check claims can't be honored
+	// without them, and quietly skipping those checks would accept tokens the
+	// caller means to reject.
+	if v == nil {
+		if c.checksClaims() {
+			return fmt.Errorf("%w, not nil, when options check claims", ErrInvalidClaimsTarget)
+		}
+
+		return nil
+	}
+
+	if err := checkJSON(claims); err != nil {
+		return malformedToken("claims", err)
+	}
+
+	if c.noDuplicateKeys {
+		if err := checkDuplicateKeys(claims); err != nil {
+			return malformedToken("claims", err)
+		}
+	}
+
+	if c.lenientNumericDates {
+		if unquoted, ok := unquoteNumericDates(claims); ok {
+			claims = unquoted
+		}
+	}
+
+	if err := c.checkExpectations(claims); err != nil {
+		return err
+	}
+
+	if c.strictClaims {
+		if err := checkUnknownClaims(claims, v); err != nil {
+			return err
+		}
+	}
+
+	if err := decodeClaims(claims, v, shared, c.useNumber); err != nil {
+		return err
+	}
+
+	if err := validateClaims(v); err != nil {
+		return err
+	}
+
+	return c.checkReplay(claims)
+}
+
+// checksClaims returns whether c has any options that look at the claims of a
+// token, rather than just its header.
+func (c *verifyConfig) checksClaims() bool {
+	return c.noDuplicateKeys || c.strictClaims || c.replayStore != nil || c.expectedIssuer != nil || c.expectedAudience != nil ||
+		c.checkTimes || len(c.requiredScopes) > 0 || len(c.requiredClaims) > 0
+}
+
+// decodeClaims does the decoding part of unmarshalClaims.
+//
+// RFC7519 permits "exp", "nbf", and "iat" to have fractional parts, but the
+// int64 fields of StandardClaims can't hold them. If decoding fails because
+// of a type error, and any of those claims has a fractional part, then the
+// fractional parts are dropped and the claims are decoded again. "exp" and
+// "iat" are rounded down, and "nbf" is rounded up, so that rounding never
+// makes a token valid for longer than its issuer intended.
+//
+// Targets that can hold fractional values, such as NumericDate, float64, or
+// interface{}, decode successfully the first time, and so see the exact value.
+//
+// If decoding fails because "exp", "nbf", or "iat" is not a number, the
+// returned error names that claim, and wraps the error from encoding/json.
+//
+// shared is true if claims are in memory that the caller will reuse, and so
+// must be copied if v keeps them. useNumber is true if WithJSONNumber was
+// passed.
+func decodeClaims(claims []byte, v interface{}, shared, useNumber bool) error {
+	if c, ok := v.(*claimsTargets); ok {
+		return c.each(func(v interface{}) error {
+			return decodeClaims(claims, v, shared, useNumber)
+		})
+	}
+
+	// Unless they're shared, a *json.RawMessage gets the claims as they are.
+	// The claims were freshly decoded from base64, so nothing else holds on to
+	// them; there's no need for json.Unmarshal to copy them. Invalid JSON is
+	// left to json.Unmarshal to report.
+	if raw, ok := v.(*json.RawMessage); ok && !shared && json.Valid(claims) {
+		*raw = claims
+		return nil
+	}
+
+	err := unmarshalClaimsJSON(claims, v, useNumber)
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*json.UnmarshalTypeError); ok {
+		if whole, ok := wholeNumericDates(claims); ok {
+			// The retry's error, if any, is less helpful than the original one.
+			if unmarshalClaimsJSON(whole, v, useNumber) == nil {
+				return nil
+			}
+		}
+	}
+
+	if name, ok := nonNumericDate(claims); ok {
+		return fmt.Errorf("jwt: cannot decode %q claim: %w", name, err)
+	}
+
+	return err
+}
+
+// nonNumericDate returns the name of the first of "exp", "nbf", and "iat" in
+// claims that is present, but is not a JSON number. It returns false if there
+// is no such claim.
+func nonNumericDate(claims []byte) (string, bool) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(claims, &m); err != nil {
+		return "", false
+	}
+
+	for _, name := range []string{"exp", "nbf", "iat"} {
+		raw, ok := m[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+
+		if !isJSONNumber(raw) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// unquoteNumericDates returns claims with any "exp", "nbf", or "iat" that is a
+// string containing a number replaced by that number. It returns false if
+// claims had no such strings.
+func unquoteNumericDates(claims []byte) ([]byte, bool) {
+	return rewriteNumericDates(claims, func(name string, raw []byte) ([]byte, bool) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, false
+		}
+
+		if !isJSONNumber([]byte(s)) {
+			return nil, false
+		}
+
+		if _, err := parseNumericDate(s); err != nil {
+			return nil, false
+		}
+
+		return []byte(s), true
+	})
+}
+
+// wholeNumericDates returns claims with any fractional "exp", "nbf", or "iat"
+// rounded to a whole number of seconds. It returns false if claims had no such
+// fractional timestamps.
+func wholeNumericDates(claims []byte) ([]byte, bool) {
+	return rewriteNumericDates(claims, func(name string, raw []byte) ([]byte, bool) {
+		if !strings.ContainsAny(string(raw), ".eE") {
+			return nil, false
+		}
+
+		t, err := parseNumericDate(string(raw))
+		if err != nil {
+			return nil, false
+		}
+
+		// Unix rounds down, which is what we want for "exp" and "iat".
+		secs := t.Unix()
+		if name == "nbf" && t.Nanosecond() != 0 {
+			secs++
+		}
+
+		return []byte(strconv.FormatInt(secs, 10)), true
+	})
+}
+
+// rewriteNumericDates returns claims with the value of each top-level "exp",
+// "nbf", and "iat" member replaced by what fn returns for it. fn gets the name
+// and raw JSON value of the member, and returns false to leave it as it is.
+//
+// The values are replaced in place. Everything else in claims, including the
+// order of its members and how other claims are encoded, is kept byte for
+// byte, so that targets like *json.RawMessage see what the token carried.
+//
+// It returns false if fn replaced nothing, or claims isn't a JSON object.
+func rewriteNumericDates(claims []byte, fn func(name string, raw []byte) ([]byte, bool)) ([]byte, bool) {
+	i := skipJSONSpace(claims, 0)
+	if i == len(claims) || claims[i] != '{' {
+		return nil, false
+	}
+
+	var out []byte
+	copied := 0 // claims[:copied] is already in out
+	for i = skipJSONSpace(claims, i+1); i < len(claims) && claims[i] != '}'; {
+		key, err := nextJSONValue(claims[i:])
+		if err != nil {
+			return nil, false
+		}
+
+		var name string
+		if err := json.Unmarshal(key, &name); err != nil {
+			return nil, false
+		}
+
+		i = skipJSONSpace(claims, i+len(key))
+		if i == len(claims) || claims[i] != ':' {
+			return nil, false
+		}
+
+		i = skipJSONSpace(claims, i+1)
+		raw, err := nextJSONValue(claims[i:])
+		if err != nil {
+			return nil, false
+		}
+
+		if name == "exp" || name == "nbf" || name == "iat" {
+			if value, ok := fn(name, raw); ok {
+				out = append(out, claims[copied:i]...)
+				out = append(out, value...)
+				copied = i + len(raw)
+			}
+		}
+
+		i = skipJSONSpace(claims, i+len(raw))
+		if i < len(claims) && claims[i] == ',' {
+			i = skipJSONSpace(claims, i+1)
+		}
+	}
+
+	if out == nil {
+		return nil, false
+	}
+
+	return append(out, claims[copied:]...), true
+}
+
+// nextJSONValue returns the JSON value that data starts with, exactly as it
+// appears in data. data must not start with whitespace.
+func nextJSONValue(data []byte) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(data)).Decode(&raw)
+	return raw, err
+}
+
+// skipJSONSpace returns the index of the first byte of data, at or after i,
+// that is not JSON whitespace.
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// isJSONNumber returns whether b is a single, valid JSON number.
+func isJSONNumber(b []byte) bool {
+	return len(b) > 0 && (b[0] == '-' || (b[0] >= '0' && b[0] <= '9')) && json.Valid(b)
+}
@@ -0,0 +1,329 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+func TestFractionalNumericDates(t *testing.T) {
+	secret := []byte("my secret key")
+
+	sign := func(claims string) []byte {
+		return forgeToken(`{"alg":"HS256"}`, claims, hmacSHA256(secret))
+	}
+
+	testCases := []struct {
+		name   string
+		claims string
+		out    jwt.StandardClaims
+	}{
+		{
+			name:   "whole numbers",
+			claims: `{"exp":1300819380,"nbf":1300819370,"iat":1300819370}`,
+			out:    jwt.StandardClaims{ExpirationTime: 1300819380, NotBefore: 1300819370, IssuedAt: 1300819370},
+		},
+		{
+			name:   "whole numbers with decimal point",
+			claims: `{"exp":1300819380.0,"nbf":1300819370.0}`,
+			out:    jwt.StandardClaims{ExpirationTime: 1300819380, NotBefore: 1300819370},
+		},
+		{
+			name:   "fractional exp is rounded down",
+			claims: `{"sub":"jdoe@example.com","exp":1300819380.123}`,
+			out:    jwt.StandardClaims{Subject: "jdoe@example.com", ExpirationTime: 1300819380},
+		},
+		{
+			name:   "fractional exp close to the next second is rounded down",
+			claims: `{"exp":1300819380.999}`,
+			out:    jwt.StandardClaims{ExpirationTime: 1300819380},
+		},
+		{
+			name:   "fractional nbf is rounded up",
+			claims: `{"nbf":1300819370.001}`,
+			out:    jwt.StandardClaims{NotBefore: 1300819371},
+		},
+		{
+			name:   "fractional iat is rounded down",
+			claims: `{"iat":1300819370.5}`,
+			out:    jwt.StandardClaims{IssuedAt: 1300819370},
+		},
+		{
+			name:   "exponent",
+			claims: `{"exp":1.30081938e9}`,
+			out:    jwt.StandardClaims{ExpirationTime: 1300819380},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims jwt.StandardClaims
+			assert.NoError(t, jwt.VerifyHS256(secret, sign(tt.claims), &claims))
+			assert.Equal(t, tt.out, claims)
+		})
+	}
+
+	t.Run("embedded in custom claims", func(t *testing.T) {
+		type CustomClaims struct {
+			jwt.StandardClaims
+			MyCoolClaim string `json:"my_cool_claim"`
+		}
+
+		var claims CustomClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"exp":1300819380.5,"my_cool_claim":"asdf"}`), &claims))
+		assert.Equal(t, int64(1300819380), claims.ExpirationTime)
+		assert.Equal(t, "asdf", claims.MyCoolClaim)
+	})
+
+	t.Run("targets that can hold fractions keep them", func(t *testing.T) {
+		var m map[string]interface{}
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"exp":1300819380.5}`), &m))
+		assert.Equal(t, 1300819380.5, m["exp"])
+
+		var claims jwt.RegisteredClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"exp":1300819380.5}`), &claims))
+		assert.Equal(t, time.Unix(1300819380, 5e8), claims.ExpirationTime.Time())
+	})
+
+	t.Run("other claims are left as they are", func(t *testing.T) {
+		var claims struct {
+			jwt.StandardClaims
+			Data json.RawMessage `json:"data"`
+		}
+
+		assert.NoError(t, jwt.VerifyHS256(secret, sign(`{"data":{"b": 1.50 ,"a":"\u00e9"},"exp":1300819380.5}`), &claims))
+		assert.Equal(t, int64(1300819380), claims.ExpirationTime)
+		assert.Equal(t, `{"b": 1.50 ,"a":"\u00e9"}`, string(claims.Data))
+	})
+
+	t.Run("other type errors are still reported", func(t *testing.T) {
+		var claims jwt.StandardClaims
+		err := jwt.VerifyHS256(secret, sign(`{"exp":1300819380.5,"sub":1}`), &claims)
+		assert.IsType(t, &json.UnmarshalTypeError{}, err)
+
+		var typeErr *json.UnmarshalTypeError
+		err = jwt.VerifyHS256(secret, sign(`{"exp":"1300819380"}`), &claims)
+		assert.True(t, errors.As(err, &typeErr))
+	})
+}
+
+func TestLenientNumericDates(t *testing.T) {
+	secret := []byte("my secret key")
+
+	sign := func(claims string) []byte {
+		return forgeToken(`{"alg":"HS256"}`, claims, hmacSHA256(secret))
+	}
+
+	t.Run("strict by default", func(t *testing.T) {
+		testCases := []struct {
+			claims string
+			name   string
+		}{
+			{`{"exp":"1300819380"}`, "exp"},
+			{`{"exp":1300819380,"nbf":"1300819370"}`, "nbf"},
+			{`{"iat":"1300819370"}`, "iat"},
+			{`{"iat":true}`, "iat"},
+		}
+
+		for _, tt := range testCases {
+			var claims jwt.StandardClaims
+			err := jwt.VerifyHS256(secret, sign(tt.claims), &claims)
+			assert.EqualError(t, err, `jwt: cannot decode "`+tt.name+`" claim: `+errors.Unwrap(err).Error(), tt.claims)
+
+			var registered jwt.RegisteredClaims
+			err = jwt.VerifyHS256(secret, sign(tt.claims), &registered)
+			assert.Contains(t, err.Error(), `"`+tt.name+`"`, tt.claims)
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		token := sign(`{"sub":"jdoe@example.com","exp":"1300819380","nbf":"1300819370.5","iat":1300819370}`)
+
+		var claims jwt.StandardClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &claims, jwt.WithLenientNumericDates()))
+		assert.Equal(t, jwt.StandardClaims{
+			Subject:        "jdoe@example.com",
+			ExpirationTime: 1300819380,
+			NotBefore:      1300819371,
+			IssuedAt:       1300819370,
+		}, claims)
+
+		var registered jwt.RegisteredClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &registered, jwt.WithLenientNumericDates()))
+		assert.Equal(t, time.Unix(1300819380, 0), registered.ExpirationTime.Time())
+		assert.Equal(t, time.Unix(1300819370, 5e8), registered.NotBefore.Time())
+	})
+
+	t.Run("lenient leaves other claims as they are", func(t *testing.T) {
+		token := sign(`{ "sub" : "jdoe@example.com", "exp":"1300819380","z":1.50,"a":{"b":"\u00e9"},"iat" : "1300819370" }`)
+
+		var raw json.RawMessage
+		assert.NoError(t, jwt.VerifyHS256(secret, token, &raw, jwt.WithLenientNumericDates()))
+		assert.Equal(t, `{ "sub" : "jdoe@example.com", "exp":1300819380,"z":1.50,"a":{"b":"\u00e9"},"iat" : 1300819370 }`, string(raw))
+	})
+
+	t.Run("lenient still rejects strings that aren't numbers", func(t *testing.T) {
+		for _, exp := range []string{`"tomorrow"`, `""`, `" 1300819380"`, `"Inf"`, `"0x1p3"`} {
+			var claims jwt.StandardClaims
+			err := jwt.VerifyHS256(secret, sign(`{"exp":`+exp+`}`), &claims, jwt.WithLenientNumericDates())
+			assert.Error(t, err, exp)
+		}
+	})
+
+	t.Run("lenient only applies to timestamps", func(t *testing.T) {
+		var claims jwt.StandardClaims
+		err := jwt.VerifyHS256(secret, sign(`{"exp":1300819380,"sub":1}`), &claims, jwt.WithLenientNumericDates())
+		assert.Error(t, err)
+	})
+}
+
+func TestInvalidClaimsTarget(t *testing.T) {
+	secret := []byte("my secret key")
+	token, err := jwt.SignHS256(secret, jwt.StandardClaims{Subject: "jdoe@example.com"})
+	assert.NoError(t, err)
+
+	var claims jwt.StandardClaims
+	var nilClaims *jwt.StandardClaims
+
+	err = jwt.VerifyHS256(secret, token, claims)
+	assert.True(t, errors.Is(err, jwt.ErrInvalidClaimsTarget))
+	assert.EqualError(t, err, "jwt: claims target must be a non-nil pointer, not a jwt.StandardClaims")
+
+	err = jwt.VerifyHS256(secret, token, nilClaims)
+	assert.EqualError(t, err, "jwt: claims target must be a non-nil pointer, not a nil *jwt.StandardClaims")
+
+	// Only the whole target may be nil. See TestVerifySignatureOnly.
+	err = jwt.VerifyHS256(secret, token, jwt.Into(&claims, nil))
+	assert.EqualError(t, err, "jwt: claims target 1 (<nil>): jwt: claims target must be a non-nil pointer, not nil")
+
+	// The target is checked before anything else, including the token and the
+	// key. If it weren't, the nil keys here would cause a panic.
+	checks := map[string]error{
+		"HS256":    jwt.VerifyHS256(nil, nil, claims),
+		"HS512":    jwt.VerifyHS512(nil, nil, claims),
+		"RS256":    jwt.VerifyRS256(nil, token, claims),
+		"RS384":    jwt.VerifyRS384(nil, token, claims),
+		"PS256":    jwt.VerifyPS256(nil, token, claims),
+		"ES256":    jwt.VerifyES256(nil, token, claims),
+		"ES512":    jwt.VerifyES512(nil, token, claims),
+		"EdDSA":    jwt.VerifyEdDSA(nil, token, claims),
+		"Verifier": jwt.NewHS256Verifier(secret).Verify(token, claims),
+		"KeySet":   jwt.VerifyWithKeySet(nil, token, claims),
+		"Custom":   jwt.VerifyCustom("X", token, claims, nil),
+		"Nested":   jwt.VerifyNested(token, claims, jwt.AllowHS256(secret), jwt.AllowHS256(secret)),
+		"String":   jwt.VerifyHS256String(secret, string(token), claims),
+	}
+
+	_, checks["Any"] = jwt.VerifyAny(token, claims)
+	_, checks["HS256Any"] = jwt.VerifyHS256Any(nil, token, claims)
+	_, checks["X5C"] = jwt.VerifyX5C(nil, "RS256", token, claims)
+
+	for name, err := range checks {
+		assert.True(t, errors.Is(err, jwt.ErrInvalidClaimsTarget), name)
+	}
+
+	// Anything that is a non-nil pointer is accepted, even if it's not a
+	// struct.
+	var m map[string]interface{}
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &m))
+
+	var raw json.RawMessage
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &raw))
+}
+
+func TestRawClaims(t *testing.T) {
+	secret := []byte("my secret key")
+
+	// The claims come back byte for byte, including key order and whitespace
+	// that json.Marshal would have changed.
+	token := forgeToken(`{"alg":"HS256"}`, `{"sub":"jdoe", "aud":["a"],"iat":1.50}`, hmacSHA256(secret))
+
+	var raw json.RawMessage
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &raw))
+	assert.Equal(t, `{"sub":"jdoe", "aud":["a"],"iat":1.50}`, string(raw))
+
+	// Each call gets its own slice.
+	var other json.RawMessage
+	assert.NoError(t, jwt.VerifyHS256(secret, token, &other))
+	raw[0] = 'x'
+	assert.Equal(t, `{"sub":"jdoe", "aud":["a"],"iat":1.50}`, string(other))
+
+	// Options that look at the claims still apply.
+	err := jwt.VerifyHS256(secret, token, &raw, jwt.WithExpectedIssuer("https://issuer.example.com"))
+	assert.True(t, errors.Is(err, jwt.ErrUnexpectedIssuer))
+
+	// Claims that aren't JSON are still rejected.
+	var invalid json.RawMessage
+	err = jwt.VerifyHS256(secret, forgeToken(`{"alg":"HS256"}`, `{"sub":`, hmacSHA256(secret)), &invalid)
+	assert.Error(t, err)
+	assert.Nil(t, invalid)
+}
+
+func TestVerifySignatureOnly(t *testing.T) {
+	secret := []byte("my secret key")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	// The claims aren't even an object. With a nil target, that doesn't
+	// matter.
+	claims := "not an object"
+	tokens := map[string][]byte{}
+	tokens["HS256"], err = jwt.SignHS256(secret, claims)
+	assert.NoError(t, err)
+
+	tokens["RS256"], err = jwt.SignRS256(rsaKey, claims)
+	assert.NoError(t, err)
+
+	tokens["ES256"], err = jwt.SignES256(ecKey, claims)
+	assert.NoError(t, err)
+
+	verify := map[string]func(s []byte, opts ...jwt.VerifyOption) error{
+		"HS256": func(s []byte, opts ...jwt.VerifyOption) error {
+			return jwt.VerifyHS256(secret, s, nil, opts...)
+		},
+		"RS256": func(s []byte, opts ...jwt.VerifyOption) error {
+			return jwt.VerifyRS256(&rsaKey.PublicKey, s, nil, opts...)
+		},
+		"ES256": func(s []byte, opts ...jwt.VerifyOption) error {
+			return jwt.VerifyES256(&ecKey.PublicKey, s, nil, opts...)
+		},
+	}
+
+	for alg, token := range tokens {
+		fn := verify[alg]
+		assert.NoError(t, fn(token), alg)
+
+		// The signature still covers the claims, even though they're never
+		// looked at.
+		parts := strings.Split(string(token), ".")
+		tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`"not an object!"`)) + "." + parts[2]
+		assert.Equal(t, jwt.ErrBadSignature, fn([]byte(tampered)), alg)
+
+		// Options about the header still apply.
+		assert.Equal(t, jwt.ErrUnexpectedType, fn(token, jwt.WithExpectedType("at+jwt")), alg)
+
+		// Options about the claims can't be honored, so they're an error rather
+		// than silently ignored.
+		err := fn(token, jwt.WithExpectedIssuer("https://example.com"))
+		assert.True(t, errors.Is(err, jwt.ErrInvalidClaimsTarget), alg)
+		assert.EqualError(t, err, "jwt: claims target must be a non-nil pointer, not nil, when options check claims", alg)
+	}
+
+	// A verifier's options are checked the same way.
+	assert.NoError(t, jwt.NewHS256Verifier(secret).Verify(tokens["HS256"], nil))
+	err = jwt.NewHS256Verifier(secret, jwt.WithLeeway(0)).Verify(tokens["HS256"], nil)
+	assert.True(t, errors.Is(err, jwt.ErrInvalidClaimsTarget))
+}
@@ -0,0 +1,67 @@
+package jwt
+
+import "errors"
+
+// Validator is implemented by claim types that check their own contents.
+//
+// After a Verify function in this package has verified a JWT's signature and
+// deserialized its claims into v, it calls Validate on v if v implements
+// Validator. If Validate returns an error, so does the Verify function. This
+// gives domain rules, such as "every token must name a tenant", a single place
+// to live that no call site can forget to check.
+//
+// Validate is only called on tokens whose signature is valid.
+type Validator interface {
+	Validate() error
+}
+
+// ErrClaimsRejected is the error returned by the Verify functions in this
+// package when a JWT's signature is valid, but the Validate method of its
+// claims returns an error.
+//
+// The returned error wraps both ErrClaimsRejected and the error from Validate,
+// so errors.Is and errors.As can be used to check for either:
+//
+//	err := jwt.VerifyHS256(secret, token, &claims)
+//	if errors.Is(err, jwt.ErrClaimsRejected) {
+//		// the signature was valid, but the claims were not
+//	}
+var ErrClaimsRejected = errors.New("jwt: claims rejected")
+
+type claimsRejectedError struct {
+	err error
+}
+
+func (e claimsRejectedError) Error() string {
+	return ErrClaimsRejected.Error() + ": " + e.err.Error()
+}
+
+func (e claimsRejectedError) Unwrap() error {
+	return e.err
+}
+
+func (e claimsRejectedError) Is(target error) bool {
+	return target == ErrClaimsRejected
+}
+
+// validateClaims calls Validate on v, if v implements Validator. If v came
+// from Into, it calls Validate on each of the targets that implement it.
+func validateClaims(v interface{}) error {
+	if c, ok := v.(*claimsTargets); ok {
+		for _, v := range c.targets {
+			if err := validateClaims(v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if v, ok := v.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return claimsRejectedError{err: err}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package jwt_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+var errMissingTenant = errors.New("missing tenant")
+
+type tenantClaims struct {
+	jwt.StandardClaims
+	Tenant string `json:"tenant"`
+}
+
+func (c *tenantClaims) Validate() error {
+	if c.Tenant == "" {
+		return errMissingTenant
+	}
+
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	secret := []byte("my secret key")
+
+	valid, err := jwt.SignHS256(secret, tenantClaims{Tenant: "acme"})
+	assert.NoError(t, err)
+
+	invalid, err := jwt.SignHS256(secret, tenantClaims{})
+	assert.NoError(t, err)
+
+	t.Run("valid claims", func(t *testing.T) {
+		var claims tenantClaims
+		assert.NoError(t, jwt.VerifyHS256(secret, valid, &claims))
+		assert.Equal(t, "acme", claims.Tenant)
+	})
+
+	t.Run("invalid claims", func(t *testing.T) {
+		var claims tenantClaims
+		err := jwt.VerifyHS256(secret, invalid, &claims)
+		assert.EqualError(t, err, "jwt: claims rejected: missing tenant")
+		assert.True(t, errors.Is(err, jwt.ErrClaimsRejected))
+		assert.True(t, errors.Is(err, errMissingTenant))
+		assert.False(t, errors.Is(err, jwt.ErrInvalidSignature))
+	})
+
+	t.Run("not called for invalid signatures", func(t *testing.T) {
+		var claims tenantClaims
+		err := jwt.VerifyHS256([]byte("other"), invalid, &claims)
+		assert.Equal(t, jwt.ErrBadSignature, err)
+	})
+
+	t.Run("VerifyAny", func(t *testing.T) {
+		var claims tenantClaims
+		_, err := jwt.VerifyAny(invalid, &claims, jwt.AllowHS256(secret))
+		assert.True(t, errors.Is(err, errMissingTenant))
+	})
+}
+
+func ExampleValidator() {
+	// The tenantClaims type used in this example is:
+	//
+	//	type tenantClaims struct {
+	//		jwt.StandardClaims
+	//		Tenant string `json:"tenant"`
+	//	}
+	//
+	//	func (c *tenantClaims) Validate() error {
+	//		if c.Tenant == "" {
+	//			return errMissingTenant
+	//		}
+	//
+	//		return nil
+	//	}
+	secret := []byte("my secret key")
+	token, _ := jwt.SignHS256(secret, tenantClaims{})
+
+	var claims tenantClaims
+	err := jwt.VerifyHS256(secret, token, &claims)
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, jwt.ErrClaimsRejected), errors.Is(err, errMissingTenant))
+	// Output:
+	//
+	// jwt: claims rejected: missing tenant
+	// true true
+}
@@ -1,7 +1,6 @@
 package jwt
 
 import (
-	"bytes"
 	"encoding/base64"
 	"encoding/json"
 )
@@ -9,10 +8,12 @@ import (
 // headerTypeJWT is the value used for "typ" in JWT headers.
 const headerTypeJWT = "JWT"
 
-// header represents a JWT header.
+// header represents the parts of a JWT header that this package produces and
+// checks. Header is the exported, more complete version of this type.
 type header struct {
 	Type      string `json:"typ"`
 	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
 }
 
 // sign encodes a header and body, has fn sign it, and then returns the
@@ -20,22 +21,29 @@ type header struct {
 //
 // alg will be used as the "alg" field in the JWT header.
 //
-// sigLen must be the number of bytes that fn will return. Knowing this value in
-// advance lets us avoid an extra allocation.
+// sigLen should be the number of bytes that fn will return. Knowing this value
+// in advance lets us avoid an extra allocation. If fn returns a signature of a
+// different length, sign still works, but must allocate again.
 //
 // v is encoded as JSON and used as the claims in the JWT.
-func sign(alg string, sigLen int, v interface{}, fn func(data []byte) ([]byte, error)) ([]byte, error) {
-	header, err := json.Marshal(header{Type: headerTypeJWT, Algorithm: alg})
+//
+// opts are the options the caller passed to the exported Sign function. They
+// are applied before fn is called, so fn is never called if opts reject the
+// claims.
+func sign(alg string, sigLen int, v interface{}, opts []SignOption, fn func(data []byte) ([]byte, error)) ([]byte, error) {
+	config := newSignConfig(opts)
+
+	encodedHeader, err := config.encodeHeader(alg)
 	if err != nil {
 		return nil, err
 	}
 
-	claims, err := json.Marshal(v)
+	claims, err := config.payload(v)
 	if err != nil {
 		return nil, err
 	}
 
-	i := base64.RawURLEncoding.EncodedLen(len(header))
+	i := len(encodedHeader)
 	j := base64.RawURLEncoding.EncodedLen(len(claims))
 
 	// We need i bytes for the header, j bytes for the claims, 2 bytes for two
@@ -43,7 +51,7 @@ func sign(alg string, sigLen int, v interface{}, fn func(data []byte) ([]byte, e
 	//
 	// Here, we build the set of data we'll need to sign.
 	buf := make([]byte, i+1+j+1+base64.RawURLEncoding.EncodedLen(sigLen))
-	base64.RawURLEncoding.Encode(buf, header)
+	copy(buf, encodedHeader)
 	buf[i] = '.' // i-1 is the last byte of the encoded header
 	base64.RawURLEncoding.Encode(buf[i+1:], claims)
 
@@ -53,6 +61,12 @@ func sign(alg string, sigLen int, v interface{}, fn func(data []byte) ([]byte, e
 		return nil, err // if fn returns an error, we want to return that error
 	}
 
+	// If fn didn't return sigLen bytes, the space we set aside for the signature
+	// is the wrong size.
+	if len(sig) != sigLen {
+		buf = append(buf[:i+1+j+1], make([]byte, base64.RawURLEncoding.EncodedLen(len(sig)))...)
+	}
+
 	// We need to write out a period between the claims and the signature.
 	buf[i+1+j] = '.' // i+1+j-1 is the last byte of the encoded claims
 	base64.RawURLEncoding.Encode(buf[i+1+j+1:], sig)
@@ -60,74 +74,284 @@ func sign(alg string, sigLen int, v interface{}, fn func(data []byte) ([]byte, e
 	return buf, nil
 }
 
+// payload returns what sign puts in the claims part of a JWT: v encoded as
+// JSON, with any claims c adds, or the inner JWT if SignNested is signing a
+// nested JWT.
+func (c *signConfig) payload(v interface{}) ([]byte, error) {
+	if c.nestedPayload != nil {
+		// There are no claims to add to or check, only another JWT.
+		if err := c.checkNoClaims(); err != nil {
+			return nil, err
+		}
+
+		return c.nestedPayload, nil
+	}
+
+	claims, err := marshalClaims(v)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err = c.addClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range c.policies {
+		if err := p.check(claims, c.clock()); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
 // verify decodes a JWT into its parts, checks that it has the right alg, and
-// then has fn verify the signature. If that succeeds, it returns the claims.
+// then has fn verify the signature. If that succeeds, it returns the header
+// and the claims, each decoded from base64 but not from JSON.
 //
 // alg is the expected value of the "alg" header. It's just a hoop to jump
 // through, its value is otherwise ignored.
 //
+// v is the claims target the caller will pass to unmarshalClaims. If it's nil,
+// the caller only wants the signature checked, so the claims aren't decoded at
+// all, and verify returns nil claims.
+//
+// opts are the options the caller passed to the exported Verify function. Only
+// the ones that affect how the token is decoded, such as WithLenientBase64,
+// are used here.
+//
 // fn will recieve the data that was supposed to be signed (the header, a
 // period, and the claims), and the actual signature in the JWT. If the
 // signature is invalid, fn must return an error.
-func verify(alg string, s []byte, fn func(data, sig []byte) error) ([]byte, error) {
-	// s[:i] will be the header
-	i := bytes.IndexByte(s, '.')
-	if i == -1 {
-		return nil, ErrInvalidSignature
-	}
+func verify(alg string, s []byte, v interface{}, opts []VerifyOption, fn func(data, sig []byte) error) ([]byte, []byte, error) {
+	return verifyBuf(alg, s, nil, v, newVerifyConfig(opts).lenientBase64, fn)
+}
+
+// verifyBuf is like verify, except that it decodes the token into buf. See
+// verifySelectBuf.
+func verifyBuf(alg string, s, buf []byte, v interface{}, lenientBase64 bool, fn func(data, sig []byte) error) ([]byte, []byte, error) {
+	_, header, claims, err := verifySelectBuf(s, buf, v != nil, lenientBase64, func(headerAlg string) func(data, sig []byte) error {
+		if headerAlg != alg {
+			return nil
+		}
+
+		return fn
+	})
 
-	// s[i+1:s+1+j] will be the claims
+	return header, claims, err
+}
+
+// verifySelect is like verify, except that instead of checking the "alg"
+// header against a single value, it passes the "alg" header to selectFn.
+// selectFn returns the function to verify the signature with, or nil if the
+// token should be rejected. verifySelect also returns the alg that was
+// selected.
+//
+// Callers must only ever select among a set of algorithms chosen ahead of time
+// by the application. The token must never decide on its own what algorithm is
+// used.
+func verifySelect(s []byte, v interface{}, opts []VerifyOption, selectFn func(alg string) func(data, sig []byte) error) (string, []byte, []byte, error) {
+	return verifySelectBuf(s, nil, v != nil, newVerifyConfig(opts).lenientBase64, selectFn)
+}
+
+// verifySelectBuf is like verifySelect, except that it decodes the parts of the
+// token into buf, rather than a buffer it allocates, if buf is long enough. A
+// buf as long as s always is. The returned claims may point into buf. Unless
+// withClaims is true, the claims are left undecoded, and nil is returned in
+// their place. If lenientBase64 is true, the parts of the token may be padded
+// or use the standard base64 alphabet; see WithLenientBase64.
+func verifySelectBuf(s, buf []byte, withClaims, lenientBase64 bool, selectFn func(alg string) func(data, sig []byte) error) (string, []byte, []byte, error) {
+	// Here, and throughout the rest of this function, a token that is ill-formed
+	// is rejected with an error wrapping ErrMalformedToken, a token with the
+	// wrong alg with ErrWrongAlgorithm, and one with the wrong signature with
+	// ErrBadSignature. See the docs for ErrInvalidSignature.
 	//
-	// The rest of the data -- s[i+1+j+1:] -- will be the signature
-	j := bytes.IndexByte(s[i+1:], '.')
-	if j == -1 {
-		return nil, ErrInvalidSignature
+	// Split ignores whitespace around s, but the signing input is sliced out of
+	// s below, so it has to start where the header does.
+	s = trimToken(s)
+	encodedHeader, encodedClaims, encodedSignature, err := splitParts(s, lenientBase64)
+	if err != nil {
+		return "", nil, nil, err
 	}
 
-	// decode the header's base64. It's stored as base64(json(...))
-	decodedHeader := make([]byte, base64.RawURLEncoding.DecodedLen(i))
-	if _, err := base64.RawURLEncoding.Decode(decodedHeader, s[:i]); err != nil {
-		return nil, err
+	// The signature is expected to match the encoded header + period + claims,
+	// exactly as they were sent. splitParts returns subslices of s, so that's
+	// the start of s, up to the period before the signature.
+	signingInput := s[:len(encodedHeader)+1+len(encodedClaims)]
+
+	// Only the copies of the parts that get decoded are normalized, never the
+	// signing input.
+	if lenientBase64 {
+		encodedHeader = normalizeBase64(encodedHeader)
+		encodedClaims = normalizeBase64(encodedClaims)
+		encodedSignature = normalizeBase64(encodedSignature)
 	}
 
-	// decodedHeader now contains json(...), let's decode that into actual data
-	var header header
-	if err := json.Unmarshal(decodedHeader, &header); err != nil {
-		return nil, err
+	// The header, signature, and claims are all decoded into one buffer, in
+	// that order, so that verifying a token allocates at most once for all
+	// three. The decoded parts are never longer than the token itself.
+	n := base64.RawURLEncoding.DecodedLen(len(encodedHeader)) +
+		base64.RawURLEncoding.DecodedLen(len(encodedSignature))
+
+	if withClaims {
+		n += base64.RawURLEncoding.DecodedLen(len(encodedClaims))
+	}
+
+	if len(buf) < n {
+		buf = make([]byte, n)
+	}
+
+	alg, decodedHeader, buf, err := decodeHeader(buf, encodedHeader)
+	if err != nil {
+		return "", nil, nil, malformedToken("header", err)
 	}
 
 	// This is just a hoop to jump through in order for a JWT to be accepted. We
-	// require all JWTs to have the exact alg we want.
-	if header.Algorithm != alg {
-		return nil, ErrInvalidSignature
+	// require all JWTs to have exactly an alg we want.
+	fn := selectFn(alg)
+	if fn == nil {
+		return "", nil, nil, ErrWrongAlgorithm
 	}
 
 	// decode the signature's base64.
-	//
-	// len(s)-(i+1+j+1) is the number of bytes in the signature, which starts at
-	// index i+1+j+1.
-	decodedSignature := make([]byte, base64.RawURLEncoding.DecodedLen(len(s)-i-1-j-1))
-	if _, err := base64.RawURLEncoding.Decode(decodedSignature, s[i+1+j+1:]); err != nil {
-		return nil, err
+	decodedSignature, buf, err := decodePart(buf, encodedSignature)
+	if err != nil {
+		return "", nil, nil, malformedToken("signature", err)
+	}
+
+	// Some algorithms always produce signatures of the same size. There's no
+	// sense in passing fn a signature for them that can't possibly be valid.
+	if size, ok := signatureSizes[alg]; ok && len(decodedSignature) != size {
+		return "", nil, nil, ErrBadSignature
 	}
 
-	// The signature is expected to match the encoded header + period + claims.
-	//
 	// If get past this check without erroring, then the signature is valid.
-	if err := fn(s[:i+1+j], decodedSignature); err != nil {
-		return nil, err
+	if err := fn(signingInput, decodedSignature); err != nil {
+		// Functions passed to VerifyCustom say a signature is bad with plain
+		// ErrInvalidSignature.
+		if err == ErrInvalidSignature {
+			err = ErrBadSignature
+		}
+
+		return "", nil, nil, err
+	}
+
+	// The signature covers the claims, so they're authentic whether or not
+	// anyone looks at them.
+	if !withClaims {
+		return alg, decodedHeader, nil, nil
 	}
 
 	// The signature is valid. It's stored as base64(json(...)), let's decode the
 	// base64.
-	//
-	// The claims go from index i+1 to i+1+j -- it has length j.
-	decodedClaims := make([]byte, base64.RawURLEncoding.DecodedLen(j))
-	if _, err := base64.RawURLEncoding.Decode(decodedClaims, s[i+1:i+1+j]); err != nil {
-		return nil, err
+	decodedClaims, _, err := decodePart(buf, encodedClaims)
+	if err != nil {
+		return "", nil, nil, malformedToken("claims", err)
+	}
+
+	// We return the base64-decoded header and claims. Callers of this function
+	// will handle doing json deserialization.
+	return alg, decodedHeader, decodedClaims, nil
+}
+
+// signatureSizes is the size, in bytes, of the signatures made with each of the
+// algorithms whose signatures are always the same size. The size of an RS256 or
+// PS256 signature depends on the key, so those functions check it themselves.
+var signatureSizes = map[string]int{
+	algHS256: 32,
+	algHS512: 64,
+	algES256: 64,
+	algES512: 132,
+	algEdDSA: 64,
+}
+
+// decodeHeader decodes the header part of a JWT into buf, as decodeHeaderPart
+// does, and returns the header's "alg" along with the header itself.
+//
+// Headers in knownHeaders are recognized as they are, without being decoded,
+// and without using buf. Any other header is decoded by decodeUnknownHeader,
+// which comes to the same result for the known headers, only more slowly.
+func decodeHeader(buf, encodedHeader []byte) (alg string, decoded, rest []byte, err error) {
+	if known, ok := knownHeaders[string(encodedHeader)]; ok {
+		return known.alg, known.json, buf, nil
 	}
 
-	// We return the base64-decoded claims. Callers of this function will handle
-	// doing json deserialization.
-	return decodedClaims, nil
+	return decodeUnknownHeader(buf, encodedHeader)
+}
+
+// decodeUnknownHeader is the slow path of decodeHeader.
+func decodeUnknownHeader(buf, encodedHeader []byte) (alg string, decoded, rest []byte, err error) {
+	decoded, rest, err = decodeHeaderPart(buf, encodedHeader)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// decoded now contains json(...), let's decode that into actual data
+	var h header
+	if err := json.Unmarshal(decoded, &h); err != nil {
+		return "", nil, nil, err
+	}
+
+	return h.Algorithm, decoded, rest, nil
+}
+
+// decodeHeaderPart decodes the header part of a JWT, as returned by Split,
+// from base64. verifySelect, PeekHeader, and Parse share it.
+//
+// The header is decoded into the start of buf, and decodeHeaderPart returns
+// what remains of buf after it. If buf is too short, decodeHeaderPart allocates
+// a buffer for the header instead.
+//
+// The returned header is JSON, and has no duplicate members, but is not
+// otherwise checked. If the header is not well-formed, decodeHeaderPart
+// returns the error that says why.
+func decodeHeaderPart(buf, encodedHeader []byte) (header, rest []byte, err error) {
+	if len(buf) < base64.RawURLEncoding.DecodedLen(len(encodedHeader)) {
+		buf = make([]byte, base64.RawURLEncoding.DecodedLen(len(encodedHeader)))
+	}
+
+	// The header is stored as base64(json(...)).
+	header, rest, err = decodePart(buf, encodedHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// JSON parsers disagree on what an object with duplicate keys means. If we
+	// were to accept such headers, then {"alg":"none","alg":"HS256"} might mean
+	// something different to us than to some other system looking at the same
+	// token.
+	if err := checkDuplicateKeys(header); err != nil {
+		return nil, nil, err
+	}
+
+	return header, rest, nil
+}
+
+// strictRawURLEncoding is the only encoding of the parts of a JWT this package
+// accepts: the URL-safe alphabet, without padding, and with any bits left over
+// at the end set to zero. Every sequence of bytes has exactly one such
+// encoding, so no two distinct tokens ever decode to the same parts.
+var strictRawURLEncoding = base64.RawURLEncoding.Strict()
+
+// decodePart decodes a base64url-encoded part of a JWT into the start of buf,
+// which must be long enough to hold it. It returns the decoded part, whose
+// capacity ends where the part does, and the rest of buf.
+//
+// The part is only as long as what Decode reports it wrote, not what
+// DecodedLen predicts. Whatever was in buf past that is never part of it.
+func decodePart(buf, encoded []byte) (part, rest []byte, err error) {
+	n, err := strictRawURLEncoding.Decode(buf, encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buf[:n:n], buf[n:], nil
+}
+
+// decodeNewPart is like decodePart, except that it decodes into a newly
+// allocated buffer.
+func decodeNewPart(encoded []byte) ([]byte, error) {
+	part, _, err := decodePart(make([]byte, base64.RawURLEncoding.DecodedLen(len(encoded))), encoded)
+	return part, err
 }
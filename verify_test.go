@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -8,35 +10,151 @@ import (
 )
 
 func TestVerify(t *testing.T) {
+	raw := new(json.RawMessage)
+
 	// echo -n '{"alg": "test"}' | base64 | tr -d =
 	// echo -n 'claims' | base64 | tr -d =
 	// echo -n 'sig' | base64 | tr -d =
-	claims, err := verify("test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2ln"), func(data, sig []byte) error {
+	header, claims, err := verify("test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2ln"), raw, nil, func(data, sig []byte) error {
 		assert.Equal(t, []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z"), data)
 		assert.Equal(t, []byte("sig"), sig)
 		return nil
 	})
 
 	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"alg": "test"}`), header)
 	assert.Equal(t, []byte("claims"), claims)
 
-	_, err = verify("not-test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2lnCg"), func(data, sig []byte) error {
+	_, _, err = verify("not-test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2lnCg"), raw, nil, func(data, sig []byte) error {
 		t.Fail()
 		return nil
 	})
 
-	assert.Equal(t, ErrInvalidSignature, err)
+	assert.Equal(t, ErrWrongAlgorithm, err)
 
 	testErr := errors.New("test error")
-	_, err = verify("test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2lnCg"), func(data, sig []byte) error {
+	_, _, err = verify("test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2lnCg"), raw, nil, func(data, sig []byte) error {
 		return testErr
 	})
 
 	assert.Equal(t, testErr, err)
+
+	// The decoded parts share a buffer, but appending to one of them must not
+	// overwrite another.
+	header, claims, err = verify("test", []byte("eyJhbGciOiAidGVzdCJ9.Y2xhaW1z.c2ln"), raw, nil, func(data, sig []byte) error {
+		return nil
+	})
+
+	assert.NoError(t, err)
+	_ = append(header, "xxxxxxxxxx"...)
+	assert.Equal(t, []byte("claims"), claims)
+
+	// The signature of an algorithm whose signatures are a fixed size is never
+	// passed to fn if it's the wrong size.
+	// echo -n '{"alg":"HS256"}' | base64 | tr -d =
+	_, _, err = verify(algHS256, []byte("eyJhbGciOiJIUzI1NiJ9.Y2xhaW1z.c2ln"), raw, nil, func(data, sig []byte) error {
+		t.Fail()
+		return nil
+	})
+
+	assert.Equal(t, ErrBadSignature, err)
+}
+
+func TestDecodePart(t *testing.T) {
+	for n := 0; n < 10; n++ {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i + 1)
+		}
+
+		encoded := []byte(base64.RawURLEncoding.EncodeToString(data))
+
+		// Fill buf with garbage, which must not end up in the part.
+		buf := make([]byte, len(encoded)+4)
+		for i := range buf {
+			buf[i] = 0xff
+		}
+
+		part, rest, err := decodePart(buf, encoded)
+		assert.NoError(t, err, len(encoded))
+		assert.Equal(t, data, part, len(encoded))
+		assert.Equal(t, len(buf)-n, len(rest), len(encoded))
+
+		part, err = decodeNewPart(encoded)
+		assert.NoError(t, err, len(encoded))
+		assert.Equal(t, data, part, len(encoded))
+
+		// Bits left over at the end must be zero, or else there would be more
+		// than one encoding of data.
+		if len(encoded)%4 != 0 {
+			lenient := append([]byte(nil), encoded...)
+			lenient[len(lenient)-1]++
+
+			_, _, err = decodePart(buf, lenient)
+			assert.Error(t, err, string(lenient))
+		}
+
+		// No base64 encoding is 1 mod 4 characters long.
+		if len(encoded)%4 == 0 {
+			_, _, err = decodePart(buf, append(encoded, 'A'))
+			assert.Error(t, err, len(encoded)+1)
+		}
+	}
+
+	// Nor is padding, or the standard alphabet, allowed.
+	buf := make([]byte, 8)
+	_, _, err := decodePart(buf, []byte("AQI="))
+	assert.Error(t, err)
+
+	_, _, err = decodePart(buf, []byte(base64.StdEncoding.EncodeToString([]byte{0xfb, 0xff, 0xbf})))
+	assert.Error(t, err)
+}
+
+func TestDecodedLengths(t *testing.T) {
+	// Each of these parts is 0, 2, and 3 mod 4 characters long once encoded,
+	// which is every length a base64 encoding without padding can have.
+	headers := []string{`{"alg":"test" }`, `{"alg":"test"  }`, `{"alg":"test"}`}
+	claims := []string{`"abc"`, `"abcd"`, `"a"`}
+	sigs := []string{"sig", "sig1", "sig12"}
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				s := []byte(base64.RawURLEncoding.EncodeToString([]byte(headers[i])) + "." +
+					base64.RawURLEncoding.EncodeToString([]byte(claims[j])) + "." +
+					base64.RawURLEncoding.EncodeToString([]byte(sigs[k])))
+
+				buf := make([]byte, len(s))
+				for n := range buf {
+					buf[n] = 0xff
+				}
+
+				_, header, decodedClaims, err := verifySelectBuf(s, buf, true, false, func(alg string) func(data, sig []byte) error {
+					return func(data, sig []byte) error {
+						assert.Equal(t, sigs[k], string(sig), string(s))
+						return nil
+					}
+				})
+
+				assert.NoError(t, err, string(s))
+				assert.Equal(t, headers[i], string(header), string(s))
+				assert.Equal(t, claims[j], string(decodedClaims), string(s))
+
+				token, err := Parse(s)
+				assert.NoError(t, err, string(s))
+				assert.Equal(t, claims[j], string(token.RawClaims), string(s))
+				assert.Equal(t, sigs[k], string(token.Signature), string(s))
+
+				var raw json.RawMessage
+				assert.NoError(t, InsecureDecodeClaims(s, &raw), string(s))
+				assert.Equal(t, claims[j], string(raw), string(s))
+			}
+		}
+	}
 }
 
 func TestSign(t *testing.T) {
-	s, err := sign("test", 3, true, func(data []byte) ([]byte, error) {
+	s, err := sign("test", 3, true, nil, func(data []byte) ([]byte, error) {
 		// echo -n '{"typ":"JWT","alg":"test"}' | base64 | tr -d =
 		// echo -n 'true' | base64 | tr -d =
 		assert.Equal(t, []byte("eyJ0eXAiOiJKV1QiLCJhbGciOiJ0ZXN0In0.dHJ1ZQ"), data)
@@ -47,9 +165,61 @@ func TestSign(t *testing.T) {
 	assert.Equal(t, []byte("eyJ0eXAiOiJKV1QiLCJhbGciOiJ0ZXN0In0.dHJ1ZQ.c2ln"), s)
 
 	testErr := errors.New("test error")
-	_, err = sign("test", 3, true, func(data []byte) ([]byte, error) {
+	_, err = sign("test", 3, true, nil, func(data []byte) ([]byte, error) {
 		return nil, testErr
 	})
 
 	assert.Equal(t, err, testErr)
+
+	_, err = sign("test", 3, true, []SignOption{WithSignPolicy(SignPolicy{RequireIssuer: true})}, func(data []byte) ([]byte, error) {
+		t.Fail() // the policy should be checked before anything gets signed
+		return nil, nil
+	})
+
+	assert.True(t, errors.Is(err, ErrPolicyViolation))
+
+	// If fn returns more or fewer bytes than sigLen, the signature must still be
+	// encoded in full, with nothing left over.
+	s, err = sign("test", 1, true, nil, func(data []byte) ([]byte, error) {
+		return []byte("sig"), nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("eyJ0eXAiOiJKV1QiLCJhbGciOiJ0ZXN0In0.dHJ1ZQ.c2ln"), s)
+
+	s, err = sign("test", 64, true, nil, func(data []byte) ([]byte, error) {
+		return []byte("sig"), nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("eyJ0eXAiOiJKV1QiLCJhbGciOiJ0ZXN0In0.dHJ1ZQ.c2ln"), s)
+}
+
+func TestEncodedHeaders(t *testing.T) {
+	for alg, encoded := range encodedHeaders {
+		var c signConfig
+		h, err := c.marshalHeader(alg)
+		assert.NoError(t, err)
+
+		// The precomputed header must be exactly what marshalHeader produces.
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(h), string(encoded), alg)
+
+		// Header parameters beyond "typ" and "alg" mean the header can't be
+		// precomputed.
+		c.keyID = "k1"
+		withKeyID, err := c.encodeHeader(alg)
+		assert.NoError(t, err)
+		assert.NotEqual(t, encoded, withKeyID, alg)
+
+		c = signConfig{headerParams: map[string]interface{}{"x5t": "abc"}}
+		withParams, err := c.encodeHeader(alg)
+		assert.NoError(t, err)
+		assert.NotEqual(t, encoded, withParams, alg)
+	}
+
+	// Algorithms this package doesn't know about are encoded every time.
+	var c signConfig
+	encoded, err := c.encodeHeader("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "eyJ0eXAiOiJKV1QiLCJhbGciOiJ0ZXN0In0", string(encoded))
 }
@@ -0,0 +1,238 @@
+// Package webhook signs and verifies outbound webhook requests using JWTs.
+//
+// A webhook sender calls Sign to produce a short-lived JWT that binds together
+// the request method, the target URL, and a SHA-256 digest of the request body.
+// The sender puts that JWT in the Webhook-Token header of the request. A
+// webhook receiver calls Verify, which verifies the JWT and then checks that the
+// request it actually received matches what the sender signed.
+//
+// This package does not choose a signing algorithm. You choose one when you
+// construct the jwt.Signer and jwt.Verifier you pass to Sign and Verify.
+//
+// # Canonicalization
+//
+// Senders and receivers must agree byte-for-byte on what gets signed. This
+// package signs the following claims:
+//
+// * "htm" is the request method, converted to upper case.
+//
+// * "htu" is the target URL, in the form returned by CanonicalURL.
+//
+// * "bsh" is the SHA-256 digest of the exact bytes of the request body, encoded
+// using unpadded, URL-safe base64. An empty body is hashed like any other body.
+//
+// * "iat" and "exp" are the time the token was issued and the time it stops
+// being valid, as seconds since the Unix epoch.
+package webhook
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ucarion/jwt"
+)
+
+// HeaderName is the HTTP header that carries the JWT produced by Sign.
+const HeaderName = "Webhook-Token"
+
+// ErrMissingToken is the error returned by Verify if a request does not have a
+// HeaderName header.
+var ErrMissingToken = errors.New("webhook: missing token")
+
+// ErrRequestMismatch is the error returned by Verify if the method or URL of a
+// request does not match the method or URL that the sender signed.
+var ErrRequestMismatch = errors.New("webhook: request does not match token")
+
+// ErrBodyMismatch is the error returned by Verify if the body of a request does
+// not match the body that the sender signed.
+var ErrBodyMismatch = errors.New("webhook: body does not match token")
+
+// Claims is the set of claims that Sign puts into a webhook JWT.
+type Claims struct {
+	jwt.StandardClaims
+
+	// Method is the canonicalized HTTP method of the request.
+	Method string `json:"htm"`
+
+	// URL is the canonicalized URL of the request. See CanonicalURL.
+	URL string `json:"htu"`
+
+	// BodySHA256 is the unpadded, URL-safe base64 encoding of the SHA-256 digest
+	// of the request body.
+	BodySHA256 string `json:"bsh"`
+}
+
+// ExpectedClaims describes what Verify expects of a webhook request.
+type ExpectedClaims struct {
+	// URL is the URL the webhook was supposed to be sent to.
+	//
+	// If URL is empty, Verify reconstructs the URL from the request it is given.
+	// That reconstruction uses the Host header and whether the request arrived
+	// over TLS, so if your server runs behind a proxy or load balancer, you
+	// should set URL explicitly instead.
+	URL string
+
+	// Now is the time against which the token's "iat" and "exp" are checked. If
+	// Now is the zero time, time.Now() is used.
+	Now time.Time
+
+	// Leeway is how much clock skew between the sender and receiver to tolerate
+	// when checking "iat" and "exp".
+	Leeway time.Duration
+}
+
+// Sign produces a JWT for a webhook request, suitable for use as the value of
+// the HeaderName header.
+//
+// The token is signed using signer, and is valid for ttl from the current time.
+// url must be an absolute URL; see CanonicalURL for how it gets normalized.
+func Sign(signer jwt.Signer, method, url string, body []byte, ttl time.Duration) ([]byte, error) {
+	canonicalURL, err := CanonicalURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return signer.Sign(Claims{
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:       now.Unix(),
+			ExpirationTime: now.Add(ttl).Unix(),
+		},
+		Method:     strings.ToUpper(method),
+		URL:        canonicalURL,
+		BodySHA256: bodyDigest(body),
+	})
+}
+
+// Verify checks that r is a genuine, fresh webhook request.
+//
+// body must be the complete body of r. Verify does not read r.Body itself,
+// because callers usually need the body afterwards.
+//
+// Verify returns ErrMissingToken if r has no token, any error returned by
+// verifier if the token is not valid, jwt.ErrExpiredToken if the token is
+// expired, jwt.ErrNotYetValid if the token was issued in the future,
+// ErrRequestMismatch if the method or URL of r differ from what was signed,
+// and ErrBodyMismatch if body differs from what was signed.
+func Verify(verifier jwt.Verifier, r *http.Request, body []byte, expected ExpectedClaims) error {
+	token := r.Header.Get(HeaderName)
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	var claims Claims
+	if err := verifier.Verify([]byte(token), &claims); err != nil {
+		return err
+	}
+
+	now := expected.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if err := claims.VerifyExpirationTime(now.Add(-expected.Leeway)); err != nil {
+		return err
+	}
+
+	if now.Add(expected.Leeway).Before(time.Unix(claims.IssuedAt, 0)) {
+		return jwt.ErrNotYetValid
+	}
+
+	expectedURL := expected.URL
+	if expectedURL == "" {
+		expectedURL = requestURL(r)
+	}
+
+	canonicalURL, err := CanonicalURL(expectedURL)
+	if err != nil {
+		return err
+	}
+
+	if claims.Method != strings.ToUpper(r.Method) || claims.URL != canonicalURL {
+		return ErrRequestMismatch
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.BodySHA256), []byte(bodyDigest(body))) != 1 {
+		return ErrBodyMismatch
+	}
+
+	return nil
+}
+
+// CanonicalURL returns the form of rawURL that gets signed into the "htu"
+// claim.
+//
+// rawURL must be absolute. The canonical form is constructed as follows:
+//
+// * The scheme and host are converted to lower case.
+//
+// * The port is removed if it is the default port for the scheme (80 for http,
+// 443 for https).
+//
+// * The path is kept in its escaped form. An empty path becomes "/".
+//
+// * The query string, if any, is kept exactly as given. Query parameters are
+// not reordered or re-escaped.
+//
+// * Any user information and fragment are removed, because they are never sent
+// to the server.
+func CanonicalURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return "", errors.New("webhook: url must be absolute")
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+
+	if port != "" {
+		host = host + ":" + port
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	s := scheme + "://" + host + path
+	if u.RawQuery != "" {
+		s += "?" + u.RawQuery
+	}
+
+	return s, nil
+}
+
+// requestURL reconstructs the URL a server-side request was sent to.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// bodyDigest returns the value of the "bsh" claim for body.
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// VerifyX5C verifies a JWT that carries the certificate of the key it was
+// signed with in its "x5c" header parameter. If the certificate chains up to
+// one of roots, and the JWT is signed with the certificate's key using alg,
+// VerifyX5C will serialize the claims inside the JWT into v, and return the
+// certificate.
+//
+// The certificate chain in "x5c" is never trusted on its own. roots decides
+// which certificates are trusted, and VerifyX5C returns ErrInvalidKey if roots
+// is nil, rather than falling back to the system's roots. The leaf certificate
+// must be valid at the current time, which is taken from WithNow or WithClock
+// if opts has either, and if it has a key usage, that usage must include
+// digital signatures. Extended key usages are not checked.
+//
+// As with the other Verify functions in this package, alg decides what
+// algorithm the JWT must be signed with, not the JWT. alg must be one of
+// "RS256", "RS384", "PS256", "ES256", "ES512", or "EdDSA"; for any other alg,
+// VerifyX5C returns ErrUnsupportedAlgorithm. The leaf certificate's key must
+// be of the right type for alg.
+//
+// opts can be used to further configure how the token is verified. See
+// VerifyOption.
+//
+// VerifyX5C will return an error wrapping ErrMalformedToken if the JWT is
+// malformed. It will return ErrWrongAlgorithm if the JWT uses any algorithm
+// other than alg, or if the key in its leaf certificate can't be used with
+// alg. It will return ErrBadSignature if the JWT has a missing or untrusted
+// certificate chain, or is not signed with the key in its leaf certificate.
+//
+// https://tools.ietf.org/html/rfc7515#section-4.1.6
+func VerifyX5C(roots *x509.CertPool, alg string, s []byte, v interface{}, opts ...VerifyOption) (*x509.Certificate, error) {
+	if err := checkClaimsTarget(v); err != nil {
+		return nil, err
+	}
+
+	if roots == nil {
+		return nil, ErrInvalidKey
+	}
+
+	switch alg {
+	case algRS256, algRS384, algPS256, algES256, algES512, algEdDSA:
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	// The header is untrusted at this point. It's only used to find the
+	// certificate chain, which is then checked against roots.
+	h, err := peekHeader(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var x5c struct {
+		X5C []string `json:"x5c"`
+	}
+
+	if err := json.Unmarshal(h, &x5c); err != nil || len(x5c.X5C) == 0 {
+		return nil, ErrBadSignature
+	}
+
+	// Each certificate in "x5c" is base64-encoded DER. Unlike elsewhere in JWT,
+	// this is standard base64, not base64url.
+	var chain []*x509.Certificate
+	for _, c := range x5c.X5C {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, ErrBadSignature
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, ErrBadSignature
+		}
+
+		chain = append(chain, cert)
+	}
+
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	c := newVerifyConfig(opts)
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   c.clock(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, ErrBadSignature
+	}
+
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return nil, ErrBadSignature
+	}
+
+	allowed, ok := allowKey(alg, leaf.PublicKey)
+	if !ok {
+		return nil, ErrWrongAlgorithm
+	}
+
+	header, claims, err := verify(alg, s, v, opts, allowed.fn(&c))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalClaims(header, claims, v, opts); err != nil {
+		return nil, err
+	}
+
+	return leaf, nil
+}
+
+// allowKey returns an AllowedAlgorithm that accepts JWTs signed with alg and
+// the private key corresponding to key. It returns false if alg is not one of
+// the public-key algorithms this package implements, or if key can't be used
+// with alg.
+func allowKey(alg string, key crypto.PublicKey) (AllowedAlgorithm, bool) {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case algRS256:
+			return AllowRS256(pub), true
+		case algRS384:
+			return AllowRS384(pub), true
+		case algPS256:
+			return AllowPS256(pub), true
+		}
+	case *ecdsa.PublicKey:
+		switch {
+		case alg == algES256 && pub.Curve == elliptic.P256():
+			return AllowES256(pub), true
+		case alg == algES512 && pub.Curve == elliptic.P521():
+			return AllowES512(pub), true
+		}
+	case ed25519.PublicKey:
+		if alg == algEdDSA {
+			return AllowEdDSA(pub), true
+		}
+	}
+
+	return AllowedAlgorithm{}, false
+}
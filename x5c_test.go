@@ -0,0 +1,175 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ucarion/jwt"
+)
+
+// newCert returns a certificate for pub, signed by parent's key priv. If parent
+// is nil, the certificate is self-signed, and is a CA.
+func newCert(t *testing.T, pub crypto.PublicKey, parent *x509.Certificate, priv crypto.Signer, template x509.Certificate) *x509.Certificate {
+	template.SerialNumber = big.NewInt(1)
+	template.Subject = pkix.Name{CommonName: "test"}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent = &template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, parent, pub, priv)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func x5c(certs ...*x509.Certificate) jwt.SignOption {
+	var chain []string
+	for _, cert := range certs {
+		chain = append(chain, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+
+	return jwt.WithHeaderParams(map[string]interface{}{"x5c": chain})
+}
+
+func TestVerifyX5C(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	root := newCert(t, &rootKey.PublicKey, nil, rootKey, x509.Certificate{})
+
+	intermediateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	intermediate := newCert(t, &intermediateKey.PublicKey, root, rootKey, x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	other := newCert(t, &otherKey.PublicKey, nil, otherKey, x509.Certificate{})
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	leaf := newCert(t, &leafKey.PublicKey, root, rootKey, x509.Certificate{KeyUsage: x509.KeyUsageDigitalSignature})
+	leafViaIntermediate := newCert(t, &leafKey.PublicKey, intermediate, intermediateKey, x509.Certificate{})
+	leafViaOther := newCert(t, &leafKey.PublicKey, other, otherKey, x509.Certificate{})
+	expired := newCert(t, &leafKey.PublicKey, root, rootKey, x509.Certificate{
+		NotBefore: time.Now().Add(-2 * time.Hour),
+		NotAfter:  time.Now().Add(-time.Hour),
+	})
+	wrongUsage := newCert(t, &leafKey.PublicKey, root, rootKey, x509.Certificate{KeyUsage: x509.KeyUsageKeyEncipherment})
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaLeaf := newCert(t, &rsaKey.PublicKey, root, rootKey, x509.Certificate{})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	claims := jwt.StandardClaims{Subject: "jdoe@example.com"}
+
+	t.Run("valid", func(t *testing.T) {
+		for _, opt := range []jwt.SignOption{x5c(leaf), x5c(leaf, root), x5c(leafViaIntermediate, intermediate)} {
+			token, err := jwt.SignES256(leafKey, claims, opt)
+			assert.NoError(t, err)
+
+			var out jwt.StandardClaims
+			cert, err := jwt.VerifyX5C(roots, "ES256", token, &out)
+			assert.NoError(t, err)
+			assert.Equal(t, claims, out)
+			assert.Equal(t, &leafKey.PublicKey, cert.PublicKey)
+		}
+
+		token, err := jwt.SignRS256(rsaKey, claims, x5c(rsaLeaf))
+		assert.NoError(t, err)
+
+		var out jwt.StandardClaims
+		cert, err := jwt.VerifyX5C(roots, "RS256", token, &out)
+		assert.NoError(t, err)
+		assert.Equal(t, rsaLeaf, cert)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sign := func(opts ...jwt.SignOption) []byte {
+			token, err := jwt.SignES256(leafKey, claims, opts...)
+			assert.NoError(t, err)
+			return token
+		}
+
+		testCases := []struct {
+			name  string
+			token []byte
+			alg   string
+			err   error
+		}{
+			{"no x5c", sign(), "ES256", jwt.ErrBadSignature},
+			{"empty x5c", sign(jwt.WithHeaderParams(map[string]interface{}{"x5c": []string{}})), "ES256", jwt.ErrBadSignature},
+			{"malformed x5c", sign(jwt.WithHeaderParams(map[string]interface{}{"x5c": "abc"})), "ES256", jwt.ErrBadSignature},
+			{"x5c not base64", sign(jwt.WithHeaderParams(map[string]interface{}{"x5c": []string{"!!!"}})), "ES256", jwt.ErrBadSignature},
+			{"x5c not a certificate", sign(jwt.WithHeaderParams(map[string]interface{}{"x5c": []string{"YWJj"}})), "ES256", jwt.ErrBadSignature},
+			{"untrusted root", sign(x5c(leafViaOther, other)), "ES256", jwt.ErrBadSignature},
+			{"missing intermediate", sign(x5c(leafViaIntermediate)), "ES256", jwt.ErrBadSignature},
+			{"expired", sign(x5c(expired)), "ES256", jwt.ErrBadSignature},
+			{"wrong key usage", sign(x5c(wrongUsage)), "ES256", jwt.ErrBadSignature},
+			{"wrong alg", sign(x5c(leaf)), "RS256", jwt.ErrWrongAlgorithm},
+			{"wrong curve for alg", sign(x5c(leaf)), "ES512", jwt.ErrWrongAlgorithm},
+			{"signed with a different key", forgeToken(`{"alg":"ES256","x5c":["`+base64.StdEncoding.EncodeToString(leaf.Raw)+`"]}`, `{}`, func([]byte) []byte { return make([]byte, 64) }), "ES256", jwt.ErrBadSignature},
+			{"rsa key for ecdsa alg", func() []byte {
+				token, err := jwt.SignRS256(rsaKey, claims, x5c(rsaLeaf))
+				assert.NoError(t, err)
+				return token
+			}(), "ES256", jwt.ErrWrongAlgorithm},
+		}
+
+		for _, tt := range testCases {
+			t.Run(tt.name, func(t *testing.T) {
+				var out jwt.StandardClaims
+				cert, err := jwt.VerifyX5C(roots, tt.alg, tt.token, &out)
+				assert.Equal(t, tt.err, err)
+				assert.True(t, errors.Is(err, jwt.ErrInvalidSignature))
+				assert.Nil(t, cert)
+			})
+		}
+	})
+
+	t.Run("nil roots", func(t *testing.T) {
+		token, err := jwt.SignES256(leafKey, claims, x5c(leaf))
+		assert.NoError(t, err)
+
+		var out jwt.StandardClaims
+		_, err = jwt.VerifyX5C(nil, "ES256", token, &out)
+		assert.Equal(t, jwt.ErrInvalidKey, err)
+	})
+
+	t.Run("unsupported alg", func(t *testing.T) {
+		token, err := jwt.SignES256(leafKey, claims, x5c(leaf))
+		assert.NoError(t, err)
+
+		for _, alg := range []string{"HS256", "none", "ES256K", ""} {
+			var out jwt.StandardClaims
+			_, err = jwt.VerifyX5C(roots, alg, token, &out)
+			assert.Equal(t, jwt.ErrUnsupportedAlgorithm, err, alg)
+		}
+	})
+}